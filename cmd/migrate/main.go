@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/joho/godotenv"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/storage/database"
+)
+
+const usage = `usage: migrate <command> [steps]
+
+Commands:
+  up [N]       apply the next N pending migrations (all of them by default)
+  down [N]     revert the last N applied migrations (one by default)
+  status       show the status of the migrations
+  baseline N   record the migrations up to version N as applied, without running them
+  seed         load the sample data of the seed directory into an up to date schema`
+
+// seedDir is the directory with the seed files, inside the migrations directory.
+const seedDir = "seed"
+
+func main() {
+	ctx := context.Background()
+	if len(os.Args) < 2 || len(os.Args) > 3 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(-1)
+	}
+
+	steps := 0
+	if len(os.Args) == 3 {
+		var err error
+		steps, err = strconv.Atoi(os.Args[2])
+		if err != nil || steps <= 0 {
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(-1)
+		}
+	}
+
+	// Load environment variables from .env file
+	if err := godotenv.Load(".env"); err != nil {
+		slog.ErrorContext(ctx, "error loading the environment file", "error", err)
+		os.Exit(-1)
+	}
+
+	dir := os.Getenv("POSTGRES_SQL_DIR")
+	migrations, err := database.LoadMigrations(os.DirFS(dir))
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to load the migrations", "directory", dir, "error", err)
+		os.Exit(-1)
+	}
+
+	// Initialize database connection
+	cfg, err := database.ConfigFromEnv()
+	if err != nil {
+		slog.ErrorContext(ctx, "invalid database configuration", "error", err)
+		os.Exit(-1)
+	}
+
+	db, err := database.New(cfg)
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to initialize the database", "error", err)
+		os.Exit(-1)
+	}
+
+	if err := db.Connect(ctx); err != nil {
+		slog.ErrorContext(ctx, "unable to connect to the database", "error", err)
+		os.Exit(-1)
+	}
+
+	err = run(ctx, db, os.Args[1], dir, migrations, steps)
+	cleanup(ctx, db)
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to run the migrations", "command", os.Args[1],
+			"error", err)
+		os.Exit(-1)
+	}
+}
+
+func run(ctx context.Context, db *database.Database, command, dir string,
+	migrations []database.Migration, steps int,
+) error {
+	switch command {
+	case "up":
+		applied, err := db.MigrateUp(ctx, migrations, steps)
+		for i := range applied {
+			slog.InfoContext(ctx, "migration applied", "version", applied[i].Version,
+				"name", applied[i].Name)
+		}
+
+		return err
+	case "down":
+		reverted, err := db.MigrateDown(ctx, migrations, steps)
+		for i := range reverted {
+			slog.InfoContext(ctx, "migration reverted", "version", reverted[i].Version,
+				"name", reverted[i].Name)
+		}
+
+		return err
+	case "baseline":
+		if steps == 0 {
+			return fmt.Errorf("the baseline version is required\n%s", usage)
+		}
+
+		recorded, err := db.MigrateBaseline(ctx, migrations, int64(steps))
+		for i := range recorded {
+			slog.InfoContext(ctx, "migration recorded", "version", recorded[i].Version,
+				"name", recorded[i].Name)
+		}
+
+		return err
+	case "seed":
+		if err := checkApplied(ctx, db, migrations); err != nil {
+			return err
+		}
+
+		seeded, err := db.Seed(ctx, os.DirFS(filepath.Join(dir, seedDir)))
+		for _, name := range seeded {
+			slog.InfoContext(ctx, "seed file loaded", "file", name)
+		}
+
+		return err
+	case "status":
+		status, err := db.MigrationsStatus(ctx, migrations)
+		if err != nil {
+			return err
+		}
+
+		for i := range status {
+			printStatus(&status[i])
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unknown command %q\n%s", command, usage)
+}
+
+// checkApplied checks all the migrations are applied, as the seed files use the latest
+// schema.
+func checkApplied(ctx context.Context, db *database.Database,
+	migrations []database.Migration,
+) error {
+	status, err := db.MigrationsStatus(ctx, migrations)
+	if err != nil {
+		return err
+	}
+
+	for i := range status {
+		if !status[i].Applied {
+			return fmt.Errorf("the migration %d-%s is pending, apply the migrations first",
+				status[i].Version, status[i].Name)
+		}
+	}
+
+	return nil
+}
+
+func printStatus(st *database.MigrationStatus) {
+	state := "pending"
+	switch {
+	case st.Missing:
+		state = "applied (missing file)"
+	case st.Modified:
+		state = "applied (modified file)"
+	case st.Applied:
+		state = "applied"
+	}
+
+	applied := ""
+	if st.Applied {
+		applied = st.AppliedAt.Format("2006-01-02 15:04:05")
+	}
+
+	fmt.Printf("%03d  %-30s  %-24s  %s\n", st.Version, st.Name, state, applied)
+}
+
+func cleanup(ctx context.Context, db *database.Database) {
+	err := db.Disconnect(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to close the database connection", "error", err)
+		os.Exit(-1)
+	}
+}
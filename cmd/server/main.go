@@ -2,15 +2,41 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/cursor"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/handler/health"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/exchange"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/currency"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/storage"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/storage/cache"
 	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/storage/database"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/storage/memory"
+)
+
+const (
+	// memoryStorage selects the in-memory storage.
+	memoryStorage = "memory"
+	// postgresStorage selects the postgres database storage (default).
+	postgresStorage = "postgres"
+
+	// defaultCacheControl lets the clients and the proxies cache the catalog reads, but
+	// they must revalidate them on every use.
+	defaultCacheControl = "public, no-cache"
+	// defaultExpiryInterval is the interval between the releases of the expired
+	// reservations.
+	defaultExpiryInterval = time.Minute
+	// defaultDrainDelay is the time the server keeps serving the requests after it's
+	// marked as not ready on shutdown.
+	defaultDrainDelay = 5 * time.Second
 )
 
 func main() {
@@ -25,13 +51,81 @@ func main() {
 	// Signal handling for graceful shutdown.
 	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
 
-	// Storage initialization.
-	db := database.New(os.Getenv("POSTGRES_USER"), os.Getenv("POSTGRES_PASSWORD"),
-		os.Getenv("POSTGRES_DB"), os.Getenv("POSTGRES_PORT"))
+	// Exchange rates initialization.
+	rates, err := newExchange(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Unable to load the exchange rates", "error", err)
+		stop()
+
+		os.Exit(-1)
+	}
+
+	go rates.Run(ctx)
+
+	// Storage initialization. The prices in different currencies are compared with the
+	// exchange rates.
+	st, err := newStorage(ctx, os.Getenv("STORAGE"), rates.Rates)
+	if err != nil {
+		slog.ErrorContext(ctx, "Unable to initialize the storage", "error", err)
+		stop()
+
+		os.Exit(-1)
+	}
+
+	// Cursors initialization.
+	cursors, err := newCursorCodec(ctx, os.Getenv("CURSOR_SECRET"))
+	if err != nil {
+		slog.ErrorContext(ctx, "Unable to initialize the cursors", "error", err)
+		stop()
+
+		os.Exit(-1)
+	}
+
+	// Readiness checks initialization.
+	checks, err := readinessChecks(st, os.Getenv("POSTGRES_SQL_DIR"))
+	if err != nil {
+		slog.ErrorContext(ctx, "Unable to initialize the readiness checks", "error", err)
+		stop()
+
+		os.Exit(-1)
+	}
+
+	// Storage cache initialization. The readiness checks use the wrapped storage.
+	st, err = newCache(st)
+	if err != nil {
+		slog.ErrorContext(ctx, "Unable to initialize the storage cache", "error", err)
+		stop()
+
+		os.Exit(-1)
+	}
+
+	// Release of the expired reservations.
+	interval, err := durationEnv("RESERVATIONS_EXPIRY_INTERVAL", defaultExpiryInterval)
+	if err != nil {
+		slog.ErrorContext(ctx, "Unable to initialize the reservations expiry", "error", err)
+		stop()
+
+		os.Exit(-1)
+	}
+
+	go expireReservations(ctx, st, interval)
 
 	// Server initialization.
+	drain, err := durationEnv("SHUTDOWN_DRAIN_DELAY", defaultDrainDelay)
+	if err != nil {
+		slog.ErrorContext(ctx, "Unable to initialize the server", "error", err)
+		stop()
+
+		os.Exit(-1)
+	}
+
 	addr := fmt.Sprintf("localhost:%s", os.Getenv("HTTP_PORT"))
-	srv := NewServer(addr, db)
+	var adminAddr string
+	if port := os.Getenv("ADMIN_PORT"); port != "" {
+		adminAddr = fmt.Sprintf("localhost:%s", port)
+	}
+
+	srv := NewServer(addr, adminAddr, st, cursors, rates, cacheControl(), checks...)
 	if err := srv.Start(ctx); err != nil {
 		slog.ErrorContext(ctx, "Unable to start the http server", "error", err)
 		stop()
@@ -40,7 +134,7 @@ func main() {
 	}
 
 	<-ctx.Done()
-	if err := srv.Stop(ctx); err != nil {
+	if err := srv.Stop(ctx, drain); err != nil {
 		slog.ErrorContext(ctx, "Unable to shutdown the server", "error", err)
 		stop()
 
@@ -49,3 +143,175 @@ func main() {
 
 	stop()
 }
+
+// cacheControl returns the Cache-Control header of the catalog reads. An empty value
+// disables the header.
+func cacheControl() string {
+	value, ok := os.LookupEnv("CACHE_CONTROL")
+	if !ok {
+		return defaultCacheControl
+	}
+
+	return value
+}
+
+// durationEnv returns the duration of the environment variable, or the default value if
+// it's not defined. The durations can't be negative.
+func durationEnv(name string, defaultValue time.Duration) (time.Duration, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return defaultValue, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, value)
+	}
+
+	return d, nil
+}
+
+// expireReservations releases the expired reservations on every interval until the
+// context is done. The failed releases are logged and retried on the next interval.
+func expireReservations(ctx context.Context, st storage.Storage, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := st.ExpireReservations(ctx)
+			if err != nil {
+				slog.WarnContext(ctx, "Unable to expire the reservations", "error", err)
+
+				continue
+			}
+
+			if expired > 0 {
+				slog.InfoContext(ctx, "Expired reservations released", "expired", expired)
+			}
+		}
+	}
+}
+
+// newStorage returns the storage selected by its name, comparing the prices with the
+// rates. The postgres database is used by default, while the in-memory storage is loaded
+// with the sample data.
+func newStorage(ctx context.Context, name string,
+	rates func() *currency.Rates,
+) (storage.Storage, error) {
+	switch name {
+	case "", postgresStorage:
+		cfg, err := database.ConfigFromEnv()
+		if err != nil {
+			return nil, err
+		}
+
+		db, err := database.New(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		db.SetRates(rates)
+
+		return db, nil
+	case memoryStorage:
+		mem, err := memory.NewWithSampleData(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		mem.SetRates(rates)
+
+		return mem, nil
+	}
+
+	return nil, fmt.Errorf("unknown storage %q", name)
+}
+
+// newCache returns the storage wrapped with a cache, unless the cache is disabled. The
+// statistics of the cache are published in the storage_cache variable of /debug/vars, in
+// the admin server.
+func newCache(st storage.Storage) (storage.Storage, error) {
+	cfg, err := cache.ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	if !cfg.Enabled() {
+		return st, nil
+	}
+
+	cached := cache.New(st, cfg)
+	expvar.Publish("storage_cache", expvar.Func(func() any {
+		return cached.Stats()
+	}))
+
+	return cached, nil
+}
+
+// newExchange returns the exchange with the rates of its source already loaded, so the
+// server doesn't start without them. They are refreshed by running the exchange.
+func newExchange(ctx context.Context) (*exchange.Exchange, error) {
+	cfg, err := exchange.ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	rates := exchange.New(cfg)
+	if err := rates.Load(ctx); err != nil {
+		return nil, err
+	}
+
+	return rates, nil
+}
+
+// readinessChecks returns the checks of the storage dependencies. The database must be
+// reachable and, if the migrations directory is defined, all its migrations must be
+// applied.
+func readinessChecks(st storage.Storage, migrationsDir string) ([]health.Check, error) {
+	db, ok := st.(*database.Database)
+	if !ok {
+		return nil, nil
+	}
+
+	checks := []health.Check{{Name: "database", Fn: db.Ping}}
+	if migrationsDir == "" {
+		return checks, nil
+	}
+
+	migrations, err := database.LoadMigrations(os.DirFS(migrationsDir))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(migrations) > 0 {
+		version := migrations[len(migrations)-1].Version
+		checks = append(checks, health.Check{
+			Name: "migrations",
+			Fn: func(ctx context.Context) error {
+				return db.CheckSchemaVersion(ctx, version)
+			},
+		})
+	}
+
+	return checks, nil
+}
+
+// newCursorCodec returns the codec of the pagination cursors signed with the secret.
+// Without secret a random one is used, so the cursors are only valid for this instance.
+func newCursorCodec(ctx context.Context, secret string) (*cursor.Codec, error) {
+	if secret != "" {
+		return cursor.NewCodec([]byte(secret)), nil
+	}
+
+	slog.WarnContext(ctx, "CURSOR_SECRET not defined, using a random secret")
+
+	return cursor.NewRandomCodec()
+}
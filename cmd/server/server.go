@@ -2,50 +2,118 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"time"
 
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/cursor"
 	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/handler/catalog"
 	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/handler/category"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/handler/health"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/handler/inventory"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/response"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/exchange"
 	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/storage"
 )
 
 const (
 	readHeaderTimeout = 1 * time.Second
+	// shutdownTimeout is the maximum time to finish the in-flight requests on shutdown.
+	shutdownTimeout = 30 * time.Second
 )
 
 // Server implements the api http server.
 type Server struct {
-	logger  *slog.Logger
-	st      storage.Storage
-	srv     *http.Server
+	logger *slog.Logger
+	st     storage.Storage
+	health *health.Handler
+	srv    *http.Server
+	// admin is the server of the internal endpoints, or nil if they aren't served.
+	admin   *http.Server
 	address string
 }
 
-// NewServer initializes the api server.
-func NewServer(addr string, st storage.Storage) *Server {
-	return &Server{
+// NewServer initializes the api server. The exchange converts the catalog prices to the
+// requested currency, the cacheControl value is the Cache-Control header of the catalog
+// and category reads, and the server is ready when all the checks succeed. The internal
+// endpoints, like the runtime metrics, are only served on the admin address, if any, so
+// they aren't exposed with the public api.
+func NewServer(addr, adminAddr string, st storage.Storage, cursors *cursor.Codec,
+	rates *exchange.Exchange, cacheControl string, checks ...health.Check,
+) *Server {
+	probes := health.NewHandler(checks...)
+	s := &Server{
 		address: addr,
 		logger:  slog.Default().With("address", addr),
 		st:      st,
+		health:  probes,
 		srv: &http.Server{
 			Addr:              addr,
-			Handler:           router(st),
+			Handler:           router(st, cursors, rates, cacheControl, probes),
 			ReadHeaderTimeout: readHeaderTimeout,
 		},
 	}
+
+	if adminAddr != "" {
+		s.admin = &http.Server{
+			Addr:              adminAddr,
+			Handler:           adminRouter(st),
+			ReadHeaderTimeout: readHeaderTimeout,
+		}
+	}
+
+	return s
 }
 
-func router(st storage.Storage) http.Handler {
-	products := catalog.NewHandler(st)
+func router(st storage.Storage, cursors *cursor.Codec, rates *exchange.Exchange,
+	cacheControl string, probes *health.Handler,
+) http.Handler {
+	products := catalog.NewHandler(st, cursors, rates)
 	cats := category.NewHandler(st)
+	stock := inventory.NewHandler(st)
 	mux := http.NewServeMux()
-	mux.HandleFunc("GET /catalog", products.HandleGetProducts)
-	mux.HandleFunc("GET /catalog/{code}", products.HandleGetProduct)
-	mux.HandleFunc("GET /categories", cats.HandleGetCategories)
+	mux.HandleFunc("GET /healthz", probes.HandleHealthz)
+	mux.HandleFunc("GET /readyz", probes.HandleReadyz)
+	mux.HandleFunc("GET /catalog", response.CacheControl(cacheControl,
+		products.HandleGetProducts))
+	mux.HandleFunc("GET /catalog/{code}", response.CacheControl(cacheControl,
+		products.HandleGetProduct))
+	mux.HandleFunc("POST /catalog", products.HandlePostProduct)
+	mux.HandleFunc("PUT /catalog/{code}", products.HandlePutProduct)
+	mux.HandleFunc("PATCH /catalog/{code}", products.HandlePatchProduct)
+	mux.HandleFunc("DELETE /catalog/{code}", products.HandleDeleteProduct)
+	mux.HandleFunc("GET /catalog/{code}/variants", products.HandleGetVariants)
+	mux.HandleFunc("POST /catalog/{code}/variants", products.HandlePostVariant)
+	mux.HandleFunc("PUT /catalog/{code}/variants/{sku}", products.HandlePutVariant)
+	mux.HandleFunc("DELETE /catalog/{code}/variants/{sku}", products.HandleDeleteVariant)
+	mux.HandleFunc("GET /catalog/{code}/prices", products.HandleGetPrices)
+	mux.HandleFunc("POST /catalog/{code}/prices", products.HandlePostPrice)
+	mux.HandleFunc("DELETE /catalog/{code}/prices/{id}", products.HandleDeletePrice)
+	mux.HandleFunc("GET /categories", response.CacheControl(cacheControl,
+		cats.HandleGetCategories))
 	mux.HandleFunc("POST /categories", cats.HandlePostCategories)
+	mux.HandleFunc("GET /categories/{code}", response.CacheControl(cacheControl,
+		cats.HandleGetCategory))
+	mux.HandleFunc("PUT /categories/{code}", cats.HandlePutCategory)
+	mux.HandleFunc("DELETE /categories/{code}", cats.HandleDeleteCategory)
+	mux.HandleFunc("GET /inventory/{sku}", stock.HandleGetStock)
+	mux.HandleFunc("POST /inventory/{sku}/adjustments", stock.HandlePostAdjustment)
+	mux.HandleFunc("POST /reservations", stock.HandlePostReservation)
+	mux.HandleFunc("GET /reservations/{id}", stock.HandleGetReservation)
+	mux.HandleFunc("POST /reservations/{id}/commit", stock.HandleCommitReservation)
+	mux.HandleFunc("DELETE /reservations/{id}", stock.HandleDeleteReservation)
+
+	return mux
+}
+
+// adminRouter returns the routes of the internal endpoints.
+func adminRouter(st storage.Storage) http.Handler {
+	stock := inventory.NewHandler(st)
+	mux := http.NewServeMux()
+	mux.Handle("GET /debug/vars", expvar.Handler())
+	mux.HandleFunc("POST /reservations/expire", stock.HandleExpireReservations)
 
 	return mux
 }
@@ -53,37 +121,59 @@ func router(st storage.Storage) http.Handler {
 // Start the server.
 func (s *Server) Start(ctx context.Context) error {
 	// Initialize database connection
-	if err := s.st.Connect(); err != nil {
+	if err := s.st.Connect(ctx); err != nil {
 		return fmt.Errorf("unable to connect to the database: %w", err)
 	}
 
-	// Start the server
-	go func() {
-		slog.InfoContext(ctx, "Starting the server...", "server", s.srv)
-		if err := s.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			slog.ErrorContext(ctx, "Server failed", "server", s.srv, "error", err)
+	// Start the servers
+	go serve(ctx, s.srv)
+	if s.admin != nil {
+		go serve(ctx, s.admin)
+	}
+
+	return nil
+}
 
-			return
-		}
+// serve listens and serves the requests of the http server until it's shut down.
+func serve(ctx context.Context, srv *http.Server) {
+	slog.InfoContext(ctx, "Starting the server...", "server", srv)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		slog.ErrorContext(ctx, "Server failed", "server", srv, "error", err)
 
-		slog.InfoContext(ctx, "Server stopped gracefully...", "server", s.srv)
-	}()
+		return
+	}
 
-	return nil
+	slog.InfoContext(ctx, "Server stopped gracefully...", "server", srv)
 }
 
-// Stop the server.
-func (s *Server) Stop(ctx context.Context) error {
+// Stop the server. It's marked as not ready first, and it keeps serving the requests
+// during the drain delay, so the load balancers see the readiness probe failing and stop
+// routing requests to it before the listener is closed. The context of the caller is
+// usually cancelled by the shutdown signal, so it's only used for the logs.
+func (s *Server) Stop(ctx context.Context, drain time.Duration) error {
 	if s.srv == nil {
 		return nil
 	}
 
-	slog.InfoContext(ctx, "Shutting down the server", "server", s.srv)
+	slog.InfoContext(ctx, "Shutting down the server", "server", s.srv, "drain", drain)
+	// The server is not ready anymore, so no new requests are routed to it.
+	s.health.ShutDown()
+	time.Sleep(drain)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
 	// We shutdown the API before the database to make sure the database is not
 	// used anymore.
-	if err := s.srv.Shutdown(ctx); err != nil {
+	if err := s.srv.Shutdown(shutdownCtx); err != nil {
 		return err
 	}
 
-	return s.st.Disconnect()
+	if s.admin != nil {
+		if err := s.admin.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+	}
+
+	return s.st.Disconnect(shutdownCtx)
 }
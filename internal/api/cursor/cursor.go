@@ -0,0 +1,103 @@
+// Package cursor implements the opaque cursor tokens used by the API for the keyset
+// pagination. The tokens are signed, so the clients can't forge or modify them.
+package cursor
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/page"
+)
+
+// keySize is the size of the random keys.
+const keySize = 32
+
+// ErrInvalidCursor is returned when a cursor token is malformed, its signature is not
+// valid or it was created for another scope.
+var ErrInvalidCursor = page.ErrInvalidCursor
+
+// payload is the content of a cursor token.
+type payload struct {
+	Scope    string   `json:"s,omitempty"`
+	Values   []string `json:"v,omitempty"`
+	ID       uint     `json:"id"`
+	Backward bool     `json:"b,omitempty"`
+}
+
+// Codec encodes and decodes the cursor tokens.
+type Codec struct {
+	key []byte
+}
+
+// NewCodec returns a new cursor codec signing the tokens with the given key.
+func NewCodec(key []byte) *Codec {
+	return &Codec{
+		key: key,
+	}
+}
+
+// NewRandomCodec returns a new cursor codec with a random key. The tokens can only be
+// decoded by the same codec.
+func NewRandomCodec() (*Codec, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("unable to generate the cursor key: %w", err)
+	}
+
+	return NewCodec(key), nil
+}
+
+// Encode returns the token of the cursor. The scope identifies the listing the cursor
+// belongs to (e.g. its sort), so the token can't be used in a different one.
+func (c *Codec) Encode(cur *page.Cursor, scope string) (string, error) {
+	data, err := json.Marshal(payload{
+		Scope:    scope,
+		Values:   cur.Values,
+		ID:       cur.ID,
+		Backward: cur.Backward,
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to encode the cursor: %w", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(data)
+
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(c.sign(encoded)), nil
+}
+
+// Decode returns the cursor of the token, checking its signature and its scope.
+func (c *Codec) Decode(token, scope string) (*page.Cursor, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, c.sign(encoded)) {
+		return nil, ErrInvalidCursor
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var p payload
+	if err := json.Unmarshal(data, &p); err != nil || p.Scope != scope {
+		return nil, ErrInvalidCursor
+	}
+
+	return &page.Cursor{Values: p.Values, ID: p.ID, Backward: p.Backward}, nil
+}
+
+func (c *Codec) sign(data string) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
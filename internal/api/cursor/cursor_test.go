@@ -0,0 +1,59 @@
+package cursor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/page"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	t.Parallel()
+	codec := NewCodec([]byte("secret"))
+	cursors := []page.Cursor{
+		{ID: 1},
+		{ID: 42, Backward: true},
+		{Values: []string{"10.99", "PROD001"}, ID: 3},
+	}
+	for _, cur := range cursors {
+		token, err := codec.Encode(&cur, "price,-code")
+		require.NoError(t, err)
+
+		decoded, err := codec.Decode(token, "price,-code")
+		require.NoError(t, err)
+		assert.Equal(t, cur, *decoded)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	t.Parallel()
+	codec := NewCodec([]byte("secret"))
+	token, err := codec.Encode(&page.Cursor{ID: 1}, "")
+	require.NoError(t, err)
+
+	other, err := NewRandomCodec()
+	require.NoError(t, err)
+	forged, err := other.Encode(&page.Cursor{ID: 1}, "")
+	require.NoError(t, err)
+
+	scoped, err := codec.Encode(&page.Cursor{ID: 1}, "price")
+	require.NoError(t, err)
+
+	tests := map[string]string{
+		"empty":              "",
+		"without signature":  "eyJpZCI6MX0",
+		"modified payload":   "eyJpZCI6Mn0" + token[len("eyJpZCI6MX0"):],
+		"modified signature": token + "a",
+		"other key":          forged,
+		"other scope":        scoped,
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := codec.Decode(tc, "")
+			require.ErrorIs(t, err, ErrInvalidCursor)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+// Package etag implements the entity tags of the API resources. The tags of the
+// entities are their versions, so the clients can make their writes conditional on the
+// version they read with the If-Match header (optimistic concurrency control). The
+// tags of the lists are the hashes of their content, and the tags of the entities whose
+// content changes without a new version have both.
+package etag
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ifMatchHeader is the header with the entity tag a write is based on.
+	ifMatchHeader = "If-Match"
+	// ifNoneMatchHeader is the header with the entity tags the client has of a resource.
+	ifNoneMatchHeader = "If-None-Match"
+	// hashSize is the number of bytes of the hash used as entity tag.
+	hashSize = 16
+	// anyTag matches any version of an existing entity.
+	anyTag = "*"
+	// versionSeparator separates the version from the hash of the content in the tags of
+	// FormatContent. It isn't part of the hashes alphabet.
+	versionSeparator = "."
+)
+
+var (
+	// ErrMissing is returned when a write doesn't have the If-Match header.
+	ErrMissing = errors.New("the If-Match header is required")
+	// ErrInvalid is returned when the If-Match header is malformed.
+	ErrInvalid = errors.New("invalid If-Match header")
+	// ErrNoMatch is returned when the If-Match header can't match any version, like the
+	// weak tags, which are never used on writes.
+	ErrNoMatch = errors.New("the entity tag doesn't match")
+)
+
+// Format returns the strong entity tag of the given version.
+func Format(version uint) string {
+	return strconv.Quote(strconv.FormatUint(uint64(version), 10))
+}
+
+// Hash returns the strong entity tag of the given content.
+func Hash(content []byte) string {
+	return strconv.Quote(digest(content))
+}
+
+// FormatContent returns the strong entity tag of the given version with the given
+// content, for the entities whose content changes without a new version, like the
+// prices valid at the time of the request. The writes only check its version.
+func FormatContent(version uint, content []byte) string {
+	return strconv.Quote(strconv.FormatUint(uint64(version), 10) + versionSeparator +
+		digest(content))
+}
+
+// Set sets the entity tag of the response to the given version.
+func Set(w http.ResponseWriter, version uint) {
+	w.Header().Set("ETag", Format(version))
+}
+
+// IfMatch returns the version the write of the request is based on, from its If-Match
+// header. It returns zero if the header is "*", as the write doesn't depend on the
+// version. The header must have a single entity tag.
+func IfMatch(req *http.Request) (uint, error) {
+	values := req.Header.Values(ifMatchHeader)
+	if len(values) == 0 {
+		return 0, ErrMissing
+	}
+
+	if len(values) > 1 || strings.Contains(values[0], ",") {
+		return 0, ErrInvalid
+	}
+
+	tag := strings.TrimSpace(values[0])
+	if tag == anyTag {
+		return 0, nil
+	}
+
+	// The weak tags never match on writes, as they need a strong comparison.
+	if weak, ok := strings.CutPrefix(tag, "W/"); ok {
+		if !quoted(weak) {
+			return 0, ErrInvalid
+		}
+
+		return 0, ErrNoMatch
+	}
+
+	if !quoted(tag) {
+		return 0, ErrInvalid
+	}
+
+	value, _, _ := strings.Cut(tag[1:len(tag)-1], versionSeparator)
+	version, err := strconv.ParseUint(value, 10, strconv.IntSize)
+	if err != nil || version == 0 {
+		return 0, ErrNoMatch
+	}
+
+	return uint(version), nil
+}
+
+// InNoneMatch returns true if one of the entity tags of the If-None-Match header of the
+// request matches the given tag, or if the header is "*", so the client already has the
+// resource. The tags are compared with the weak comparison, as the header is only used
+// on reads.
+func InNoneMatch(req *http.Request, tag string) bool {
+	for _, value := range req.Header.Values(ifNoneMatchHeader) {
+		for t := range strings.SplitSeq(value, ",") {
+			t = strings.TrimSpace(t)
+			if t == anyTag || strings.TrimPrefix(t, "W/") == strings.TrimPrefix(tag, "W/") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// HasNoneMatch returns true if the request has the If-None-Match header.
+func HasNoneMatch(req *http.Request) bool {
+	return len(req.Header.Values(ifNoneMatchHeader)) > 0
+}
+
+// digest returns the hash of the content, encoded to be used in the entity tags.
+func digest(content []byte) string {
+	sum := sha256.Sum256(content)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:hashSize])
+}
+
+// quoted returns true if the tag is a quoted string.
+func quoted(tag string) bool {
+	return len(tag) >= 2 && tag[0] == '"' && tag[len(tag)-1] == '"'
+}
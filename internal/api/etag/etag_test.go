@@ -0,0 +1,108 @@
+package etag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSet(t *testing.T) {
+	t.Parallel()
+	recorder := httptest.NewRecorder()
+	Set(recorder, 42)
+	assert.Equal(t, `"42"`, recorder.Header().Get("ETag"))
+}
+
+func TestIfMatch(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		err     error
+		headers []string
+		version uint
+	}{
+		"version":       {headers: []string{`"3"`}, version: 3},
+		"with content":  {headers: []string{`"3.AbC-_d"`}, version: 3},
+		"spaces":        {headers: []string{` "3" `}, version: 3},
+		"any":           {headers: []string{"*"}},
+		"missing":       {err: ErrMissing},
+		"unquoted":      {headers: []string{"3"}, err: ErrInvalid},
+		"list":          {headers: []string{`"3", "4"`}, err: ErrInvalid},
+		"several":       {headers: []string{`"3"`, `"4"`}, err: ErrInvalid},
+		"weak":          {headers: []string{`W/"3"`}, err: ErrNoMatch},
+		"invalid weak":  {headers: []string{"W/3"}, err: ErrInvalid},
+		"not a version": {headers: []string{`"abc"`}, err: ErrNoMatch},
+		"zero":          {headers: []string{`"0"`}, err: ErrNoMatch},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodPut, "/catalog/PROD001", nil)
+			for _, h := range tc.headers {
+				req.Header.Add("If-Match", h)
+			}
+
+			version, err := IfMatch(req)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.version, version)
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	t.Parallel()
+	tag := Hash([]byte(`{"code":"PROD001"}`))
+	assert.Equal(t, tag, Hash([]byte(`{"code":"PROD001"}`)))
+	assert.NotEqual(t, tag, Hash([]byte(`{"code":"PROD002"}`)))
+	assert.Regexp(t, `^"[A-Za-z0-9_-]+"$`, tag)
+}
+
+func TestFormatContent(t *testing.T) {
+	t.Parallel()
+	tag := FormatContent(3, []byte(`{"code":"PROD001"}`))
+	assert.NotEqual(t, tag, FormatContent(3, []byte(`{"code":"PROD002"}`)))
+	assert.Regexp(t, `^"3\.[A-Za-z0-9_-]+"$`, tag)
+
+	req := httptest.NewRequest(http.MethodPut, "/catalog/PROD001", nil)
+	req.Header.Set("If-Match", tag)
+	version, err := IfMatch(req)
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), version)
+}
+
+func TestInNoneMatch(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		headers []string
+		match   bool
+	}{
+		"missing":  {},
+		"same":     {headers: []string{`"3"`}, match: true},
+		"weak":     {headers: []string{`W/"3"`}, match: true},
+		"list":     {headers: []string{`"1", "3"`}, match: true},
+		"several":  {headers: []string{`"1"`, `"3"`}, match: true},
+		"any":      {headers: []string{"*"}, match: true},
+		"other":    {headers: []string{`"4"`}},
+		"unquoted": {headers: []string{"3"}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodGet, "/catalog/PROD001", nil)
+			for _, h := range tc.headers {
+				req.Header.Add("If-None-Match", h)
+			}
+
+			assert.Equal(t, len(tc.headers) > 0, HasNoneMatch(req))
+			assert.Equal(t, tc.match, InNoneMatch(req, `"3"`))
+		})
+	}
+}
@@ -1,17 +1,37 @@
-// Package catalog implements the catalog handler for the API.
-// It defines just one endpoint to get the whole catalog of products from the products repository.
+// Package catalog implements the catalog handler for the API, with the products, their
+// variants and their scheduled prices. The lists of products are paginated, filtered by
+// their fields, their category and their stock, sorted, or searched by text, and the
+// products are read one by one, with their prices converted to the requested currency.
+// The writes of the products and their variants and prices are checked against their
+// versions.
 package catalog
 
 import (
+	"context"
 	"errors"
+	"maps"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/shopspring/decimal"
 
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/cursor"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/etag"
 	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/response"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/category"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/currency"
 	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/filter"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/inventory"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/page"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/price"
 	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/product"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/search"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/sorting"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/variant"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/storage"
 )
 
 const (
@@ -20,42 +40,155 @@ const (
 
 	offsetParamName = "offset"
 	defaultOffset   = 0
+
+	sortParamName = "sort"
+
+	updatedSinceParamName = "updated_since"
+
+	// searchParamName is the parameter with the full text search query.
+	searchParamName = "q"
+
+	// currencyParamName is the parameter with the currency of the prices of the response.
+	currencyParamName = "currency"
+
+	// onSaleParamName is the parameter to filter the products by their price being a sale,
+	// or not.
+	onSaleParamName = "on_sale"
+
+	// inStockParamName is the parameter to filter the products by some variant having
+	// units available, or none of them.
+	inStockParamName = "in_stock"
+
+	// descendantsParamName is the parameter to include the products of the descendants
+	// of the category filtered.
+	descendantsParamName = "descendants"
+
+	// attributeParamPrefix is the prefix of the parameters filtering the products by an
+	// attribute, like attr.material=cotton.
+	attributeParamPrefix = "attr."
 )
 
+// errSearchSort is returned when a search has a sort or a cursor, as the results are
+// sorted by relevance and paginated by offset.
+var errSearchSort = errors.New("the searches can't be sorted or paginated with cursors")
+
+// Price response from the API, with the ISO 4217 code of its currency.
+type Price struct {
+	Amount   decimal.Decimal `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
 // Category response from the API.
 type Category struct {
 	Name string `json:"name"`
 	Code string `json:"code"`
 }
 
+// Variant response from the API. The price is the one valid at the time of the request,
+// and the compare at price is the original price if it's a sale.
 type Variant struct {
-	Name  string `json:"name"`
-	SKU   string
-	Price decimal.Decimal
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	CompareAtPrice *Price    `json:"compare_at_price,omitempty"`
+	Name           string    `json:"name"`
+	SKU            string
+	Price          Price
+	// InStock is true if some units of the variant are available. It's only set in the
+	// lists of variants.
+	InStock *bool `json:"in_stock,omitempty"`
+	Version uint  `json:"version"`
 }
 
+// Product response from the API. The price is the one valid at the time of the request,
+// and the compare at price is the original price if it's a sale.
 type Product struct {
-	Category Category  `json:"category"`
-	Code     string    `json:"code"`
-	Variants []Variant `json:"variants,omitempty"`
-	Price    float64   `json:"price"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	Category    Category          `json:"category"`
+	Breadcrumbs []Category        `json:"breadcrumbs,omitempty"`
+	Code        string            `json:"code"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	// Snippet is the fragment of the name and the description of a product found by a
+	// search, with the matching words highlighted.
+	Snippet        string    `json:"snippet,omitempty"`
+	Variants       []Variant `json:"variants,omitempty"`
+	CompareAtPrice *Price    `json:"compare_at_price,omitempty"`
+	Price          Price     `json:"price"`
+	// InStock is true if some units of any variant are available. It's only set in the
+	// lists of products.
+	InStock *bool `json:"in_stock,omitempty"`
+	Version uint  `json:"version"`
 }
 
 type productsRepository interface {
-	// GetProducts obtains a list of products from the repository with a limit and an offset.
-	GetProducts(limit, offset int, filters ...filter.Filter) ([]product.Product, error)
+	// GetProducts obtains a page of sorted products from the repository, and the total
+	// number of products matching the filters.
+	GetProducts(ctx context.Context, pg page.Page, sorts []sorting.Sort,
+		filters ...filter.Filter) ([]product.Product, int64, error)
+	// SearchProducts obtains a page of the products matching the full text search query
+	// and the filters, sorted by relevance, and the total number of matching products.
+	SearchProducts(ctx context.Context, query string, pg page.Page,
+		filters ...filter.Filter) ([]search.Result, int64, error)
 	// GetProduct obtains a product from the storage by its code.
-	GetProduct(productCode string) (*product.Product, error)
+	GetProduct(ctx context.Context, productCode string) (*product.Product, error)
+	// CreateProduct adds a new product, with its variants, to the storage. The category is
+	// looked up by its code.
+	CreateProduct(ctx context.Context, prod *product.Product) error
+	// UpdateProduct updates the name, the description, the attributes, the price with its
+	// currency and the category of the product with the same code. If the product has a
+	// version, it fails with a version mismatch error if the stored product has a
+	// different one.
+	UpdateProduct(ctx context.Context, prod *product.Product) error
+	// DeleteProduct removes a product, with its variants, from the storage. If the version
+	// isn't zero, it fails with a version mismatch error if the product has a different one.
+	DeleteProduct(ctx context.Context, productCode string, version uint) error
+	// CreateVariant adds a new variant to the product with the given code.
+	CreateVariant(ctx context.Context, productCode string, v *variant.Variant) error
+	// UpdateVariant updates the name and the price, with its currency, of the variant of
+	// the product with the same SKU. If the variant has a version, it fails with a version
+	// mismatch error if the stored variant has a different one.
+	UpdateVariant(ctx context.Context, productCode string, v *variant.Variant) error
+	// DeleteVariant removes the variant with the given SKU from the product. If the version
+	// isn't zero, it fails with a version mismatch error if the variant has a different one.
+	DeleteVariant(ctx context.Context, productCode, sku string, version uint) error
+	// CreatePrice adds a scheduled price to the product with the given code, or to its
+	// variant if the price has a SKU.
+	CreatePrice(ctx context.Context, productCode string, p *price.Price) error
+	// DeletePrice removes the scheduled price with the given id from the product.
+	DeletePrice(ctx context.Context, productCode string, id uint) error
+	// GetStocks obtains the stock of the variants with the given SKUs, ignoring the
+	// unknown ones.
+	GetStocks(ctx context.Context, skus []string) ([]inventory.Stock, error)
+	// WithTx runs fn as a unit of work, with a storage bound to a transaction.
+	WithTx(ctx context.Context, fn func(tx storage.Storage) error) error
+}
+
+// priceConverter converts the prices to other currencies.
+type priceConverter interface {
+	// Convert returns the amount in the from currency converted to the to currency,
+	// rounded to the decimals of the to currency.
+	Convert(amount decimal.Decimal, from, to string) (decimal.Decimal, error)
 }
 
 type Handler struct {
-	repo productsRepository
+	repo    productsRepository
+	cursors *cursor.Codec
+	prices  priceConverter
+	// now returns the time of the requests, used to resolve the valid prices.
+	now func() time.Time
 }
 
-// NewHandler returns a new api handler.
-func NewHandler(r productsRepository) *Handler {
+// NewHandler returns a new api handler. The cursors codec encodes and decodes the
+// cursors used to paginate the products, and the converter converts the prices to the
+// currency requested.
+func NewHandler(r productsRepository, cursors *cursor.Codec, prices priceConverter) *Handler {
 	return &Handler{
-		repo: r,
+		repo:    r,
+		cursors: cursors,
+		prices:  prices,
+		now:     time.Now,
 	}
 }
 
@@ -64,7 +197,12 @@ type ProductResponse struct {
 	Product Product `json:"product"`
 }
 
-// HandleGetProduct handles the get of a product by its code.
+// HandleGetProduct handles the get of a product by its code, with the prices valid at the
+// time of the request, optionally converted to another currency. The entity tag of the
+// response is the version of the product, to make the writes conditional on it, and it
+// isn't sent again if the client already has it. The converted prices change with the
+// exchange rates, and the scheduled prices change when they start or end, so the entity
+// tag of those products has the hash of the response too.
 func (h *Handler) HandleGetProduct(w http.ResponseWriter, req *http.Request) {
 	productCode := req.PathValue("code")
 	if productCode == "" {
@@ -73,7 +211,14 @@ func (h *Handler) HandleGetProduct(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	res, err := h.repo.GetProduct(productCode)
+	target, err := h.getCurrency(req)
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	res, err := h.repo.GetProduct(req.Context(), productCode)
 	if err != nil {
 		if errors.Is(err, product.ErrNotFound) {
 			response.ErrorResponse(w, http.StatusNotFound, err.Error())
@@ -84,64 +229,457 @@ func (h *Handler) HandleGetProduct(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	now := h.now()
+	resp := newProductResponse(res, now)
+	validators := response.Validators{
+		ETag:         etag.Format(res.Version),
+		LastModified: res.UpdatedAt,
+	}
+	if _, scheduled := res.Prices.NextChange(now); scheduled {
+		validators = response.Validators{Version: res.Version}
+	}
+
+	if target != "" {
+		if err := h.convertProduct(&resp.Product, target); err != nil {
+			response.ErrorResponse(w, http.StatusServiceUnavailable, err.Error())
+
+			return
+		}
+
+		validators = response.Validators{Version: res.Version}
+	}
+
+	response.CachedResponse(w, req, resp, validators)
+}
+
+// newProductResponse returns the API response of the product, with its variants and the
+// prices valid at the given time.
+func newProductResponse(res *product.Product, now time.Time) ProductResponse {
+	current, compareAt := newPrices(res.ActivePrice(now))
 	resp := ProductResponse{
 		Product: Product{
-			Code: res.Code,
-			Category: Category{
-				Name: res.Category.Name,
-				Code: res.Category.Code,
-			},
-			Price:    res.Price.InexactFloat64(),
-			Variants: make([]Variant, 0),
+			CreatedAt:      res.CreatedAt,
+			UpdatedAt:      res.UpdatedAt,
+			Attributes:     res.Attributes,
+			Code:           res.Code,
+			Name:           res.Name,
+			Description:    res.Description,
+			Price:          current,
+			CompareAtPrice: compareAt,
+			Variants:       make([]Variant, 0),
+			Version:        res.Version,
+			Breadcrumbs:    newBreadcrumbs(res.Category),
 		},
 	}
+	if res.Category != nil {
+		resp.Product.Category = Category{
+			Name: res.Category.Name,
+			Code: res.Category.Code,
+		}
+	}
+
 	for i := range res.Variants {
-		variant := res.Variants[i]
-		price := variant.Price
-		if price.IsZero() {
-			price = res.Price
+		resp.Product.Variants = append(resp.Product.Variants,
+			newVariant(&res.Variants[i], res, now))
+	}
+
+	return resp
+}
+
+// newVariant returns the API variant with its price valid at the given time, inheriting
+// the price of the product if the variant doesn't have price.
+func newVariant(v *variant.Variant, prod *product.Product, now time.Time) Variant {
+	current, compareAt := newPrices(prod.VariantPrice(v, now))
+
+	return Variant{
+		CreatedAt:      v.CreatedAt,
+		UpdatedAt:      v.UpdatedAt,
+		Name:           v.Name,
+		SKU:            v.SKU,
+		Price:          current,
+		CompareAtPrice: compareAt,
+		Version:        v.Version,
+	}
+}
+
+// newPrices returns the API price of the model price, and its original price if it's a
+// sale.
+func newPrices(p price.Price) (Price, *Price) {
+	current := Price{Amount: p.Amount, Currency: p.Currency}
+	if !p.OnSale() {
+		return current, nil
+	}
+
+	return current, &Price{Amount: p.CompareAt, Currency: p.Currency}
+}
+
+// convertProduct converts the prices of the API product, and of its variants, to the
+// currency.
+func (h *Handler) convertProduct(p *Product, to string) error {
+	if err := h.convertPrices(to, &p.Price, p.CompareAtPrice); err != nil {
+		return err
+	}
+
+	for i := range p.Variants {
+		v := &p.Variants[i]
+		if err := h.convertPrices(to, &v.Price, v.CompareAtPrice); err != nil {
+			return err
 		}
+	}
 
-		resp.Product.Variants = append(resp.Product.Variants, Variant{
-			Name:  variant.Name,
-			SKU:   variant.SKU,
-			Price: price,
-		})
+	return nil
+}
+
+// convertPrices converts the API prices to the currency, ignoring the missing ones.
+func (h *Handler) convertPrices(to string, prices ...*Price) error {
+	for _, p := range prices {
+		if p == nil {
+			continue
+		}
+
+		if err := h.convertPrice(p, to); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// convertPrice converts the API price to the currency. It fails if the exchange rates
+// weren't loaded or they don't have the currencies, which isn't an error of the request.
+func (h *Handler) convertPrice(p *Price, to string) error {
+	amount, err := h.prices.Convert(p.Amount, p.Currency, to)
+	if err != nil {
+		return err
+	}
+
+	*p = Price{Amount: amount, Currency: to}
+
+	return nil
+}
+
+// getCurrency returns the currency of the prices of the response, or an empty string to
+// return them in their own currency.
+func (h *Handler) getCurrency(req *http.Request) (string, error) {
+	code := strings.ToUpper(h.getQueryParam(req, currencyParamName))
+	if code == "" {
+		return "", nil
+	}
+
+	if err := currency.Validate(code); err != nil {
+		return "", err
 	}
 
-	response.OKResponse(w, resp)
+	return code, nil
 }
 
 // ProductsResponse defines the API response for the list of product.
 type ProductsResponse struct {
-	Products    []Product `json:"products"`
-	NumProducts int       `json:"total"`
-	Offset      int       `json:"offset"`
+	Products []Product `json:"products"`
+	// NumProducts is the total number of products matching the filters.
+	NumProducts int64 `json:"total"`
+	// Count is the number of products in the page.
+	Count  int `json:"count"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	// NextCursor and PrevCursor are the cursors to fetch the next and previous pages.
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
 
 // HandleGetProducts handle the get of a list of products.
-// It accepts a page limit and an offset, and it returns the list of products, the
-// offset and the number of products returned.
+// It accepts a page limit, either an offset or a cursor, the sort fields and the
+// filters, like the products updated since a time or on sale, and it returns the list
+// of products with the prices valid at the time of the request, the offset, the limit,
+// the number of products returned, the total number of products matching the filters
+// and the cursors to the next and previous pages. With a search query, it returns the
+// matching products instead. The prices are converted to the currency of the request,
+// if any. The list isn't sent again if the client already has it.
 func (h *Handler) HandleGetProducts(w http.ResponseWriter, req *http.Request) {
-	limit, err := h.getIntQueryParam(req, limitParamName, defaultLimit)
+	target, err := h.getCurrency(req)
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	if req.URL.Query().Has(searchParamName) {
+		h.handleSearchProducts(w, req, target)
+
+		return
+	}
+
+	sorts, err := sorting.Parse(h.getQueryParam(req, sortParamName))
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	sorts = convertedPriceSorts(sorts, target)
+	pg, err := h.getPage(req, sorts)
 	if err != nil {
 		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
 
 		return
 	}
 
-	offset, err := h.getIntQueryParam(req, offsetParamName, defaultOffset)
+	filters, err := h.getFilters(req, target)
 	if err != nil {
 		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
 
 		return
 	}
 
+	limit := pg.Limit
+	if limit >= 0 {
+		pg.Limit++
+	}
+
+	res, total, err := h.repo.GetProducts(req.Context(), pg, sorts, filters...)
+	if err != nil {
+		response.ErrorResponse(w, listErrorStatus(err), err.Error())
+
+		return
+	}
+
+	now := h.now()
+	pg.Limit = limit
+	res, next, prev, err := h.pageCursors(res, pg, sorts, now)
+	if err != nil {
+		response.ErrorResponse(w, listErrorStatus(err), err.Error())
+
+		return
+	}
+
+	stock, err := h.getStock(req.Context(), res...)
+	if err != nil {
+		response.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+
+		return
+	}
+
+	// Map the response.
+	var lastModified time.Time
+	products := make([]Product, 0, len(res))
+	for i := range res {
+		if res[i].UpdatedAt.After(lastModified) {
+			lastModified = res[i].UpdatedAt
+		}
+
+		p := newListProduct(&res[i], now)
+		p.InStock = productInStock(&res[i], stock)
+		if target != "" {
+			if err := h.convertPrices(target, &p.Price, p.CompareAtPrice); err != nil {
+				response.ErrorResponse(w, http.StatusServiceUnavailable, err.Error())
+
+				return
+			}
+		}
+
+		products = append(products, p)
+	}
+
+	// Return the products.
+	resp := ProductsResponse{
+		NumProducts: total,
+		Count:       len(products),
+		Limit:       pg.Limit,
+		Offset:      pg.Offset,
+		Products:    products,
+		NextCursor:  next,
+		PrevCursor:  prev,
+	}
+
+	response.CachedResponse(w, req, resp, response.Validators{
+		LastModified: lastModified,
+		List:         true,
+	})
+}
+
+// handleSearchProducts handles the full text search of products. It accepts the same
+// filters and page parameters as the list of products, but the results are sorted by
+// relevance and only paginated by offset, and they have a snippet highlighting the
+// matching words. The prices are converted to the target currency, if any.
+func (h *Handler) handleSearchProducts(w http.ResponseWriter, req *http.Request,
+	target string,
+) {
+	if h.getQueryParam(req, sortParamName) != "" || h.getQueryParam(req, cursorParamName) != "" {
+		response.ErrorResponse(w, http.StatusBadRequest, errSearchSort.Error())
+
+		return
+	}
+
+	pg, err := h.getPage(req, nil)
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	filters, err := h.getFilters(req, target)
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	res, total, err := h.repo.SearchProducts(req.Context(), h.getQueryParam(req, searchParamName),
+		pg, filters...)
+	if err != nil {
+		response.ErrorResponse(w, listErrorStatus(err), err.Error())
+
+		return
+	}
+
+	found := make([]product.Product, 0, len(res))
+	for i := range res {
+		found = append(found, res[i].Product)
+	}
+
+	stock, err := h.getStock(req.Context(), found...)
+	if err != nil {
+		response.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+
+		return
+	}
+
+	now := h.now()
+	var lastModified time.Time
+	products := make([]Product, 0, len(res))
+	for i := range res {
+		if res[i].Product.UpdatedAt.After(lastModified) {
+			lastModified = res[i].Product.UpdatedAt
+		}
+
+		p := newListProduct(&res[i].Product, now)
+		p.Snippet = res[i].Snippet
+		p.InStock = productInStock(&res[i].Product, stock)
+		if target != "" {
+			if err := h.convertPrices(target, &p.Price, p.CompareAtPrice); err != nil {
+				response.ErrorResponse(w, http.StatusServiceUnavailable, err.Error())
+
+				return
+			}
+		}
+
+		products = append(products, p)
+	}
+
+	resp := ProductsResponse{
+		NumProducts: total,
+		Count:       len(products),
+		Limit:       pg.Limit,
+		Offset:      pg.Offset,
+		Products:    products,
+	}
+
+	response.CachedResponse(w, req, resp, response.Validators{
+		LastModified: lastModified,
+		List:         true,
+	})
+}
+
+// getStock returns the SKUs of the variants of the products with some units available.
+func (h *Handler) getStock(ctx context.Context,
+	products ...product.Product,
+) (map[string]bool, error) {
+	skus := make([]string, 0, len(products))
+	for i := range products {
+		for _, v := range products[i].Variants {
+			skus = append(skus, v.SKU)
+		}
+	}
+
+	if len(skus) == 0 {
+		return map[string]bool{}, nil
+	}
+
+	levels, err := h.repo.GetStocks(ctx, skus)
+	if err != nil {
+		return nil, err
+	}
+
+	stock := make(map[string]bool, len(levels))
+	for i := range levels {
+		stock[levels[i].SKU] = levels[i].InStock()
+	}
+
+	return stock, nil
+}
+
+// productInStock returns true if some variant of the product has units available.
+func productInStock(p *product.Product, stock map[string]bool) *bool {
+	inStock := slices.ContainsFunc(p.Variants, func(v variant.Variant) bool {
+		return stock[v.SKU]
+	})
+
+	return &inStock
+}
+
+// newListProduct returns the API product of a list, without its variants, with the price
+// valid at the given time.
+func newListProduct(p *product.Product, now time.Time) Product {
+	current, compareAt := newPrices(p.ActivePrice(now))
+
+	return Product{
+		CreatedAt:      p.CreatedAt,
+		UpdatedAt:      p.UpdatedAt,
+		Attributes:     p.Attributes,
+		Code:           p.Code,
+		Name:           p.Name,
+		Description:    p.Description,
+		Price:          current,
+		CompareAtPrice: compareAt,
+		Version:        p.Version,
+		Category: Category{
+			Name: p.Category.Name,
+			Code: p.Category.Code,
+		},
+		Breadcrumbs: newBreadcrumbs(p.Category),
+	}
+}
+
+// newBreadcrumbs returns the API categories from the root of the category tree to the
+// category.
+func newBreadcrumbs(cat *category.Category) []Category {
+	if cat == nil {
+		return nil
+	}
+
+	path := cat.Path()
+	res := make([]Category, 0, len(path))
+	for i := range path {
+		res = append(res, Category{Name: path[i].Name, Code: path[i].Code})
+	}
+
+	return res
+}
+
+// getFilters returns the filters of the request: the category, optionally with its
+// descendants, the maximum price, the minimum update time, the sales and the
+// attributes. The price filter applies to the price of the products valid now, their
+// scheduled price or otherwise their base price, converted to the target currency, or
+// to the default one, so the prices in different currencies are compared.
+func (h *Handler) getFilters(req *http.Request, target string) ([]filter.Filter, error) {
+	descendants := false
+	if param := h.getQueryParam(req, descendantsParamName); param != "" {
+		var err error
+		if descendants, err = strconv.ParseBool(param); err != nil {
+			return nil, errors.New("descendants must be a boolean")
+		}
+	}
+
 	filters := make([]filter.Filter, 0)
 	cat := h.getQueryParam(req, "category")
 	if cat != "" {
+		key := "category"
+		if descendants {
+			key = "category_tree"
+		}
+
 		filters = append(filters, filter.Filter{
-			Key:       "category",
+			Key:       key,
 			Value:     cat,
 			Operation: filter.Equal,
 		})
@@ -150,41 +688,124 @@ func (h *Handler) HandleGetProducts(w http.ResponseWriter, req *http.Request) {
 	price := h.getQueryParam(req, "price")
 	if price != "" {
 		filters = append(filters, filter.Filter{
-			Key:       "price",
+			Key:       convertedPriceField(target),
 			Value:     price,
 			Operation: filter.LessThan,
 		})
 	}
 
-	res, err := h.repo.GetProducts(limit, offset, filters...)
-	if err != nil {
-		response.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+	updatedSince := h.getQueryParam(req, updatedSinceParamName)
+	if updatedSince != "" {
+		if _, err := time.Parse(time.RFC3339Nano, updatedSince); err != nil {
+			return nil, errors.New("updated_since must be a RFC 3339 timestamp")
+		}
 
-		return
+		filters = append(filters, filter.Filter{
+			Key:       "updated_at",
+			Value:     updatedSince,
+			Operation: filter.GreaterOrEqual,
+		})
 	}
 
-	// Map the response.
-	products := make([]Product, 0, len(res))
-	for i := range res {
-		p := res[i]
-		products = append(products, Product{
-			Code:  p.Code,
-			Price: p.Price.InexactFloat64(),
-			Category: Category{
-				Name: p.Category.Name,
-				Code: p.Category.Code,
-			},
+	if param := h.getQueryParam(req, onSaleParamName); param != "" {
+		onSale, err := strconv.ParseBool(param)
+		if err != nil {
+			return nil, errors.New("on_sale must be a boolean")
+		}
+
+		filters = append(filters, filter.Filter{
+			Key:       "on_sale",
+			Value:     strconv.FormatBool(onSale),
+			Operation: filter.Equal,
 		})
 	}
 
-	// Return the products.
-	resp := ProductsResponse{
-		NumProducts: len(products),
-		Offset:      offset,
-		Products:    products,
+	if param := h.getQueryParam(req, inStockParamName); param != "" {
+		inStock, err := strconv.ParseBool(param)
+		if err != nil {
+			return nil, errors.New("in_stock must be a boolean")
+		}
+
+		filters = append(filters, filter.Filter{
+			Key:       "in_stock",
+			Value:     strconv.FormatBool(inStock),
+			Operation: filter.Equal,
+		})
+	}
+
+	return append(filters, attributeFilters(req)...), nil
+}
+
+// convertedPriceField returns the field of the prices converted to the target currency,
+// or to the default one without target.
+func convertedPriceField(target string) string {
+	if target == "" {
+		target = currency.Default
 	}
 
-	response.OKResponse(w, resp)
+	return product.PriceField(target)
+}
+
+// convertedPriceSorts returns the sorts with the price sorts replaced by the sorts of
+// the prices converted to the target currency, or to the default one.
+func convertedPriceSorts(sorts []sorting.Sort, target string) []sorting.Sort {
+	res := slices.Clone(sorts)
+	for i := range res {
+		if res[i].Field == "price" {
+			res[i].Field = convertedPriceField(target)
+		}
+	}
+
+	return res
+}
+
+// attributeFilters returns the filters of the attribute parameters, sorted by key. An
+// attribute with several values matches any of them.
+func attributeFilters(req *http.Request) []filter.Filter {
+	params := req.URL.Query()
+	filters := make([]filter.Filter, 0)
+	for _, name := range slices.Sorted(maps.Keys(params)) {
+		key, ok := strings.CutPrefix(name, attributeParamPrefix)
+		if !ok {
+			continue
+		}
+
+		f := filter.Filter{Key: product.AttributeField(key), Operation: filter.Equal}
+		if values := params[name]; len(values) > 1 {
+			f.Operation = filter.In
+			f.Values = values
+		} else {
+			f.Value = values[0]
+		}
+
+		filters = append(filters, f)
+	}
+
+	return filters
+}
+
+// listErrorStatus returns the status of the errors listing the products: 400 if it's
+// caused by the request parameters, 503 if a price can't be converted to compare it,
+// and 500 otherwise.
+func listErrorStatus(err error) int {
+	switch {
+	case isBadRequest(err):
+		return http.StatusBadRequest
+	case errors.Is(err, currency.ErrUnknownRate):
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusInternalServerError
+}
+
+// isBadRequest returns true if the error is caused by the request parameters.
+func isBadRequest(err error) bool {
+	var filterErr *filter.FieldError
+	var sortErr *sorting.FieldError
+
+	return errors.As(err, &filterErr) || errors.As(err, &sortErr) ||
+		errors.Is(err, filter.ErrInvalidFilter) || errors.Is(err, sorting.ErrInvalidSort) ||
+		errors.Is(err, page.ErrInvalidCursor) || errors.Is(err, search.ErrInvalidQuery)
 }
 
 func (h *Handler) getIntQueryParam(req *http.Request, name string, defaultValue int) (int, error) {
@@ -1,34 +1,573 @@
 package catalog
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
-	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
-	"github.com/testcontainers/testcontainers-go"
-	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	"gitlab.com/flimzy/testy"
 
-	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/storage/database"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/cursor"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/handler/handlertest"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/category"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/inventory"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/storage"
 )
 
-const (
-	dbName     = "users"
-	dbUser     = "user"
-	dbPassword = "password"
-)
+const cursorSecret = "secret"
 
-// nolint: funlen
 func TestProductDetailsHandler(t *testing.T) {
 	t.Parallel()
-	tests := []struct {
-		name               string
-		product            string
-		expectedStatusCode int
-	}{
+	handlertest.Run(t, func(t *testing.T, kind handlertest.Kind) {
+		tests := productDetailsTests()
+		for i := range tests {
+			tc := tests[i]
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+				ctx := context.TODO()
+				req, err := http.NewRequestWithContext(ctx, "GET",
+					fmt.Sprintf("/catalog/%s", tc.product), nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				repo := handlertest.New(ctx, t, kind)
+				checkResponse(t, doRequest(t, repo, req), tc.expectedStatusCode)
+			})
+		}
+	})
+}
+
+func TestProducsListHandler(t *testing.T) {
+	t.Parallel()
+	handlertest.Run(t, func(t *testing.T, kind handlertest.Kind) {
+		tests := productsListTests()
+		for i := range tests {
+			tc := tests[i]
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+				ctx := context.TODO()
+				req, err := http.NewRequestWithContext(ctx, "GET", tc.request, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				repo := handlertest.New(ctx, t, kind)
+				checkResponse(t, doRequest(t, repo, req), tc.expectedStatusCode)
+			})
+		}
+	})
+}
+
+func TestProductsSearchHandler(t *testing.T) {
+	t.Parallel()
+	handlertest.Run(t, func(t *testing.T, kind handlertest.Kind) {
+		// The searches don't modify the products, so they share the storage.
+		ctx := context.TODO()
+		repo := handlertest.New(ctx, t, kind)
+		tests := productsSearchTests()
+		for i := range tests {
+			tc := tests[i]
+			t.Run(tc.name, func(t *testing.T) {
+				checkSearch(ctx, t, repo, &tc)
+			})
+		}
+	})
+}
+
+func TestProductWriteHandler(t *testing.T) {
+	t.Parallel()
+	handlertest.Run(t, func(t *testing.T, kind handlertest.Kind) {
+		tests := productWriteTests()
+		for i := range tests {
+			tc := tests[i]
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+				ctx := context.TODO()
+				checkWrite(ctx, t, handlertest.New(ctx, t, kind), &tc)
+			})
+		}
+	})
+}
+
+func TestProducsListCursors(t *testing.T) {
+	t.Parallel()
+	handlertest.Run(t, func(t *testing.T, kind handlertest.Kind) {
+		ctx := context.TODO()
+		checkCursors(ctx, t, handlertest.New(ctx, t, kind))
+	})
+}
+
+// checkCursors walks all the pages of the products forwards and backwards following
+// the cursors.
+func checkCursors(ctx context.Context, t *testing.T, repo productsRepository) {
+	t.Helper()
+	codes := make([]string, 0)
+	request := "/catalog?limit=3"
+	pages := []ProductsResponse{}
+	for request != "" {
+		resp := getProducts(ctx, t, repo, request)
+		pages = append(pages, resp)
+		for i := range resp.Products {
+			codes = append(codes, resp.Products[i].Code)
+		}
+
+		request = ""
+		if resp.NextCursor != "" {
+			request = "/catalog?limit=3&cursor=" + resp.NextCursor
+		}
+	}
+
+	expected := []string{
+		"PROD001", "PROD002", "PROD003", "PROD004", "PROD005", "PROD006", "PROD007", "PROD008",
+	}
+	if !slices.Equal(expected, codes) {
+		t.Fatalf("Unexpected products: expected %v, got %v", expected, codes)
+	}
+
+	if len(pages) != 3 || pages[0].PrevCursor != "" {
+		t.Fatalf("Unexpected pages: %+v", pages)
+	}
+
+	resp := getProducts(ctx, t, repo, "/catalog?limit=3&cursor="+pages[2].PrevCursor)
+	if resp.Products[0].Code != "PROD004" || resp.PrevCursor == "" || resp.NextCursor == "" {
+		t.Fatalf("Unexpected previous page: %+v", resp)
+	}
+
+	resp = getProducts(ctx, t, repo, "/catalog?limit=3&cursor="+resp.PrevCursor)
+	if resp.Products[0].Code != "PROD001" || resp.PrevCursor != "" {
+		t.Fatalf("Unexpected first page: %+v", resp)
+	}
+}
+
+func TestProducsListSortedCursors(t *testing.T) {
+	t.Parallel()
+	handlertest.Run(t, func(t *testing.T, kind handlertest.Kind) {
+		ctx := context.TODO()
+		checkSortedCursors(ctx, t, handlertest.New(ctx, t, kind))
+	})
+}
+
+// checkSortedCursors walks all the pages of the products sorted by price following the
+// cursors.
+func checkSortedCursors(ctx context.Context, t *testing.T, repo productsRepository) {
+	t.Helper()
+	codes := make([]string, 0)
+	request := "/catalog?limit=3&sort=-price"
+	for request != "" {
+		resp := getProducts(ctx, t, repo, request)
+		for i := range resp.Products {
+			codes = append(codes, resp.Products[i].Code)
+		}
+
+		request = ""
+		if resp.NextCursor != "" {
+			request = "/catalog?limit=3&sort=-price&cursor=" + resp.NextCursor
+		}
+	}
+
+	expected := []string{
+		"PROD005", "PROD007", "PROD004", "PROD002", "PROD001", "PROD008", "PROD003", "PROD006",
+	}
+	if !slices.Equal(expected, codes) {
+		t.Fatalf("Unexpected products: expected %v, got %v", expected, codes)
+	}
+}
+
+func TestProducsListMixedCurrencies(t *testing.T) {
+	t.Parallel()
+	handlertest.Run(t, func(t *testing.T, kind handlertest.Kind) {
+		ctx := context.TODO()
+		checkMixedCurrencies(ctx, t, handlertest.New(ctx, t, kind))
+	})
+}
+
+// checkMixedCurrencies checks the products priced in different currencies are sorted
+// and filtered by their prices converted to the requested currency, without leaving out
+// any of them.
+func checkMixedCurrencies(ctx context.Context, t *testing.T, repo productsRepository) {
+	t.Helper()
+	// PROD006 costs 9.23 euros and PROD005 18.45 euros.
+	for code, body := range map[string]string{
+		"PROD006": `{"price": 1500, "currency": "JPY"}`,
+		"PROD005": `{"price": 20, "currency": "USD"}`,
+	} {
+		req, err := http.NewRequestWithContext(ctx, "PATCH", "/catalog/"+code,
+			strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("If-Match", "*")
+		if recorder := doRequest(t, repo, req); recorder.Code != http.StatusOK {
+			t.Fatalf("Unable to update the product: %d - %s", recorder.Code, recorder.Body)
+		}
+	}
+
+	expected := []string{
+		"PROD003", "PROD006", "PROD008", "PROD001", "PROD002", "PROD004", "PROD007", "PROD005",
+	}
+	resp := getProducts(ctx, t, repo, "/catalog?sort=price&limit=-1")
+	if codes := productCodes(resp.Products); !slices.Equal(expected, codes) ||
+		resp.NumProducts != int64(len(expected)) {
+		t.Fatalf("Unexpected products: expected %v, got %v of %d", expected, codes,
+			resp.NumProducts)
+	}
+
+	codes := make([]string, 0)
+	request := "/catalog?limit=3&sort=price"
+	for request != "" {
+		resp := getProducts(ctx, t, repo, request)
+		codes = append(codes, productCodes(resp.Products)...)
+		request = ""
+		if resp.NextCursor != "" {
+			request = "/catalog?limit=3&sort=price&cursor=" + resp.NextCursor
+		}
+	}
+
+	if !slices.Equal(expected, codes) {
+		t.Fatalf("Unexpected pages: expected %v, got %v", expected, codes)
+	}
+
+	// PROD006 costs 10.01 dollars.
+	for request, expected := range map[string][]string{
+		"/catalog?price=10":              {"PROD003", "PROD006", "PROD008"},
+		"/catalog?price=10&currency=USD": {"PROD003"},
+	} {
+		resp := getProducts(ctx, t, repo, request+"&sort=price")
+		if codes := productCodes(resp.Products); !slices.Equal(expected, codes) {
+			t.Errorf("Unexpected products of %s: expected %v, got %v", request, expected,
+				codes)
+		}
+	}
+}
+
+// productCodes returns the codes of the products.
+func productCodes(products []Product) []string {
+	codes := make([]string, 0, len(products))
+	for i := range products {
+		codes = append(codes, products[i].Code)
+	}
+
+	return codes
+}
+
+func TestCategoryDescendants(t *testing.T) {
+	t.Parallel()
+	handlertest.Run(t, func(t *testing.T, kind handlertest.Kind) {
+		ctx := context.TODO()
+		checkCategoryDescendants(ctx, t, handlertest.New(ctx, t, kind))
+	})
+}
+
+// checkCategoryDescendants moves a product to a subcategory and lists the products of
+// the ancestors of its category.
+func checkCategoryDescendants(ctx context.Context, t *testing.T, repo storage.Storage) {
+	t.Helper()
+	// Move the t-shirt to the Clothing > Shirts > Polos category.
+	shirts := category.Category{Code: "CAT004", Name: "Shirts", ParentCode: "CAT001"}
+	polos := category.Category{Code: "CAT005", Name: "Polos", ParentCode: "CAT004"}
+	for _, cat := range []*category.Category{&shirts, &polos} {
+		if err := repo.AddCategory(ctx, cat); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prod, err := repo.GetProduct(ctx, "PROD001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prod.Category = &polos
+	prod.Version = 0
+	if err = repo.UpdateProduct(ctx, prod); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string][]string{
+		"/catalog?category=1":                  {"PROD004", "PROD007"},
+		"/catalog?category=1&descendants=true": {"PROD001", "PROD004", "PROD007"},
+		"/catalog?category=4&descendants=1":    {"PROD001"},
+	}
+	for request, expected := range tests {
+		resp := getProducts(ctx, t, repo, request)
+		codes := make([]string, 0, len(resp.Products))
+		for i := range resp.Products {
+			codes = append(codes, resp.Products[i].Code)
+		}
+
+		if !slices.Equal(expected, codes) {
+			t.Errorf("Unexpected products of %s: expected %v, got %v", request, expected, codes)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", "/catalog/PROD001", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := doRequest(t, repo, req)
+	var resp ProductResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Category{
+		{Code: "CAT001", Name: "Clothing"}, {Code: "CAT004", Name: "Shirts"},
+		{Code: "CAT005", Name: "Polos"},
+	}
+	if !slices.Equal(expected, resp.Product.Breadcrumbs) {
+		t.Errorf("Unexpected breadcrumbs: expected %v, got %v", expected,
+			resp.Product.Breadcrumbs)
+	}
+}
+
+func TestConditionalGet(t *testing.T) {
+	t.Parallel()
+	handlertest.Run(t, func(t *testing.T, kind handlertest.Kind) {
+		ctx := context.TODO()
+		checkConditionalGet(ctx, t, handlertest.New(ctx, t, kind))
+	})
+}
+
+// checkConditionalGet checks the validators of the product and list responses, and that
+// they change with the product.
+func checkConditionalGet(ctx context.Context, t *testing.T, repo productsRepository) {
+	t.Helper()
+	for _, request := range []string{"/catalog/PROD001", "/catalog?limit=3"} {
+		recorder := doConditionalGet(ctx, t, repo, request, nil)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Unexpected status code: expected %d, got %d", http.StatusOK, recorder.Code)
+		}
+
+		tag := recorder.Header().Get("ETag")
+		lastModified := recorder.Header().Get("Last-Modified")
+		if tag == "" || lastModified == "" {
+			t.Fatalf("Missing validators: ETag %q, Last-Modified %q", tag, lastModified)
+		}
+
+		recorder = doConditionalGet(ctx, t, repo, request, map[string]string{
+			"If-None-Match": tag,
+		})
+		if recorder.Code != http.StatusNotModified || recorder.Body.Len() > 0 {
+			t.Errorf("Unexpected response of %s: %d - %s", request, recorder.Code,
+				recorder.Body)
+		}
+
+		// The changes of the product change the validators.
+		req, err := http.NewRequestWithContext(ctx, "PATCH", "/catalog/PROD001",
+			strings.NewReader(`{"price": 12.5}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("If-Match", "*")
+		if recorder = doRequest(t, repo, req); recorder.Code != http.StatusOK {
+			t.Fatalf("Unable to update the product: %d - %s", recorder.Code, recorder.Body)
+		}
+
+		recorder = doConditionalGet(ctx, t, repo, request, map[string]string{
+			"If-None-Match": tag,
+		})
+		if recorder.Code != http.StatusOK || recorder.Header().Get("ETag") == tag {
+			t.Errorf("Unexpected response of %s after the update: %d - %s", request,
+				recorder.Code, recorder.Header().Get("ETag"))
+		}
+	}
+}
+
+func TestScheduledPrices(t *testing.T) {
+	t.Parallel()
+	handlertest.Run(t, func(t *testing.T, kind handlertest.Kind) {
+		ctx := context.TODO()
+		checkScheduledPrices(ctx, t, handlertest.New(ctx, t, kind))
+	})
+}
+
+// checkScheduledPrices puts a product on sale and checks its entity tag and price before
+// and after the removal of the sale.
+func checkScheduledPrices(ctx context.Context, t *testing.T, repo productsRepository) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, "POST", "/catalog/PROD001/prices",
+		strings.NewReader(`{"amount": "8.99", "compare_at": "10.99",
+			"effective_from": "2020-01-01T00:00:00Z", "effective_to": "2100-01-01T00:00:00Z"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := doRequest(t, repo, req)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Unable to create the price: %d - %s", recorder.Code, recorder.Body)
+	}
+
+	if location := recorder.Header().Get("Location"); location != "/catalog/PROD001/prices/1" {
+		t.Errorf("Unexpected location %q", location)
+	}
+
+	// The price changes when the sale ends, so the entity tag has the hash of the product
+	// too, but it can still be used on the writes.
+	recorder = doConditionalGet(ctx, t, repo, "/catalog/PROD001", nil)
+	tag := recorder.Header().Get("ETag")
+	if recorder.Code != http.StatusOK || !strings.HasPrefix(tag, `"2.`) {
+		t.Errorf("Unexpected response of the product on sale: %d - %s", recorder.Code, tag)
+	}
+
+	if !strings.Contains(recorder.Body.String(), `"compare_at_price"`) {
+		t.Errorf("Missing original price: %s", recorder.Body)
+	}
+
+	// The price filter uses the sale price, the one in the response.
+	recorder = doConditionalGet(ctx, t, repo, "/catalog?on_sale=true&price=9", nil)
+	if body := recorder.Body.String(); recorder.Code != http.StatusOK ||
+		!strings.Contains(body, `"total":1`) || !strings.Contains(body, `"PROD001"`) {
+		t.Errorf("Unexpected products on sale: %d - %s", recorder.Code, body)
+	}
+
+	req, err = http.NewRequestWithContext(ctx, "DELETE", "/catalog/PROD001/prices/1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if recorder = doRequest(t, repo, req); recorder.Code != http.StatusNoContent {
+		t.Fatalf("Unable to delete the price: %d - %s", recorder.Code, recorder.Body)
+	}
+
+	recorder = doConditionalGet(ctx, t, repo, "/catalog/PROD001", nil)
+	if tag := recorder.Header().Get("ETag"); recorder.Code != http.StatusOK || tag != `"3"` {
+		t.Errorf("Unexpected response of the product: %d - %s", recorder.Code, tag)
+	}
+
+	if strings.Contains(recorder.Body.String(), `"compare_at_price"`) {
+		t.Errorf("Unexpected original price: %s", recorder.Body)
+	}
+
+	req, err = http.NewRequestWithContext(ctx, "PATCH", "/catalog/PROD001",
+		strings.NewReader(`{"price": 12.5}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The removal of the price changed the version.
+	req.Header.Set("If-Match", tag)
+	if recorder = doRequest(t, repo, req); recorder.Code != http.StatusPreconditionFailed {
+		t.Errorf("Unexpected response of the stale update: %d - %s", recorder.Code,
+			recorder.Body)
+	}
+}
+
+func TestStock(t *testing.T) {
+	t.Parallel()
+	handlertest.Run(t, func(t *testing.T, kind handlertest.Kind) {
+		ctx := context.TODO()
+		checkStock(ctx, t, handlertest.New(ctx, t, kind))
+	})
+}
+
+// checkStock adds stock to a variant and checks the availability of the products and
+// the variants.
+func checkStock(ctx context.Context, t *testing.T, repo storage.Storage) {
+	t.Helper()
+	if _, err := repo.AdjustStock(ctx, "SKU001B", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := getProducts(ctx, t, repo, "/catalog?in_stock=true")
+	if resp.NumProducts != 1 || resp.Products[0].Code != "PROD001" {
+		t.Fatalf("Unexpected products in stock: %+v", resp.Products)
+	}
+
+	if inStock := resp.Products[0].InStock; inStock == nil || !*inStock {
+		t.Errorf("Unexpected availability of PROD001: %v", inStock)
+	}
+
+	resp = getProducts(ctx, t, repo, "/catalog?in_stock=false&limit=-1")
+	for _, p := range resp.Products {
+		if p.Code == "PROD001" || p.InStock == nil || *p.InStock {
+			t.Errorf("Unexpected product out of stock: %s - %v", p.Code, p.InStock)
+		}
+	}
+
+	recorder := doConditionalGet(ctx, t, repo, "/catalog/PROD001/variants", nil)
+	var variants VariantsResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&variants); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range variants.Variants {
+		if v.InStock == nil || *v.InStock != (v.SKU == "SKU001B") {
+			t.Errorf("Unexpected availability of %s: %v", v.SKU, v.InStock)
+		}
+	}
+
+	// The reserved units aren't available.
+	err := repo.CreateReservation(ctx, &inventory.Reservation{
+		ExpiresAt: time.Now().Add(time.Hour),
+		Lines:     []inventory.Line{{SKU: "SKU001B", Quantity: 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp = getProducts(ctx, t, repo, "/catalog?in_stock=true"); resp.NumProducts != 0 {
+		t.Errorf("Unexpected products in stock: %+v", resp.Products)
+	}
+}
+
+func doConditionalGet(ctx context.Context, t *testing.T, repo productsRepository,
+	request string, headers map[string]string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, "GET", request, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	return doRequest(t, repo, req)
+}
+
+func getProducts(ctx context.Context, t *testing.T, repo productsRepository,
+	request string,
+) ProductsResponse {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, "GET", request, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := doRequest(t, repo, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Unexpected status code: expected %d, got %d: %s", http.StatusOK,
+			recorder.Code, recorder.Body)
+	}
+
+	var resp ProductsResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	return resp
+}
+
+type productDetailsTest struct {
+	name               string
+	product            string
+	expectedStatusCode int
+}
+
+func productDetailsTests() []productDetailsTest {
+	return []productDetailsTest{
 		{
 			name:               "empty product",
 			product:            "",
@@ -44,70 +583,38 @@ func TestProductDetailsHandler(t *testing.T) {
 			product:            "PROD005",
 			expectedStatusCode: http.StatusOK,
 		},
+		{
+			name:               "existing product in dollars",
+			product:            "PROD005?currency=usd",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "existing product in yens",
+			product:            "PROD004?currency=JPY",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "with invalid currency",
+			product:            "PROD005?currency=dollars",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "with currency without exchange rate",
+			product:            "PROD005?currency=GBP",
+			expectedStatusCode: http.StatusServiceUnavailable,
+		},
 	}
+}
 
-	for i := range tests {
-		tc := tests[i]
-		t.Run(tc.name, func(t *testing.T) {
-			t.Parallel()
-
-			// Storage initialization.
-			ctx := context.TODO()
-			postgresContainer := startDatabase(ctx, t)
-			defer stopDatabase(t, postgresContainer)
-
-			dbPort, err := postgresContainer.MappedPort(ctx, "5432")
-			if err != nil {
-				t.Errorf("unable to inspect the postgres container: %s", err)
-				t.FailNow()
-			}
-
-			db := database.New(dbUser, dbPassword, dbName, dbPort.Port())
-			err = db.Connect()
-			if err != nil {
-				t.Errorf("unable to connect to the database: %s", err)
-				t.FailNow()
-			}
-
-			defer func() {
-				err = db.Disconnect()
-				if err != nil {
-					t.Errorf("unable to connect to the database: %s", err)
-					t.FailNow()
-				}
-			}()
-
-			req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("/catalog/%s", tc.product), nil)
-			if err != nil {
-				t.Fatal(err)
-			}
-
-			recorder := doRequest(t, db, req)
-			if tc.expectedStatusCode != recorder.Code {
-				t.Errorf("Unexpected status code: expected %d, got %d", tc.expectedStatusCode,
-					recorder.Code)
-				t.Errorf("body: %s", recorder.Body)
-				t.FailNow()
-			}
-
-			if tc.expectedStatusCode == http.StatusOK {
-				d := testy.DiffAsJSON(testy.Snapshot(t), recorder.Body)
-				if d != nil {
-					t.Error(d)
-				}
-			}
-		})
-	}
+type productsListTest struct {
+	name               string
+	request            string
+	expectedStatusCode int
 }
 
 // nolint: funlen
-func TestProducsListHandler(t *testing.T) {
-	t.Parallel()
-	tests := []struct {
-		name               string
-		request            string
-		expectedStatusCode int
-	}{
+func productsListTests() []productsListTest {
+	return []productsListTest{
 		{
 			name:               "list products",
 			request:            "/catalog",
@@ -133,6 +640,16 @@ func TestProducsListHandler(t *testing.T) {
 			request:            "/catalog?category=1",
 			expectedStatusCode: http.StatusOK,
 		},
+		{
+			name:               "with category and descendants",
+			request:            "/catalog?category=1&descendants=true",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "with invalid descendants",
+			request:            "/catalog?category=1&descendants=all",
+			expectedStatusCode: http.StatusBadRequest,
+		},
 		{
 			name:               "with price",
 			request:            "/catalog?price=10",
@@ -143,95 +660,847 @@ func TestProducsListHandler(t *testing.T) {
 			request:            "/catalog?category=1&price=15.1",
 			expectedStatusCode: http.StatusOK,
 		},
+		{
+			name:               "updated since",
+			request:            "/catalog?updated_since=2000-01-01T00:00:00Z",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "updated since the future",
+			request:            "/catalog?updated_since=2999-01-01T00:00:00Z",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "invalid updated since",
+			request:            "/catalog?updated_since=yesterday",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "with cursor",
+			request: "/catalog?limit=2&cursor=eyJpZCI6Mn0." +
+				"41b9oA-qJnzK7_RL8ttSW8e-aS1lQpF5xPuW9R6szJw",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "with price in another currency",
+			request:            "/catalog?price=20&currency=USD",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "with price sort in another currency",
+			request:            "/catalog?sort=-price&currency=JPY",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "with price in a currency without rate",
+			request:            "/catalog?price=20&currency=GBP",
+			expectedStatusCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:               "with invalid price",
+			request:            "/catalog?price=cheap",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "with currency",
+			request:            "/catalog?limit=3&currency=JPY",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "with invalid currency",
+			request:            "/catalog?currency=yen",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "search with invalid currency",
+			request:            "/catalog?q=shoes&currency=yen",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "with invalid cursor",
+			request:            "/catalog?cursor=eyJpZCI6Mn0.invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "with cursor and offset",
+			request: "/catalog?offset=2&cursor=eyJpZCI6Mn0." +
+				"41b9oA-qJnzK7_RL8ttSW8e-aS1lQpF5xPuW9R6szJw",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "with attribute",
+			request:            "/catalog?attr.material=cotton",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "with attribute values",
+			request:            "/catalog?attr.material=wool&attr.material=leather",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "with unknown attribute",
+			request:            "/catalog?attr.color=red",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "with invalid attribute",
+			request:            "/catalog?attr.the%20material=cotton",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "list variants",
+			request:            "/catalog/PROD001/variants",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "list variants of non existing product",
+			request:            "/catalog/PROD009/variants",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "with sort",
+			request:            "/catalog?limit=3&sort=category,-price",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "with unknown sort",
+			request:            "/catalog?sort=name",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "with sort of a filter field",
+			request:            "/catalog?sort=updated_at",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "with invalid sort",
+			request:            "/catalog?sort=price,,code",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "with cursor of another sort",
+			request: "/catalog?sort=price&cursor=eyJpZCI6Mn0." +
+				"41b9oA-qJnzK7_RL8ttSW8e-aS1lQpF5xPuW9R6szJw",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "search with empty query",
+			request:            "/catalog?q=",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "search with sort",
+			request:            "/catalog?q=shoes&sort=price",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "search with cursor",
+			request: "/catalog?q=shoes&cursor=eyJpZCI6Mn0." +
+				"41b9oA-qJnzK7_RL8ttSW8e-aS1lQpF5xPuW9R6szJw",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "search with invalid filter",
+			request:            "/catalog?q=shoes&price=cheap",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "on sale",
+			request:            "/catalog?on_sale=true",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "invalid on sale",
+			request:            "/catalog?on_sale=maybe",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "in stock",
+			request:            "/catalog?in_stock=true",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "invalid in stock",
+			request:            "/catalog?in_stock=maybe",
+			expectedStatusCode: http.StatusBadRequest,
+		},
 	}
+}
 
-	for i := range tests {
-		tc := tests[i]
-		t.Run(tc.name, func(t *testing.T) {
-			t.Parallel()
+type productsSearchTest struct {
+	name    string
+	request string
+	// snippet is a fragment of the snippet of the first product.
+	snippet       string
+	expectedCodes []string
+	expectedTotal int64
+}
 
-			// Storage initialization.
-			ctx := context.TODO()
-			postgresContainer := startDatabase(ctx, t)
-			defer stopDatabase(t, postgresContainer)
+func productsSearchTests() []productsSearchTest {
+	return []productsSearchTest{
+		{
+			name:          "search",
+			request:       "/catalog?q=scarf",
+			snippet:       "Wool <mark>Scarf</mark>",
+			expectedCodes: []string{"PROD005"},
+			expectedTotal: 1,
+		},
+		{
+			name:          "search sorted by relevance",
+			request:       "/catalog?q=shoes",
+			snippet:       "Running <mark>Shoes</mark>",
+			expectedCodes: []string{"PROD002", "PROD006"},
+			expectedTotal: 2,
+		},
+		{
+			name:          "search variant sku",
+			request:       "/catalog?q=SKU004B",
+			expectedCodes: []string{"PROD004"},
+			expectedTotal: 1,
+		},
+		{
+			name:          "search alternatives",
+			request:       "/catalog?q=belt+or+cap",
+			expectedCodes: []string{"PROD003", "PROD008"},
+			expectedTotal: 2,
+		},
+		{
+			name:          "search excluding a word",
+			request:       "/catalog?q=shoes+-running",
+			expectedCodes: []string{"PROD006"},
+			expectedTotal: 1,
+		},
+		{
+			name:          "search with category",
+			request:       "/catalog?q=shoes+or+scarf&category=3",
+			expectedCodes: []string{"PROD005"},
+			expectedTotal: 1,
+		},
+		{
+			name:          "search with offset",
+			request:       "/catalog?q=shoes&limit=1&offset=1",
+			expectedCodes: []string{"PROD006"},
+			expectedTotal: 2,
+		},
+		{
+			name:          "search without matches",
+			request:       "/catalog?q=umbrella",
+			expectedCodes: []string{},
+		},
+	}
+}
 
-			dbPort, err := postgresContainer.MappedPort(ctx, "5432")
-			if err != nil {
-				t.Errorf("unable to inspect the postgres container: %s", err)
-				t.FailNow()
-			}
+// checkSearch does the search request and checks the products found, and the snippet
+// of the first one.
+func checkSearch(ctx context.Context, t *testing.T, repo productsRepository,
+	tc *productsSearchTest,
+) {
+	t.Helper()
+	resp := getProducts(ctx, t, repo, tc.request)
+	codes := make([]string, 0, len(resp.Products))
+	for i := range resp.Products {
+		codes = append(codes, resp.Products[i].Code)
+	}
 
-			db := database.New(dbUser, dbPassword, dbName, dbPort.Port())
-			err = db.Connect()
-			if err != nil {
-				t.Errorf("unable to connect to the database: %s", err)
-				t.FailNow()
-			}
+	if !slices.Equal(tc.expectedCodes, codes) || resp.NumProducts != tc.expectedTotal {
+		t.Fatalf("Unexpected products: expected %v of %d, got %v of %d", tc.expectedCodes,
+			tc.expectedTotal, codes, resp.NumProducts)
+	}
 
-			defer func() {
-				err = db.Disconnect()
-				if err != nil {
-					t.Errorf("unable to connect to the database: %s", err)
-					t.FailNow()
-				}
-			}()
-
-			req, err := http.NewRequestWithContext(ctx, "GET", tc.request, nil)
-			if err != nil {
-				t.Fatal(err)
-			}
-
-			recorder := doRequest(t, db, req)
-			if tc.expectedStatusCode != recorder.Code {
-				t.Errorf("Unexpected status code: expected %d, got %d", tc.expectedStatusCode,
-					recorder.Code)
-				t.Errorf("body: %s", recorder.Body)
-				t.FailNow()
-			}
-
-			if tc.expectedStatusCode == http.StatusOK {
-				d := testy.DiffAsJSON(testy.Snapshot(t), recorder.Body)
-				if d != nil {
-					t.Error(d)
-				}
-			}
-		})
+	if resp.NextCursor != "" || resp.PrevCursor != "" {
+		t.Errorf("Unexpected cursors: %+v", resp)
 	}
-}
 
-func startDatabase(ctx context.Context, t *testing.T) *postgres.PostgresContainer {
-	t.Helper()
-	container, err := postgres.Run(ctx, "postgres:17-alpine",
-		postgres.WithInitScripts(filepath.Join("testdata", "init-db.sql")),
-		postgres.WithDatabase(dbName),
-		postgres.WithUsername(dbUser),
-		postgres.WithPassword(dbPassword),
-		postgres.BasicWaitStrategies(),
-	)
-	if err != nil {
-		t.Error(err)
-		t.FailNow()
+	if tc.snippet != "" && !strings.Contains(resp.Products[0].Snippet, tc.snippet) {
+		t.Errorf("Unexpected snippet: expected %q in %q", tc.snippet, resp.Products[0].Snippet)
 	}
+}
 
-	return container
+type productWriteTest struct {
+	name    string
+	method  string
+	request string
+	body    string
+	// ifMatch is the If-Match header of the request, and etag the expected entity tag of
+	// the response.
+	ifMatch string
+	etag    string
+	// check is the request to check the result of the write, the list of products by
+	// default.
+	check              string
+	expectedStatusCode int
 }
 
-func stopDatabase(t *testing.T, container *postgres.PostgresContainer) {
-	t.Helper()
-	if err := testcontainers.TerminateContainer(container); err != nil {
-		t.Errorf("failed to terminate container: %s", err)
-		t.FailNow()
+// nolint: funlen
+func productWriteTests() []productWriteTest {
+	return []productWriteTest{
+		{
+			name:               "create product",
+			method:             "POST",
+			request:            "/catalog",
+			body:               `{"code": "PROD009", "price": "19.99", "category": "CAT002"}`,
+			etag:               `"1"`,
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:    "create product with details",
+			method:  "POST",
+			request: "/catalog",
+			body: `{"code": "PROD009", "name": "Silk Tie", "description": "Tie for the office.",
+				"attributes": {"material": "silk", "width": "7cm"}, "price": "19.99",
+				"category": "CAT003"}`,
+			etag:               `"1"`,
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:    "create product with invalid attribute",
+			method:  "POST",
+			request: "/catalog",
+			body: `{"code": "PROD009", "attributes": {"the material": "silk"}, "price": "19.99",
+				"category": "CAT003"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "create product with invalid body",
+			method:             "POST",
+			request:            "/catalog",
+			body:               `{"code": "PROD009"`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "create product without code",
+			method:             "POST",
+			request:            "/catalog",
+			body:               `{"price": 19.99, "category": "CAT002"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:    "create product in yens",
+			method:  "POST",
+			request: "/catalog",
+			body: `{"code": "PROD009", "price": 1500, "currency": "jpy",
+				"category": "CAT002"}`,
+			check:              "/catalog/PROD009?currency=EUR",
+			etag:               `"1"`,
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:    "create product with invalid currency",
+			method:  "POST",
+			request: "/catalog",
+			body: `{"code": "PROD009", "price": 15, "currency": "EURO",
+				"category": "CAT002"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:    "create product with decimals in yens",
+			method:  "POST",
+			request: "/catalog",
+			body: `{"code": "PROD009", "price": 15.5, "currency": "JPY",
+				"category": "CAT002"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "create product with negative price",
+			method:             "POST",
+			request:            "/catalog",
+			body:               `{"code": "PROD009", "price": -1, "category": "CAT002"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "create product with too many decimals",
+			method:             "POST",
+			request:            "/catalog",
+			body:               `{"code": "PROD009", "price": 1.999, "category": "CAT002"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "create product with unknown category",
+			method:             "POST",
+			request:            "/catalog",
+			body:               `{"code": "PROD009", "price": 19.99, "category": "CAT009"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "create duplicated product",
+			method:             "POST",
+			request:            "/catalog",
+			body:               `{"code": "PROD001", "price": 19.99, "category": "CAT002"}`,
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:               "update product",
+			method:             "PUT",
+			request:            "/catalog/PROD001",
+			ifMatch:            `"1"`,
+			body:               `{"price": 11.5, "category": "CAT002"}`,
+			etag:               `"2"`,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "update product with any version",
+			method:             "PUT",
+			request:            "/catalog/PROD001",
+			ifMatch:            "*",
+			body:               `{"price": 11.5, "category": "CAT002"}`,
+			etag:               `"2"`,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "update product with stale version",
+			method:             "PUT",
+			request:            "/catalog/PROD001",
+			ifMatch:            `"2"`,
+			body:               `{"price": 11.5, "category": "CAT002"}`,
+			expectedStatusCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:               "update product with weak entity tag",
+			method:             "PUT",
+			request:            "/catalog/PROD001",
+			ifMatch:            `W/"1"`,
+			body:               `{"price": 11.5, "category": "CAT002"}`,
+			expectedStatusCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:               "update product with invalid entity tag",
+			method:             "PUT",
+			request:            "/catalog/PROD001",
+			ifMatch:            "1",
+			body:               `{"price": 11.5, "category": "CAT002"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "update product without entity tag",
+			method:             "PUT",
+			request:            "/catalog/PROD001",
+			body:               `{"price": 11.5, "category": "CAT002"}`,
+			expectedStatusCode: http.StatusPreconditionRequired,
+		},
+		{
+			name:               "update product without price",
+			method:             "PUT",
+			request:            "/catalog/PROD001",
+			ifMatch:            `"1"`,
+			body:               `{"category": "CAT002"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "update product code",
+			method:             "PUT",
+			request:            "/catalog/PROD001",
+			ifMatch:            `"1"`,
+			body:               `{"code": "PROD009", "price": 11.5, "category": "CAT002"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "update non existing product",
+			method:             "PUT",
+			request:            "/catalog/PROD009",
+			ifMatch:            `"1"`,
+			body:               `{"price": 11.5, "category": "CAT002"}`,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "patch product currency",
+			method:             "PATCH",
+			request:            "/catalog/PROD001",
+			ifMatch:            `"1"`,
+			body:               `{"price": 1500, "currency": "JPY"}`,
+			check:              "/catalog/PROD001",
+			etag:               `"2"`,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "patch product price",
+			method:             "PATCH",
+			request:            "/catalog/PROD005",
+			ifMatch:            `"1"`,
+			body:               `{"price": 21.5}`,
+			etag:               `"2"`,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "patch product details",
+			method:             "PATCH",
+			request:            "/catalog/PROD005",
+			ifMatch:            `"1"`,
+			body:               `{"name": "Silk Scarf", "attributes": {"material": "silk"}}`,
+			etag:               `"2"`,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "patch product removing attributes",
+			method:             "PATCH",
+			request:            "/catalog/PROD005",
+			ifMatch:            `"1"`,
+			body:               `{"attributes": null}`,
+			etag:               `"2"`,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "patch product with empty attributes",
+			method:             "PATCH",
+			request:            "/catalog/PROD005",
+			ifMatch:            `"1"`,
+			body:               `{"attributes": {}}`,
+			etag:               `"2"`,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "patch product with stale version",
+			method:             "PATCH",
+			request:            "/catalog/PROD005",
+			ifMatch:            `"2"`,
+			body:               `{"price": 21.5}`,
+			expectedStatusCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:               "patch product category",
+			method:             "PATCH",
+			request:            "/catalog/PROD005",
+			ifMatch:            `"1"`,
+			body:               `{"code": "PROD005", "category": "CAT001"}`,
+			etag:               `"2"`,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "patch non existing product",
+			method:             "PATCH",
+			request:            "/catalog/PROD009",
+			ifMatch:            `"1"`,
+			body:               `{"price": 21.5}`,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "delete product",
+			method:             "DELETE",
+			request:            "/catalog/PROD002",
+			ifMatch:            `"1"`,
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "delete product with stale version",
+			method:             "DELETE",
+			request:            "/catalog/PROD002",
+			ifMatch:            `"2"`,
+			expectedStatusCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:               "delete product without entity tag",
+			method:             "DELETE",
+			request:            "/catalog/PROD002",
+			expectedStatusCode: http.StatusPreconditionRequired,
+		},
+		{
+			name:               "delete non existing product",
+			method:             "DELETE",
+			request:            "/catalog/PROD009",
+			ifMatch:            `"1"`,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "create variant",
+			method:             "POST",
+			request:            "/catalog/PROD002/variants",
+			body:               `{"name": "Variant C", "sku": "SKU002C", "price": 13.49}`,
+			check:              "/catalog/PROD002/variants",
+			etag:               `"1"`,
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "create variant in dollars",
+			method:             "POST",
+			request:            "/catalog/PROD002/variants",
+			body:               `{"name": "Variant C", "sku": "SKU002C", "price": 13.49, "currency": "usd"}`,
+			check:              "/catalog/PROD002?currency=USD",
+			etag:               `"1"`,
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "create variant with invalid currency",
+			method:             "POST",
+			request:            "/catalog/PROD002/variants",
+			body:               `{"name": "Variant C", "sku": "SKU002C", "price": 13.49, "currency": "US"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "create variant without price",
+			method:             "POST",
+			request:            "/catalog/PROD002/variants",
+			body:               `{"name": "Variant C", "sku": "SKU002C"}`,
+			check:              "/catalog/PROD002/variants",
+			etag:               `"1"`,
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "create variant without name",
+			method:             "POST",
+			request:            "/catalog/PROD002/variants",
+			body:               `{"sku": "SKU002C"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "create duplicated variant",
+			method:             "POST",
+			request:            "/catalog/PROD002/variants",
+			body:               `{"name": "Variant C", "sku": "SKU001A"}`,
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:               "create variant of non existing product",
+			method:             "POST",
+			request:            "/catalog/PROD009/variants",
+			body:               `{"name": "Variant A", "sku": "SKU009A"}`,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "update variant",
+			method:             "PUT",
+			request:            "/catalog/PROD001/variants/SKU001B",
+			ifMatch:            `"1"`,
+			body:               `{"name": "Variant B2", "price": 12.5}`,
+			check:              "/catalog/PROD001/variants",
+			etag:               `"2"`,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "update variant with stale version",
+			method:             "PUT",
+			request:            "/catalog/PROD001/variants/SKU001B",
+			ifMatch:            `"2"`,
+			body:               `{"name": "Variant B2", "price": 12.5}`,
+			expectedStatusCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:               "update variant without price",
+			method:             "PUT",
+			request:            "/catalog/PROD001/variants/SKU001A",
+			ifMatch:            `"1"`,
+			body:               `{"name": "Variant A", "sku": "SKU001A"}`,
+			check:              "/catalog/PROD001/variants",
+			etag:               `"2"`,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "update variant sku",
+			method:             "PUT",
+			request:            "/catalog/PROD001/variants/SKU001A",
+			ifMatch:            `"1"`,
+			body:               `{"name": "Variant A", "sku": "SKU001Z"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "update variant of another product",
+			method:             "PUT",
+			request:            "/catalog/PROD002/variants/SKU001A",
+			ifMatch:            `"1"`,
+			body:               `{"name": "Variant A"}`,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "delete variant",
+			method:             "DELETE",
+			request:            "/catalog/PROD001/variants/SKU001C",
+			ifMatch:            `"1"`,
+			check:              "/catalog/PROD001/variants",
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "delete variant with stale version",
+			method:             "DELETE",
+			request:            "/catalog/PROD001/variants/SKU001C",
+			ifMatch:            `"2"`,
+			expectedStatusCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:               "delete variant without entity tag",
+			method:             "DELETE",
+			request:            "/catalog/PROD001/variants/SKU001C",
+			expectedStatusCode: http.StatusPreconditionRequired,
+		},
+		{
+			name:               "delete non existing variant",
+			method:             "DELETE",
+			request:            "/catalog/PROD001/variants/SKU002A",
+			ifMatch:            `"1"`,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:    "create sale price",
+			method:  "POST",
+			request: "/catalog/PROD001/prices",
+			body: `{"amount": "8.99", "compare_at": "10.99",
+				"effective_from": "2020-01-01T00:00:00Z"}`,
+			check:              "/catalog/PROD001",
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:    "create sale price and filter",
+			method:  "POST",
+			request: "/catalog/PROD002/prices",
+			body: `{"amount": "10.49", "compare_at": "12.49",
+				"effective_from": "2020-01-01T00:00:00Z"}`,
+			check:              "/catalog?on_sale=true",
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:    "create scheduled price",
+			method:  "POST",
+			request: "/catalog/PROD001/prices",
+			body: `{"amount": "9.49", "effective_from": "2100-01-01T00:00:00Z",
+				"effective_to": "2100-02-01T00:00:00Z"}`,
+			check:              "/catalog/PROD001/prices",
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:    "create variant price",
+			method:  "POST",
+			request: "/catalog/PROD001/prices",
+			body: `{"sku": "SKU001A", "amount": "11", "compare_at": "12.99", "currency": "usd",
+				"effective_from": "2020-01-01T00:00:00Z"}`,
+			check:              "/catalog/PROD001/variants",
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:    "create price of non existing variant",
+			method:  "POST",
+			request: "/catalog/PROD001/prices",
+			body: `{"sku": "SKU002A", "amount": "11",
+				"effective_from": "2020-01-01T00:00:00Z"}`,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:    "create price with lower original price",
+			method:  "POST",
+			request: "/catalog/PROD001/prices",
+			body: `{"amount": "11", "compare_at": "10.99",
+				"effective_from": "2020-01-01T00:00:00Z"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:    "create price ending before its start",
+			method:  "POST",
+			request: "/catalog/PROD001/prices",
+			body: `{"amount": "11", "effective_from": "2020-01-01T00:00:00Z",
+				"effective_to": "2019-01-01T00:00:00Z"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "create price of non existing product",
+			method:             "POST",
+			request:            "/catalog/PROD009/prices",
+			body:               `{"amount": "11"}`,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "delete non existing price",
+			method:             "DELETE",
+			request:            "/catalog/PROD001/prices/1",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "delete price with invalid id",
+			method:             "DELETE",
+			request:            "/catalog/PROD001/prices/first",
+			expectedStatusCode: http.StatusBadRequest,
+		},
 	}
 }
 
-func doRequest(t *testing.T, db *database.Database, req *http.Request) *httptest.ResponseRecorder {
+func doRequest(t *testing.T, repo productsRepository,
+	req *http.Request,
+) *httptest.ResponseRecorder {
 	t.Helper()
-	cat := NewHandler(db)
+	cat := NewHandler(repo, cursor.NewCodec([]byte(cursorSecret)), handlertest.Rates(t))
 	recorder := httptest.NewRecorder()
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /catalog", cat.HandleGetProducts)
 	mux.HandleFunc("GET /catalog/{code}", cat.HandleGetProduct)
+	mux.HandleFunc("POST /catalog", cat.HandlePostProduct)
+	mux.HandleFunc("PUT /catalog/{code}", cat.HandlePutProduct)
+	mux.HandleFunc("PATCH /catalog/{code}", cat.HandlePatchProduct)
+	mux.HandleFunc("DELETE /catalog/{code}", cat.HandleDeleteProduct)
+	mux.HandleFunc("GET /catalog/{code}/variants", cat.HandleGetVariants)
+	mux.HandleFunc("POST /catalog/{code}/variants", cat.HandlePostVariant)
+	mux.HandleFunc("PUT /catalog/{code}/variants/{sku}", cat.HandlePutVariant)
+	mux.HandleFunc("DELETE /catalog/{code}/variants/{sku}", cat.HandleDeleteVariant)
+	mux.HandleFunc("GET /catalog/{code}/prices", cat.HandleGetPrices)
+	mux.HandleFunc("POST /catalog/{code}/prices", cat.HandlePostPrice)
+	mux.HandleFunc("DELETE /catalog/{code}/prices/{id}", cat.HandleDeletePrice)
 	mux.ServeHTTP(recorder, req)
 
 	return recorder
 }
+
+// checkWrite does the write request and checks its response. On success it checks the
+// result of the write.
+func checkWrite(ctx context.Context, t *testing.T, repo productsRepository,
+	tc *productWriteTest,
+) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, tc.method, tc.request, strings.NewReader(tc.body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tc.ifMatch != "" {
+		req.Header.Set("If-Match", tc.ifMatch)
+	}
+
+	recorder := doRequest(t, repo, req)
+	checkResponse(t, recorder, tc.expectedStatusCode)
+	if recorder.Code >= http.StatusBadRequest {
+		return
+	}
+
+	if etag := recorder.Header().Get("ETag"); etag != tc.etag {
+		t.Errorf("Unexpected entity tag: expected %s, got %s", tc.etag, etag)
+	}
+
+	check := tc.check
+	if check == "" {
+		check = "/catalog?limit=20"
+	}
+
+	req, err = http.NewRequestWithContext(ctx, "GET", check, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder = doRequest(t, repo, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Error doing the request: %d - %s", recorder.Code, recorder.Body)
+	}
+
+	d := testy.DiffAsJSON(handlertest.Snapshot(t, "postsave"), withoutTimestamps(recorder.Body))
+	if d != nil {
+		t.Error(d)
+	}
+}
+
+// timestampRegexp matches the timestamps of the responses, that change on every run.
+var timestampRegexp = regexp.MustCompile(`"(created_at|updated_at)":"[^"]*"`)
+
+// withoutTimestamps returns the response body with the timestamps replaced by a
+// placeholder, so it can be compared with the snapshots.
+func withoutTimestamps(body *bytes.Buffer) []byte {
+	return timestampRegexp.ReplaceAll(body.Bytes(), []byte(`"$1":"timestamp"`))
+}
+
+func checkResponse(t *testing.T, recorder *httptest.ResponseRecorder, expectedStatusCode int) {
+	t.Helper()
+	if expectedStatusCode != recorder.Code {
+		t.Errorf("Unexpected status code: expected %d, got %d", expectedStatusCode,
+			recorder.Code)
+		t.Errorf("body: %s", recorder.Body)
+		t.FailNow()
+	}
+
+	if expectedStatusCode == http.StatusOK || expectedStatusCode == http.StatusCreated {
+		d := testy.DiffAsJSON(handlertest.Snapshot(t), withoutTimestamps(recorder.Body))
+		if d != nil {
+			t.Error(d)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/page"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/product"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/sorting"
+)
+
+const cursorParamName = "cursor"
+
+// errCursorAndOffset is returned when a request has both a cursor and an offset.
+var errCursorAndOffset = errors.New("cursor and offset can't be used together")
+
+// getPage returns the page requested, either by offset or by cursor. The cursor must
+// have been created for the same sorts.
+func (h *Handler) getPage(req *http.Request, sorts []sorting.Sort) (page.Page, error) {
+	limit, err := h.getIntQueryParam(req, limitParamName, defaultLimit)
+	if err != nil {
+		return page.Page{}, err
+	}
+
+	offset, err := h.getIntQueryParam(req, offsetParamName, defaultOffset)
+	if err != nil {
+		return page.Page{}, err
+	}
+
+	pg := page.Page{Limit: limit, Offset: offset}
+	token := h.getQueryParam(req, cursorParamName)
+	if token != "" {
+		if h.getQueryParam(req, offsetParamName) != "" {
+			return page.Page{}, errCursorAndOffset
+		}
+
+		pg.Offset = 0
+		pg.Cursor, err = h.cursors.Decode(token, sorting.Format(sorts))
+		if err != nil {
+			return page.Page{}, err
+		}
+	}
+
+	return pg, nil
+}
+
+// pageCursors removes the extra product fetched from the storage and returns the
+// products of the page and the cursors to the next and previous pages. The cursors are
+// empty when there are no more products in that direction, and they point to the
+// prices valid at the given time.
+func (h *Handler) pageCursors(res []product.Product, pg page.Page,
+	sorts []sorting.Sort, now time.Time,
+) ([]product.Product, string, string, error) {
+	hasNext, hasPrev := false, pg.Offset > 0
+	switch {
+	case pg.Limit < 0:
+	case pg.Cursor == nil || !pg.Cursor.Backward:
+		hasPrev = hasPrev || pg.Cursor != nil
+		if len(res) > pg.Limit {
+			res = res[:pg.Limit]
+			hasNext = true
+		}
+	default:
+		hasNext = true
+		if len(res) > pg.Limit {
+			res = res[len(res)-pg.Limit:]
+			hasPrev = true
+		}
+	}
+
+	if len(res) == 0 {
+		return res, "", "", nil
+	}
+
+	var next, prev string
+	var err error
+	if hasNext {
+		next, err = h.encodeCursor(&res[len(res)-1], sorts, now, false)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("unable to create the next cursor: %w", err)
+		}
+	}
+
+	if hasPrev {
+		prev, err = h.encodeCursor(&res[0], sorts, now, true)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("unable to create the previous cursor: %w", err)
+		}
+	}
+
+	return res, next, prev, nil
+}
+
+// encodeCursor returns the cursor pointing to the product, with the values of its sort
+// fields at the given time. The converted prices are converted with the current rates.
+func (h *Handler) encodeCursor(p *product.Product, sorts []sorting.Sort, now time.Time,
+	backward bool,
+) (string, error) {
+	cur := &page.Cursor{ID: p.ID, Backward: backward}
+	for i := range sorts {
+		value, _ := p.SortValue(sorts[i].Field, now)
+		if code, ok := product.PriceCurrency(sorts[i].Field); ok {
+			active := p.ActivePrice(now)
+			amount, err := h.prices.Convert(active.Amount, active.Currency, code)
+			if err != nil {
+				return "", err
+			}
+
+			value = amount.String()
+		}
+
+		cur.Values = append(cur.Values, value)
+	}
+
+	return h.cursors.Encode(cur, sorting.Format(sorts))
+}
@@ -0,0 +1,159 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/response"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/price"
+)
+
+// PriceRequest defines the API request to schedule a price of a product, or of one of
+// its variants if it has a SKU. The price is valid from its start, or from now without
+// start, until its end, or forever without end. A sale has the original price in
+// compare_at, and a price without currency is in the currency of the product.
+type PriceRequest struct {
+	EffectiveFrom *time.Time       `json:"effective_from"`
+	EffectiveTo   *time.Time       `json:"effective_to"`
+	CompareAt     *decimal.Decimal `json:"compare_at"`
+	Amount        decimal.Decimal  `json:"amount"`
+	Currency      string           `json:"currency"`
+	SKU           string           `json:"sku"`
+}
+
+// ScheduledPrice response from the API, with its validity and if it's valid at the time
+// of the request.
+type ScheduledPrice struct {
+	EffectiveFrom  time.Time  `json:"effective_from"`
+	EffectiveTo    *time.Time `json:"effective_to,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	CompareAtPrice *Price     `json:"compare_at_price,omitempty"`
+	SKU            string     `json:"sku,omitempty"`
+	Price          Price      `json:"price"`
+	ID             uint       `json:"id"`
+	Active         bool       `json:"active"`
+}
+
+// PricesResponse defines the API response for the scheduled prices of a product.
+type PricesResponse struct {
+	Prices    []ScheduledPrice `json:"prices"`
+	NumPrices int              `json:"total"`
+}
+
+// PriceResponse defines the API response for a single scheduled price.
+type PriceResponse struct {
+	Price ScheduledPrice `json:"price"`
+}
+
+// HandleGetPrices handles the get of the scheduled prices of a product and its variants,
+// including the expired ones.
+func (h *Handler) HandleGetPrices(w http.ResponseWriter, req *http.Request) {
+	prod, err := h.repo.GetProduct(req.Context(), req.PathValue("code"))
+	if err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	now := h.now()
+	resp := PricesResponse{
+		Prices:    make([]ScheduledPrice, 0, len(prod.Prices)),
+		NumPrices: len(prod.Prices),
+	}
+	for i := range prod.Prices {
+		resp.Prices = append(resp.Prices, newScheduledPrice(&prod.Prices[i], now))
+	}
+
+	response.OKResponse(w, resp)
+}
+
+// HandlePostPrice handles the scheduling of a new price of a product or its variant.
+// The prices can't be modified, so they don't need the If-Match header.
+func (h *Handler) HandlePostPrice(w http.ResponseWriter, req *http.Request) {
+	var body PriceRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	now := h.now()
+	code := req.PathValue("code")
+	p := body.toModel(now)
+	if err := h.repo.CreatePrice(req.Context(), code, p); err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	response.CreatedResponse(w, fmt.Sprintf("/catalog/%s/prices/%d", code, p.ID),
+		PriceResponse{Price: newScheduledPrice(p, now)})
+}
+
+// HandleDeletePrice handles the removal of a scheduled price by its id.
+func (h *Handler) HandleDeletePrice(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseUint(req.PathValue("id"), 10, 0)
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest,
+			fmt.Sprintf("invalid price id %q", req.PathValue("id")))
+
+		return
+	}
+
+	if err := h.repo.DeletePrice(req.Context(), req.PathValue("code"), uint(id)); err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	response.NoContentResponse(w)
+}
+
+// newScheduledPrice returns the API scheduled price, active if it's valid at the given
+// time.
+func newScheduledPrice(p *price.Price, now time.Time) ScheduledPrice {
+	current, compareAt := newPrices(*p)
+	res := ScheduledPrice{
+		EffectiveFrom:  p.EffectiveFrom,
+		CreatedAt:      p.CreatedAt,
+		CompareAtPrice: compareAt,
+		SKU:            p.SKU,
+		Price:          current,
+		ID:             p.ID,
+		Active:         p.Active(now),
+	}
+	if !p.EffectiveTo.IsZero() {
+		res.EffectiveTo = &p.EffectiveTo
+	}
+
+	return res
+}
+
+// toModel returns the model price of the request, with its validity in UTC.
+func (r *PriceRequest) toModel(now time.Time) *price.Price {
+	p := &price.Price{
+		EffectiveFrom: now.UTC(),
+		SKU:           r.SKU,
+		Amount:        r.Amount,
+		Currency:      strings.ToUpper(r.Currency),
+	}
+	if r.EffectiveFrom != nil {
+		p.EffectiveFrom = r.EffectiveFrom.UTC()
+	}
+
+	if r.EffectiveTo != nil {
+		p.EffectiveTo = r.EffectiveTo.UTC()
+	}
+
+	if r.CompareAt != nil {
+		p.CompareAt = *r.CompareAt
+	}
+
+	return p
+}
@@ -1,97 +0,0 @@
-DO $$ DECLARE
-    r RECORD;
-BEGIN
-    FOR r IN (SELECT tablename FROM pg_tables WHERE schemaname = 'public') LOOP
-        EXECUTE 'DROP TABLE IF EXISTS public.' || quote_ident(r.tablename) || ' CASCADE';
-    END LOOP;
-END $$;
-
-CREATE TABLE IF NOT EXISTS category (
-    id SERIAL PRIMARY KEY,
-    code VARCHAR(32) UNIQUE NOT NULL,
-    name VARCHAR(255) NOT NULL
-);
-
-CREATE TABLE IF NOT EXISTS products (
-    id SERIAL PRIMARY KEY,
-    code VARCHAR(32),
-    price DECIMAL(10, 2) NOT NULL,
-	category INTEGER NOT NULL REFERENCES category(id) ON DELETE CASCADE,
-    created_at TIMESTAMP DEFAULT NOW(),
-    updated_at TIMESTAMP DEFAULT NOW()
-);
-
-CREATE TABLE IF NOT EXISTS product_variants (
-    id SERIAL PRIMARY KEY,
-    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
-    name VARCHAR(256) NOT NULL,
-    sku VARCHAR(32) UNIQUE,
-    price DECIMAL(10, 2) NULL,
-    created_at TIMESTAMP DEFAULT NOW(),
-    updated_at TIMESTAMP DEFAULT NOW()
-);
-
--- Insert 3 categories
-INSERT INTO category (code, name) VALUES
-('CAT001', 'Clothing'),
-('CAT002', 'Shoes'),
-('CAT003', 'Accessories');
-
--- Insert 8 products
-INSERT INTO products (code, price, category) VALUES
-('PROD001', 10.99, (SELECT id FROM category WHERE code = 'CAT001')),
-('PROD002', 12.49, (SELECT id FROM category WHERE code = 'CAT002')),
-('PROD003', 8.75, (SELECT id FROM category WHERE code = 'CAT003')),
-('PROD004', 15.00, (SELECT id FROM category WHERE code = 'CAT001')),
-('PROD005', 22.99, (SELECT id FROM category WHERE code = 'CAT003')),
-('PROD006', 5.50, (SELECT id FROM category WHERE code = 'CAT002')),
-('PROD007', 18.20, (SELECT id FROM category WHERE code = 'CAT001')),
-('PROD008', 9.99, (SELECT id FROM category WHERE code = 'CAT003'));
-
--- Insert variants for each product using product code to look up product_id
-
--- Product 1: 3 variants
-INSERT INTO product_variants (product_id, name, sku, price) VALUES
-((SELECT id FROM products WHERE code = 'PROD001'), 'Variant A', 'SKU001A', 11.99),
-((SELECT id FROM products WHERE code = 'PROD001'), 'Variant B', 'SKU001B', NULL),
-((SELECT id FROM products WHERE code = 'PROD001'), 'Variant C', 'SKU001C', NULL);
-
--- Product 2: 2 variants
-INSERT INTO product_variants (product_id, name, sku, price) VALUES
-((SELECT id FROM products WHERE code = 'PROD002'), 'Variant A', 'SKU002A', NULL),
-((SELECT id FROM products WHERE code = 'PROD002'), 'Variant B', 'SKU002B', NULL);
-
--- Product 3: 1 variant
-INSERT INTO product_variants (product_id, name, sku, price) VALUES
-((SELECT id FROM products WHERE code = 'PROD003'), 'Variant A', 'SKU003A', 8.99);
-
--- Product 4: 4 variants
-INSERT INTO product_variants (product_id, name, sku, price) VALUES
-((SELECT id FROM products WHERE code = 'PROD004'), 'Variant A', 'SKU004A', 15.50),
-((SELECT id FROM products WHERE code = 'PROD004'), 'Variant B', 'SKU004B', 16.00),
-((SELECT id FROM products WHERE code = 'PROD004'), 'Variant C', 'SKU004C', NULL),
-((SELECT id FROM products WHERE code = 'PROD004'), 'Variant D', 'SKU004D', 16.99);
-
--- Product 5: 6 variants
-INSERT INTO product_variants (product_id, name, sku, price) VALUES
-((SELECT id FROM products WHERE code = 'PROD005'), 'Variant A', 'SKU005A', 23.99),
-((SELECT id FROM products WHERE code = 'PROD005'), 'Variant B', 'SKU005B', NULL),
-((SELECT id FROM products WHERE code = 'PROD005'), 'Variant C', 'SKU005C', NULL),
-((SELECT id FROM products WHERE code = 'PROD005'), 'Variant D', 'SKU005D', 22.99),
-((SELECT id FROM products WHERE code = 'PROD005'), 'Variant E', 'SKU005E', 23.49),
-((SELECT id FROM products WHERE code = 'PROD005'), 'Variant F', 'SKU005F', NULL);
-
--- Product 6: 2 variants
--- No variants for this product
-
--- Product 7: 5 variants
-INSERT INTO product_variants (product_id, name, sku, price) VALUES
-((SELECT id FROM products WHERE code = 'PROD007'), 'Variant A', 'SKU007A', NULL),
-((SELECT id FROM products WHERE code = 'PROD007'), 'Variant B', 'SKU007B', NULL),
-((SELECT id FROM products WHERE code = 'PROD007'), 'Variant C', 'SKU007C', NULL),
-((SELECT id FROM products WHERE code = 'PROD007'), 'Variant D', 'SKU007D', NULL),
-((SELECT id FROM products WHERE code = 'PROD007'), 'Variant E', 'SKU007E', 18.75);
-
--- Product 8: 1 variant
-INSERT INTO product_variants (product_id, name, sku, price) VALUES
-((SELECT id FROM products WHERE code = 'PROD008'), 'Variant A', 'SKU008A', 10.49);
@@ -0,0 +1,180 @@
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/etag"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/response"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/variant"
+)
+
+// errSKUMismatch is returned when the SKU of the body doesn't match the SKU of the path.
+var errSKUMismatch = errors.New("the variant SKU can't be changed")
+
+// VariantRequest defines the API request to create or update a variant. A variant
+// without price inherits the price of the product, and a price without currency is in
+// the currency of the product.
+type VariantRequest struct {
+	Price    *decimal.Decimal `json:"price"`
+	Currency string           `json:"currency"`
+	Name     string           `json:"name"`
+	SKU      string           `json:"sku"`
+}
+
+// VariantsResponse defines the API response for the list of variants of a product.
+type VariantsResponse struct {
+	Variants    []Variant `json:"variants"`
+	NumVariants int       `json:"total"`
+}
+
+// VariantResponse defines the API response for a single variant.
+type VariantResponse struct {
+	Variant Variant `json:"variant"`
+}
+
+// HandleGetVariants handles the get of the variants of a product, with their prices
+// valid at the time of the request and their availability.
+func (h *Handler) HandleGetVariants(w http.ResponseWriter, req *http.Request) {
+	prod, err := h.repo.GetProduct(req.Context(), req.PathValue("code"))
+	if err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	stock, err := h.getStock(req.Context(), *prod)
+	if err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	now := h.now()
+	resp := VariantsResponse{
+		Variants:    make([]Variant, 0, len(prod.Variants)),
+		NumVariants: len(prod.Variants),
+	}
+	for i := range prod.Variants {
+		v := newVariant(&prod.Variants[i], prod, now)
+		inStock := stock[v.SKU]
+		v.InStock = &inStock
+		resp.Variants = append(resp.Variants, v)
+	}
+
+	response.OKResponse(w, resp)
+}
+
+// HandlePostVariant handles the creation of a new variant of a product.
+func (h *Handler) HandlePostVariant(w http.ResponseWriter, req *http.Request) {
+	var body VariantRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	code := req.PathValue("code")
+	prod, err := h.repo.GetProduct(req.Context(), code)
+	if err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	v := body.toModel()
+	if err := h.repo.CreateVariant(req.Context(), code, v); err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	etag.Set(w, v.Version)
+	response.CreatedResponse(w, fmt.Sprintf("/catalog/%s/variants/%s", code, v.SKU),
+		VariantResponse{Variant: newVariant(v, prod, h.now())})
+}
+
+// HandlePutVariant handles the replacement of the data of a variant by its SKU. The
+// If-Match header must have the entity tag of the variant version being replaced.
+func (h *Handler) HandlePutVariant(w http.ResponseWriter, req *http.Request) {
+	version, err := etag.IfMatch(req)
+	if err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	var body VariantRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	sku := req.PathValue("sku")
+	if body.SKU != "" && body.SKU != sku {
+		response.ErrorResponse(w, http.StatusBadRequest,
+			fmt.Sprintf("%s: %s", errSKUMismatch, body.SKU))
+
+		return
+	}
+
+	body.SKU = sku
+	code := req.PathValue("code")
+	prod, err := h.repo.GetProduct(req.Context(), code)
+	if err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	v := body.toModel()
+	v.Version = version
+	if err := h.repo.UpdateVariant(req.Context(), code, v); err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	etag.Set(w, v.Version)
+	response.OKResponse(w, VariantResponse{Variant: newVariant(v, prod, h.now())})
+}
+
+// HandleDeleteVariant handles the removal of a variant by its SKU. The If-Match header
+// must have the entity tag of the variant version being removed.
+func (h *Handler) HandleDeleteVariant(w http.ResponseWriter, req *http.Request) {
+	version, err := etag.IfMatch(req)
+	if err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	err = h.repo.DeleteVariant(req.Context(), req.PathValue("code"), req.PathValue("sku"),
+		version)
+	if err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	response.NoContentResponse(w)
+}
+
+func (r *VariantRequest) toModel() *variant.Variant {
+	v := &variant.Variant{
+		Name:     r.Name,
+		SKU:      r.SKU,
+		Currency: strings.ToUpper(r.Currency),
+	}
+	if r.Price != nil {
+		v.Price = *r.Price
+	}
+
+	return v
+}
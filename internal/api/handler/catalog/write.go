@@ -0,0 +1,260 @@
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/etag"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/response"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/category"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/price"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/product"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/variant"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/storage"
+)
+
+// errCodeMismatch is returned when the code of the body doesn't match the code of the
+// path.
+var errCodeMismatch = errors.New("the product code can't be changed")
+
+// ProductRequest defines the API request to create or update a product. On partial
+// updates the missing fields are not modified, and otherwise a price without currency
+// is in the default currency.
+type ProductRequest struct {
+	Price       *decimal.Decimal  `json:"price"`
+	Currency    string            `json:"currency"`
+	Name        *string           `json:"name"`
+	Description *string           `json:"description"`
+	Attributes  map[string]string `json:"attributes"`
+	Code        string            `json:"code"`
+	Category    string            `json:"category"`
+	// hasAttributes is set if the request has the attributes, even if they are null, so
+	// the partial updates can remove them.
+	hasAttributes bool
+}
+
+// UnmarshalJSON decodes the request, recording if it has the attributes.
+func (r *ProductRequest) UnmarshalJSON(data []byte) error {
+	type request ProductRequest
+	if err := json.Unmarshal(data, (*request)(r)); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	_, r.hasAttributes = fields["attributes"]
+
+	return nil
+}
+
+// HandlePostProduct handles the creation of a new product.
+func (h *Handler) HandlePostProduct(w http.ResponseWriter, req *http.Request) {
+	var body ProductRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	prod := body.toModel()
+	if err := h.repo.CreateProduct(req.Context(), prod); err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	etag.Set(w, prod.Version)
+	response.CreatedResponse(w, "/catalog/"+prod.Code, newProductResponse(prod, h.now()))
+}
+
+// HandlePutProduct handles the replacement of the data of a product by its code. The
+// If-Match header must have the entity tag of the product version being replaced.
+func (h *Handler) HandlePutProduct(w http.ResponseWriter, req *http.Request) {
+	version, err := etag.IfMatch(req)
+	if err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	body, err := decodeProductRequest(req)
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	prod := body.toModel()
+	prod.Version = version
+	if err := h.repo.UpdateProduct(req.Context(), prod); err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	etag.Set(w, prod.Version)
+	response.OKResponse(w, newProductResponse(prod, h.now()))
+}
+
+// HandlePatchProduct handles the partial update of a product by its code. The If-Match
+// header must have the entity tag of the product version being updated. The product is
+// read in the same transaction as the update, without the cache, so the fields not in
+// the request keep their stored values. Null or empty attributes remove all of them.
+func (h *Handler) HandlePatchProduct(w http.ResponseWriter, req *http.Request) {
+	version, err := etag.IfMatch(req)
+	if err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	body, err := decodeProductRequest(req)
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	var prod *product.Product
+	err = h.repo.WithTx(req.Context(), func(tx storage.Storage) error {
+		var err error
+		if prod, err = tx.GetProduct(req.Context(), body.Code); err != nil {
+			return err
+		}
+
+		// The update is based on the read product, so it fails if the product changes
+		// before being updated, even if any version was allowed.
+		if version != 0 && prod.Version != version {
+			return product.ErrVersionMismatch
+		}
+
+		body.patch(prod)
+
+		return tx.UpdateProduct(req.Context(), prod)
+	})
+	if err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	etag.Set(w, prod.Version)
+	response.OKResponse(w, newProductResponse(prod, h.now()))
+}
+
+// HandleDeleteProduct handles the removal of a product by its code. The If-Match header
+// must have the entity tag of the product version being removed.
+func (h *Handler) HandleDeleteProduct(w http.ResponseWriter, req *http.Request) {
+	version, err := etag.IfMatch(req)
+	if err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	if err := h.repo.DeleteProduct(req.Context(), req.PathValue("code"), version); err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	response.NoContentResponse(w)
+}
+
+// decodeProductRequest decodes the body of a request to update the product of the path.
+// The code of the body is optional, but it must match the code of the path.
+func decodeProductRequest(req *http.Request) (*ProductRequest, error) {
+	var body ProductRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	code := req.PathValue("code")
+	if body.Code != "" && body.Code != code {
+		return nil, fmt.Errorf("%w: %s", errCodeMismatch, body.Code)
+	}
+
+	body.Code = code
+
+	return &body, nil
+}
+
+// patch sets the fields of the request to the product.
+func (r *ProductRequest) patch(prod *product.Product) {
+	if r.Price != nil {
+		prod.Price = *r.Price
+	}
+
+	if r.Currency != "" {
+		prod.Currency = strings.ToUpper(r.Currency)
+	}
+
+	if r.Category != "" {
+		prod.Category = &category.Category{Code: r.Category}
+	}
+
+	if r.Name != nil {
+		prod.Name = *r.Name
+	}
+
+	if r.Description != nil {
+		prod.Description = *r.Description
+	}
+
+	if r.hasAttributes {
+		prod.Attributes = r.Attributes
+	}
+}
+
+func (r *ProductRequest) toModel() *product.Product {
+	prod := &product.Product{
+		Code:       r.Code,
+		Attributes: r.Attributes,
+		Currency:   strings.ToUpper(r.Currency),
+		Category:   &category.Category{Code: r.Category},
+	}
+	if r.Price != nil {
+		prod.Price = *r.Price
+	}
+
+	if r.Name != nil {
+		prod.Name = *r.Name
+	}
+
+	if r.Description != nil {
+		prod.Description = *r.Description
+	}
+
+	return prod
+}
+
+// writeError writes the error response with the status code of the error.
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, product.ErrInvalidProduct), errors.Is(err, variant.ErrInvalidVariant),
+		errors.Is(err, category.ErrInvalidCategory), errors.Is(err, price.ErrInvalidPrice):
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, product.ErrNotFound), errors.Is(err, variant.ErrNotFound),
+		errors.Is(err, price.ErrNotFound):
+		response.ErrorResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, product.ErrDuplicated), errors.Is(err, variant.ErrDuplicated):
+		response.ErrorResponse(w, http.StatusConflict, err.Error())
+	case errors.Is(err, product.ErrVersionMismatch), errors.Is(err, variant.ErrVersionMismatch),
+		errors.Is(err, etag.ErrNoMatch):
+		response.ErrorResponse(w, http.StatusPreconditionFailed, err.Error())
+	case errors.Is(err, etag.ErrMissing):
+		response.ErrorResponse(w, http.StatusPreconditionRequired, err.Error())
+	case errors.Is(err, etag.ErrInvalid):
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+	default:
+		response.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -2,10 +2,14 @@
 package category
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/etag"
 	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/response"
 	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/category"
 )
@@ -14,13 +18,44 @@ import (
 type Category struct {
 	Name string `json:"name"`
 	Code string `json:"code"`
+	// Parent is the code of the parent category, empty for the root categories.
+	Parent  string `json:"parent,omitempty"`
+	Version uint   `json:"version,omitempty"`
 }
 
+// CategoryNode is a category of the category tree, with its subcategories.
+type CategoryNode struct {
+	Category
+	Children []CategoryNode `json:"children,omitempty"`
+}
+
+const (
+	// reassignParamName is the parameter with the category to move the products to when
+	// removing a category.
+	reassignParamName = "reassign_to"
+
+	// treeParamName is the parameter to get the categories nested under their parents.
+	treeParamName = "tree"
+)
+
 type categoryRepository interface {
 	// GetAllCategories gets a list of all the categories stored in the storage.
-	GetAllCategories() (category.Categories, error)
-	// AddCategory adds a new category to the storage.
-	AddCategory(cat *category.Category) error
+	GetAllCategories(ctx context.Context) (category.Categories, error)
+	// AddCategory adds a new category to the storage, under its parent category if it has
+	// one.
+	AddCategory(ctx context.Context, cat *category.Category) error
+	// GetCategory obtains a category, with its ancestors, from the storage by its code.
+	GetCategory(ctx context.Context, code string) (*category.Category, error)
+	// UpdateCategory updates the name and the parent of the category with the same code.
+	// It fails with a cycle error if the parent is the category or one of its
+	// descendants. If the category has a version, it fails with a version mismatch error
+	// if the stored category has a different one.
+	UpdateCategory(ctx context.Context, cat *category.Category) error
+	// DeleteCategory removes a category from the storage. If some products belong to it
+	// they are moved to the reassignTo category, and it fails if reassignTo is empty. It
+	// fails if the category has subcategories. If the version isn't zero, it fails with a
+	// version mismatch error if the category has a different one.
+	DeleteCategory(ctx context.Context, code, reassignTo string, version uint) error
 }
 
 type Handler struct {
@@ -40,23 +75,54 @@ type CategoriesResponse struct {
 	NumCategories int        `json:"total"`
 }
 
-// HandleGetCategories handle the get of a list of categories.
-func (h *Handler) HandleGetCategories(w http.ResponseWriter, _ *http.Request) {
-	res, err := h.repo.GetAllCategories()
+// CategoryTreeResponse defines the API response for the tree of categories.
+type CategoryTreeResponse struct {
+	Categories    []CategoryNode `json:"categories"`
+	NumCategories int            `json:"total"`
+}
+
+// HandleGetCategories handle the get of a list of categories. With the tree parameter
+// the root categories are returned with their subcategories nested under them. The list
+// isn't sent again if the client already has it.
+func (h *Handler) HandleGetCategories(w http.ResponseWriter, req *http.Request) {
+	tree := false
+	if param := req.URL.Query().Get(treeParamName); param != "" {
+		var err error
+		if tree, err = strconv.ParseBool(param); err != nil {
+			response.ErrorResponse(w, http.StatusBadRequest, "tree must be a boolean")
+
+			return
+		}
+	}
+
+	res, err := h.repo.GetAllCategories(req.Context())
 	if err != nil {
 		response.ErrorResponse(w, http.StatusInternalServerError, err.Error())
 
 		return
 	}
 
+	var lastModified time.Time
+	for i := range res {
+		if res[i].UpdatedAt.After(lastModified) {
+			lastModified = res[i].UpdatedAt
+		}
+	}
+
+	validators := response.Validators{LastModified: lastModified, List: true}
+	if tree {
+		response.CachedResponse(w, req, CategoryTreeResponse{
+			NumCategories: len(res),
+			Categories:    newCategoryNodes(res.Tree()),
+		}, validators)
+
+		return
+	}
+
 	// Map the response.
 	categories := make([]Category, 0, len(res))
 	for i := range res {
-		p := res[i]
-		categories = append(categories, Category{
-			Code: p.Code,
-			Name: p.Name,
-		})
+		categories = append(categories, newCategory(&res[i]))
 	}
 
 	// Return the products as a JSON response.
@@ -65,10 +131,34 @@ func (h *Handler) HandleGetCategories(w http.ResponseWriter, _ *http.Request) {
 		Categories:    categories,
 	}
 
-	response.OKResponse(w, resp)
+	response.CachedResponse(w, req, resp, validators)
 }
 
-// HandlePostCategories handle the creation of a new category.
+// newCategory returns the API category.
+func newCategory(cat *category.Category) Category {
+	return Category{
+		Code:    cat.Code,
+		Name:    cat.Name,
+		Parent:  cat.ParentCode,
+		Version: cat.Version,
+	}
+}
+
+// newCategoryNodes returns the API nodes of the category tree.
+func newCategoryNodes(nodes []category.Node) []CategoryNode {
+	res := make([]CategoryNode, 0, len(nodes))
+	for i := range nodes {
+		res = append(res, CategoryNode{
+			Category: newCategory(&nodes[i].Category),
+			Children: newCategoryNodes(nodes[i].Children),
+		})
+	}
+
+	return res
+}
+
+// HandlePostCategories handle the creation of a new category. It fails with a conflict
+// if there is already a category with the same code.
 func (h *Handler) HandlePostCategories(w http.ResponseWriter, req *http.Request) {
 	decoder := json.NewDecoder(req.Body)
 	var cat Category
@@ -79,18 +169,126 @@ func (h *Handler) HandlePostCategories(w http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	newCategory := &category.Category{
-		Name: cat.Name,
-		Code: cat.Code,
+	created := &category.Category{
+		Name:       cat.Name,
+		Code:       cat.Code,
+		ParentCode: cat.Parent,
 	}
-	err = h.repo.AddCategory(newCategory)
+	err = h.repo.AddCategory(req.Context(), created)
 	if err != nil {
-		if errors.Is(err, category.ErrInvalidCategory) {
-			response.ErrorResponse(w, http.StatusBadRequest, err.Error())
-		} else {
-			response.ErrorResponse(w, http.StatusInternalServerError, err.Error())
-		}
+		writeError(w, err)
+
+		return
 	}
 
-	response.OKResponse(w, newCategory)
+	etag.Set(w, created.Version)
+	response.OKResponse(w, newCategoryResponse(created))
+}
+
+// CategoryResponse defines the API response for a single category.
+type CategoryResponse struct {
+	Category Category `json:"category"`
+}
+
+// HandleGetCategory handles the get of a category by its code. The entity tag of the
+// response is the version of the category, to make the writes conditional on it, and it
+// isn't sent again if the client already has it.
+func (h *Handler) HandleGetCategory(w http.ResponseWriter, req *http.Request) {
+	cat, err := h.repo.GetCategory(req.Context(), req.PathValue("code"))
+	if err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	response.CachedResponse(w, req, newCategoryResponse(cat), response.Validators{
+		ETag:         etag.Format(cat.Version),
+		LastModified: cat.UpdatedAt,
+	})
+}
+
+// newCategoryResponse returns the API response of the category.
+func newCategoryResponse(cat *category.Category) CategoryResponse {
+	return CategoryResponse{Category: newCategory(cat)}
+}
+
+// HandlePutCategory handles the update of the name and the parent of a category by its
+// code. A category without parent is moved to the root of the tree. The If-Match header
+// must have the entity tag of the category version being updated.
+func (h *Handler) HandlePutCategory(w http.ResponseWriter, req *http.Request) {
+	version, err := etag.IfMatch(req)
+	if err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	var body Category
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	code := req.PathValue("code")
+	if body.Code != "" && body.Code != code {
+		response.ErrorResponse(w, http.StatusBadRequest, "the category code can't be changed")
+
+		return
+	}
+
+	cat := &category.Category{Code: code, Name: body.Name, ParentCode: body.Parent,
+		Version: version}
+	if err := h.repo.UpdateCategory(req.Context(), cat); err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	etag.Set(w, cat.Version)
+	response.OKResponse(w, newCategoryResponse(cat))
+}
+
+// HandleDeleteCategory handles the removal of a category by its code. The categories
+// with products can only be removed if the reassign_to parameter has the code of the
+// category to move the products to. The If-Match header must have the entity tag of
+// the category version being removed.
+func (h *Handler) HandleDeleteCategory(w http.ResponseWriter, req *http.Request) {
+	version, err := etag.IfMatch(req)
+	if err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	reassignTo := req.URL.Query().Get(reassignParamName)
+	err = h.repo.DeleteCategory(req.Context(), req.PathValue("code"), reassignTo, version)
+	if err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	response.NoContentResponse(w)
+}
+
+// writeError writes the error response with the status code of the error.
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, category.ErrInvalidCategory):
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, category.ErrNotFound):
+		response.ErrorResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, category.ErrDuplicated), errors.Is(err, category.ErrInUse),
+		errors.Is(err, category.ErrCycle):
+		response.ErrorResponse(w, http.StatusConflict, err.Error())
+	case errors.Is(err, category.ErrVersionMismatch), errors.Is(err, etag.ErrNoMatch):
+		response.ErrorResponse(w, http.StatusPreconditionFailed, err.Error())
+	case errors.Is(err, etag.ErrMissing):
+		response.ErrorResponse(w, http.StatusPreconditionRequired, err.Error())
+	case errors.Is(err, etag.ErrInvalid):
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+	default:
+		response.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+	}
 }
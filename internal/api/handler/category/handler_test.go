@@ -5,103 +5,379 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
-	"path/filepath"
 	"strings"
 	"testing"
 
-	"github.com/testcontainers/testcontainers-go"
-	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	"gitlab.com/flimzy/testy"
 
-	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/storage/database"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/handler/handlertest"
 )
 
-const (
-	dbName     = "users"
-	dbUser     = "user"
-	dbPassword = "password"
-)
+func TestGetHandler(t *testing.T) {
+	t.Parallel()
+	handlertest.Run(t, func(t *testing.T, kind handlertest.Kind) {
+		ctx := context.TODO()
+		req, err := http.NewRequestWithContext(ctx, "GET", "/categories", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recorder := doRequest(t, handlertest.New(ctx, t, kind), req)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Unexpected status code: expected %d, got %d: %s", http.StatusOK,
+				recorder.Code, recorder.Body)
+		}
+
+		d := testy.DiffAsJSON(handlertest.Snapshot(t), recorder.Body)
+		if d != nil {
+			t.Error(d)
+		}
+	})
+}
+
+func TestPostHandler(t *testing.T) {
+	t.Parallel()
+	handlertest.Run(t, func(t *testing.T, kind handlertest.Kind) {
+		tests := postHandlerTests()
+		for i := range tests {
+			tc := tests[i]
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+				ctx := context.TODO()
+				checkPost(ctx, t, handlertest.New(ctx, t, kind), &tc)
+			})
+		}
+	})
+}
+
+// checkPost checks the list of categories before and after the creation request.
+func checkPost(ctx context.Context, t *testing.T, repo categoryRepository, tc *postHandlerTest) {
+	t.Helper()
+	checkCategories(ctx, t, repo, "presave")
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "/categories", tc.category)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := doRequest(t, repo, req)
+	if recorder.Code != tc.expectedStatusCode {
+		t.Fatalf("Unexpected status code: expected %d, got %d: %s", tc.expectedStatusCode,
+			recorder.Code, recorder.Body)
+	}
+
+	checkCategories(ctx, t, repo, "postsave")
+}
+
+// checkCategories checks the list of categories with the snapshot of the label.
+func checkCategories(ctx context.Context, t *testing.T, repo categoryRepository,
+	label string,
+) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, "GET", "/categories", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := doRequest(t, repo, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Error doing the request: %d - %s", recorder.Code, recorder.Body)
+	}
+
+	d := testy.DiffAsJSON(handlertest.Snapshot(t, label), recorder.Body)
+	if d != nil {
+		t.Error(d)
+	}
+}
+
+func TestCategoryHandler(t *testing.T) {
+	t.Parallel()
+	handlertest.Run(t, func(t *testing.T, kind handlertest.Kind) {
+		tests := categoryHandlerTests()
+		for i := range tests {
+			tc := tests[i]
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+				ctx := context.TODO()
+				checkCategoryRequest(ctx, t, handlertest.New(ctx, t, kind), &tc)
+			})
+		}
+	})
+}
+
+// checkCategoryRequest does the request and checks its response. On success it checks
+// the list of categories after the request.
+func checkCategoryRequest(ctx context.Context, t *testing.T, repo categoryRepository,
+	tc *categoryHandlerTest,
+) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, tc.method, tc.request, strings.NewReader(tc.body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tc.ifMatch != "" {
+		req.Header.Set("If-Match", tc.ifMatch)
+	}
+
+	recorder := doRequest(t, repo, req)
+	if recorder.Code != tc.expectedStatusCode {
+		t.Fatalf("Unexpected status code: expected %d, got %d: %s", tc.expectedStatusCode,
+			recorder.Code, recorder.Body)
+	}
+
+	if recorder.Code >= http.StatusBadRequest {
+		return
+	}
+
+	if etag := recorder.Header().Get("ETag"); etag != tc.etag {
+		t.Errorf("Unexpected entity tag: expected %s, got %s", tc.etag, etag)
+	}
+
+	if recorder.Code == http.StatusOK {
+		d := testy.DiffAsJSON(handlertest.Snapshot(t), recorder.Body)
+		if d != nil {
+			t.Error(d)
+		}
+	}
+
+	checkCategories(ctx, t, repo, "postsave")
+}
+
+type categoryHandlerTest struct {
+	name    string
+	method  string
+	request string
+	body    string
+	// ifMatch is the If-Match header of the request, and etag the expected entity tag of
+	// the response.
+	ifMatch            string
+	etag               string
+	expectedStatusCode int
+}
 
 // nolint: funlen
-func TestGetHandler(t *testing.T) {
+func TestCategoryTree(t *testing.T) {
 	t.Parallel()
-	tests := []struct {
-		name               string
+	handlertest.Run(t, func(t *testing.T, kind handlertest.Kind) {
+		ctx := context.TODO()
+		checkCategoryTree(ctx, t, handlertest.New(ctx, t, kind))
+	})
+}
+
+// checkCategoryTree builds a tree of categories and checks the nested list, the parent
+// of a subcategory and the cycle prevention.
+func checkCategoryTree(ctx context.Context, t *testing.T, repo categoryRepository) {
+	t.Helper()
+	steps := []struct {
+		method             string
+		request            string
+		body               string
 		expectedStatusCode int
 	}{
+		{"POST", "/categories", `{"code": "CAT004", "name": "Shirts", "parent": "CAT001"}`,
+			http.StatusOK},
+		{"POST", "/categories", `{"code": "CAT005", "name": "Polos", "parent": "CAT004"}`,
+			http.StatusOK},
+		{"POST", "/categories", `{"code": "CAT006", "name": "Hats", "parent": "CAT009"}`,
+			http.StatusBadRequest},
+		{"PUT", "/categories/CAT001", `{"name": "Clothing", "parent": "CAT005"}`,
+			http.StatusConflict},
+		{"DELETE", "/categories/CAT004", "", http.StatusConflict},
+		{"GET", "/categories?tree=yes", "", http.StatusBadRequest},
+	}
+	for _, step := range steps {
+		req, err := http.NewRequestWithContext(ctx, step.method, step.request,
+			strings.NewReader(step.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("If-Match", "*")
+		recorder := doRequest(t, repo, req)
+		if recorder.Code != step.expectedStatusCode {
+			t.Fatalf("Unexpected status code of %s %s: expected %d, got %d: %s", step.method,
+				step.request, step.expectedStatusCode, recorder.Code, recorder.Body)
+		}
+	}
+
+	for label, request := range map[string]string{
+		"tree":        "/categories?tree=true",
+		"subcategory": "/categories/CAT005",
+	} {
+		req, err := http.NewRequestWithContext(ctx, "GET", request, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recorder := doRequest(t, repo, req)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Error doing the request: %d - %s", recorder.Code, recorder.Body)
+		}
+
+		d := testy.DiffAsJSON(handlertest.Snapshot(t, label), recorder.Body)
+		if d != nil {
+			t.Error(d)
+		}
+	}
+}
+
+func categoryHandlerTests() []categoryHandlerTest {
+	return []categoryHandlerTest{
 		{
-			name:               "list of categories",
+			name:               "create category",
+			method:             "POST",
+			request:            "/categories",
+			body:               `{"code": "CAT004", "name": "Shirts", "parent": "CAT001"}`,
+			etag:               `"1"`,
 			expectedStatusCode: http.StatusOK,
 		},
+		{
+			name:               "create duplicated category",
+			method:             "POST",
+			request:            "/categories",
+			body:               `{"code": "CAT001", "name": "Shirts"}`,
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:               "get category",
+			method:             "GET",
+			request:            "/categories/CAT002",
+			etag:               `"1"`,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "get non existing category",
+			method:             "GET",
+			request:            "/categories/CAT009",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "update category",
+			method:             "PUT",
+			request:            "/categories/CAT002",
+			ifMatch:            `"1"`,
+			body:               `{"name": "Sneakers"}`,
+			etag:               `"2"`,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "update category with stale version",
+			method:             "PUT",
+			request:            "/categories/CAT002",
+			ifMatch:            `"2"`,
+			body:               `{"name": "Sneakers"}`,
+			expectedStatusCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:               "update category without entity tag",
+			method:             "PUT",
+			request:            "/categories/CAT002",
+			body:               `{"name": "Sneakers"}`,
+			expectedStatusCode: http.StatusPreconditionRequired,
+		},
+		{
+			name:               "update category without name",
+			method:             "PUT",
+			request:            "/categories/CAT002",
+			ifMatch:            `"1"`,
+			body:               `{"code": "CAT002"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "update category code",
+			method:             "PUT",
+			request:            "/categories/CAT002",
+			ifMatch:            `"1"`,
+			body:               `{"code": "CAT009", "name": "Sneakers"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "update non existing category",
+			method:             "PUT",
+			request:            "/categories/CAT009",
+			ifMatch:            `"1"`,
+			body:               `{"name": "Sneakers"}`,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "update category parent",
+			method:             "PUT",
+			request:            "/categories/CAT002",
+			ifMatch:            `"1"`,
+			body:               `{"name": "Shoes", "parent": "CAT001"}`,
+			etag:               `"2"`,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "update category parent to itself",
+			method:             "PUT",
+			request:            "/categories/CAT002",
+			ifMatch:            `"1"`,
+			body:               `{"name": "Shoes", "parent": "CAT002"}`,
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:               "update category with unknown parent",
+			method:             "PUT",
+			request:            "/categories/CAT002",
+			ifMatch:            `"1"`,
+			body:               `{"name": "Shoes", "parent": "CAT009"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "delete category in use",
+			method:             "DELETE",
+			request:            "/categories/CAT002",
+			ifMatch:            `"1"`,
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:               "delete category reassigning the products",
+			method:             "DELETE",
+			request:            "/categories/CAT002?reassign_to=CAT001",
+			ifMatch:            `"1"`,
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "delete category with stale version",
+			method:             "DELETE",
+			request:            "/categories/CAT002?reassign_to=CAT001",
+			ifMatch:            `"2"`,
+			expectedStatusCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:               "delete category reassigning to itself",
+			method:             "DELETE",
+			request:            "/categories/CAT002?reassign_to=CAT002",
+			ifMatch:            `"1"`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "delete category reassigning to unknown category",
+			method:             "DELETE",
+			request:            "/categories/CAT002?reassign_to=CAT009",
+			ifMatch:            `"1"`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "delete non existing category",
+			method:             "DELETE",
+			request:            "/categories/CAT009",
+			ifMatch:            `"1"`,
+			expectedStatusCode: http.StatusNotFound,
+		},
 	}
+}
 
-	for i := range tests {
-		tc := tests[i]
-		t.Run(tc.name, func(t *testing.T) {
-			t.Parallel()
-
-			// Storage initialization.
-			ctx := context.TODO()
-			postgresContainer := startDatabase(ctx, t)
-			defer stopDatabase(t, postgresContainer)
-
-			dbPort, err := postgresContainer.MappedPort(ctx, "5432")
-			if err != nil {
-				t.Errorf("unable to inspect the postgres container: %s", err)
-				t.FailNow()
-			}
-
-			db := database.New(dbUser, dbPassword, dbName, dbPort.Port())
-			err = db.Connect()
-			if err != nil {
-				t.Errorf("unable to connect to the database: %s", err)
-				t.FailNow()
-			}
-
-			defer func() {
-				err = db.Disconnect()
-				if err != nil {
-					t.Errorf("unable to connect to the database: %s", err)
-					t.FailNow()
-				}
-			}()
-
-			cats := NewHandler(db)
-			recorder := httptest.NewRecorder()
-			mux := http.NewServeMux()
-			mux.HandleFunc("GET /categories", cats.HandleGetCategories)
-
-			req, err := http.NewRequestWithContext(ctx, "GET", "/categories", nil)
-			if err != nil {
-				t.Fatal(err)
-			}
-
-			mux.ServeHTTP(recorder, req)
-			if tc.expectedStatusCode != recorder.Code {
-				t.Errorf("Unexpected status code: expected %d, got %d", tc.expectedStatusCode,
-					recorder.Code)
-				t.Errorf("body: %s", recorder.Body)
-				t.FailNow()
-			}
-
-			if tc.expectedStatusCode == http.StatusOK {
-				d := testy.DiffAsJSON(testy.Snapshot(t), recorder.Body)
-				if d != nil {
-					t.Error(d)
-				}
-			}
-		})
-	}
+type postHandlerTest struct {
+	category           io.Reader
+	name               string
+	expectedStatusCode int
 }
 
-// nolint: funlen, gocognit, gocyclo, cyclop
-func TestPostHandler(t *testing.T) {
-	t.Parallel()
-	tests := []struct {
-		category           io.Reader
-		name               string
-		expectedStatusCode int
-	}{
+func postHandlerTests() []postHandlerTest {
+	return []postHandlerTest{
 		{
 			name:               "empty",
 			category:           strings.NewReader(``),
@@ -128,117 +404,20 @@ func TestPostHandler(t *testing.T) {
 			expectedStatusCode: http.StatusOK,
 		},
 	}
-
-	for i := range tests {
-		tc := tests[i]
-		t.Run(tc.name, func(t *testing.T) {
-			t.Parallel()
-
-			// Storage initialization.
-			ctx := context.TODO()
-			postgresContainer := startDatabase(ctx, t)
-			defer stopDatabase(t, postgresContainer)
-
-			dbPort, err := postgresContainer.MappedPort(ctx, "5432")
-			if err != nil {
-				t.Errorf("unable to inspect the postgres container: %s", err)
-				t.FailNow()
-			}
-
-			db := database.New(dbUser, dbPassword, dbName, dbPort.Port())
-			err = db.Connect()
-			if err != nil {
-				t.Errorf("unable to connect to the database: %s", err)
-				t.FailNow()
-			}
-
-			defer func() {
-				err = db.Disconnect()
-				if err != nil {
-					t.Errorf("unable to connect to the database: %s", err)
-					t.FailNow()
-				}
-			}()
-
-			req, err := http.NewRequestWithContext(ctx, "GET", "/categories", nil)
-			if err != nil {
-				t.Fatal(err)
-			}
-
-			recorder := doRequest(t, db, req)
-			if recorder.Code != http.StatusOK {
-				t.Errorf("Error doging the request: %d - %s", recorder.Code, recorder.Body)
-				t.FailNow()
-			}
-
-			d := testy.DiffAsJSON(testy.Snapshot(t, "presave"), recorder.Body)
-			if d != nil {
-				t.Error(d)
-			}
-
-			req, err = http.NewRequestWithContext(ctx, "POST", "/categories", tc.category)
-			if err != nil {
-				t.Fatal(err)
-			}
-
-			recorder = doRequest(t, db, req)
-			if recorder.Code != tc.expectedStatusCode {
-				t.Errorf("Unexpected status code: expected %d, got %d", tc.expectedStatusCode,
-					recorder.Code)
-				t.FailNow()
-			}
-
-			req, err = http.NewRequestWithContext(ctx, "GET", "/categories", nil)
-			if err != nil {
-				t.Fatal(err)
-			}
-
-			recorder = doRequest(t, db, req)
-			if recorder.Code != http.StatusOK {
-				t.Errorf("Error doging the request: %d - %s", recorder.Code, recorder.Body)
-				t.FailNow()
-			}
-
-			d = testy.DiffAsJSON(testy.Snapshot(t, "postsave"), recorder.Body)
-			if d != nil {
-				t.Error(d)
-			}
-		})
-	}
-}
-
-func startDatabase(ctx context.Context, t *testing.T) *postgres.PostgresContainer {
-	t.Helper()
-	container, err := postgres.Run(ctx, "postgres:17-alpine",
-		postgres.WithInitScripts(filepath.Join("testdata", "init-db.sql")),
-		postgres.WithDatabase(dbName),
-		postgres.WithUsername(dbUser),
-		postgres.WithPassword(dbPassword),
-		postgres.BasicWaitStrategies(),
-	)
-	if err != nil {
-		t.Error(err)
-		t.FailNow()
-	}
-
-	return container
-}
-
-func stopDatabase(t *testing.T, container *postgres.PostgresContainer) {
-	t.Helper()
-	if err := testcontainers.TerminateContainer(container); err != nil {
-		t.Errorf("failed to terminate container: %s", err)
-		t.FailNow()
-	}
 }
 
-func doRequest(t *testing.T, db *database.Database, req *http.Request) *httptest.ResponseRecorder {
+func doRequest(t *testing.T, repo categoryRepository,
+	req *http.Request,
+) *httptest.ResponseRecorder {
 	t.Helper()
-	cats := NewHandler(db)
+	cats := NewHandler(repo)
 	recorder := httptest.NewRecorder()
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /categories", cats.HandleGetCategories)
 	mux.HandleFunc("POST /categories", cats.HandlePostCategories)
+	mux.HandleFunc("GET /categories/{code}", cats.HandleGetCategory)
+	mux.HandleFunc("PUT /categories/{code}", cats.HandlePutCategory)
+	mux.HandleFunc("DELETE /categories/{code}", cats.HandleDeleteCategory)
 	mux.ServeHTTP(recorder, req)
 
 	return recorder
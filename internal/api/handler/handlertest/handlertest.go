@@ -0,0 +1,98 @@
+// Package handlertest runs the tests of the API handlers with every storage, the
+// in-memory one and a postgres database, sharing their snapshots.
+package handlertest
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"gitlab.com/flimzy/testy"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/currency"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/storage"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/storage/database/dbtest"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/storage/memory"
+)
+
+// Kind of storage the handlers are tested with.
+type Kind string
+
+const (
+	// Memory is the in-memory storage with the sample data.
+	Memory Kind = "memory"
+	// Database is a postgres database with the seed data. The tests using it are skipped
+	// if docker isn't available.
+	Database Kind = "database"
+)
+
+// kinds are the storages the handlers are tested with.
+var kinds = []Kind{Memory, Database}
+
+// stubRE matches the characters replaced in the names of the snapshots, as in testy.
+var stubRE = regexp.MustCompile(`[\s\/]`)
+
+// Run runs fn with every kind of storage, each one in a parallel subtest named after
+// it.
+func Run(t *testing.T, fn func(t *testing.T, kind Kind)) {
+	t.Helper()
+	for _, kind := range kinds {
+		t.Run(string(kind), func(t *testing.T) {
+			t.Parallel()
+			fn(t, kind)
+		})
+	}
+}
+
+// New returns a new storage of the kind, removed at the end of the test. It compares the
+// prices in different currencies with the test rates.
+func New(ctx context.Context, t *testing.T, kind Kind) storage.Storage {
+	t.Helper()
+	rates := Rates(t)
+	if kind == Database {
+		db := dbtest.New(ctx, t)
+		db.SetRates(func() *currency.Rates { return rates })
+
+		return db
+	}
+
+	st, err := memory.NewWithSampleData(ctx)
+	if err != nil {
+		t.Fatalf("unable to initialize the storage: %s", err)
+	}
+
+	st.SetRates(func() *currency.Rates { return rates })
+
+	return st
+}
+
+// Rates returns the exchange rates of the tests, from euros to dollars and yens.
+func Rates(t *testing.T) *currency.Rates {
+	t.Helper()
+	rates, err := currency.NewRates("EUR", map[string]decimal.Decimal{
+		"USD": decimal.RequireFromString("1.0842"),
+		"JPY": decimal.RequireFromString("162.51"),
+	})
+	if err != nil {
+		t.Fatalf("unable to initialize the exchange rates: %s", err)
+	}
+
+	return rates
+}
+
+// Snapshot returns the snapshot file of the test, like testy.Snapshot, without the
+// subtest of the storage in its name, so all the storages share the snapshots.
+func Snapshot(t *testing.T, suffix ...string) *testy.File {
+	t.Helper()
+	parts := strings.Split(t.Name(), "/")
+	if len(parts) > 1 && slices.Contains(kinds, Kind(parts[1])) {
+		parts = slices.Delete(parts, 1, 2)
+	}
+
+	name := stubRE.ReplaceAllString(strings.Join(append(parts, suffix...), "_"), "_")
+
+	return &testy.File{Path: "testdata/" + strings.ReplaceAll(name, ":", "_")}
+}
@@ -0,0 +1,111 @@
+// Package health implements the liveness and readiness probes of the API.
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/response"
+)
+
+const (
+	// StatusOK is the status of a healthy service or dependency.
+	StatusOK = "ok"
+	// StatusFail is the status of a failing service or dependency.
+	StatusFail = "fail"
+	// StatusShuttingDown is the status of a service that is shutting down.
+	StatusShuttingDown = "shutting_down"
+
+	// defaultTimeout is the maximum duration of a dependency check.
+	defaultTimeout = 2 * time.Second
+)
+
+// Check of a dependency of the service, like the database.
+type Check struct {
+	// Name of the dependency.
+	Name string
+	// Fn returns an error if the dependency is not ready.
+	Fn func(ctx context.Context) error
+}
+
+// DependencyStatus is the status of a dependency in the readiness response.
+type DependencyStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// StatusResponse defines the API response of the probes.
+type StatusResponse struct {
+	Checks map[string]DependencyStatus `json:"checks,omitempty"`
+	Status string                      `json:"status"`
+}
+
+type Handler struct {
+	checks       []Check
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+// NewHandler returns a new health handler. The service is ready when all the checks
+// succeed.
+func NewHandler(checks ...Check) *Handler {
+	return &Handler{
+		checks:  checks,
+		timeout: defaultTimeout,
+	}
+}
+
+// ShutDown marks the service as shutting down, so it's not ready anymore.
+func (h *Handler) ShutDown() {
+	h.shuttingDown.Store(true)
+}
+
+// HandleHealthz handles the liveness probe. It succeeds while the process is up.
+func (h *Handler) HandleHealthz(w http.ResponseWriter, _ *http.Request) {
+	response.OKResponse(w, StatusResponse{Status: StatusOK})
+}
+
+// HandleReadyz handles the readiness probe. It checks all the dependencies, and it
+// fails if any of them fails or the service is shutting down.
+func (h *Handler) HandleReadyz(w http.ResponseWriter, req *http.Request) {
+	if h.shuttingDown.Load() {
+		response.JSONResponse(w, http.StatusServiceUnavailable,
+			StatusResponse{Status: StatusShuttingDown})
+
+		return
+	}
+
+	resp := StatusResponse{
+		Status: StatusOK,
+		Checks: make(map[string]DependencyStatus, len(h.checks)),
+	}
+	for _, check := range h.checks {
+		status := h.runCheck(req.Context(), check)
+		if status.Status != StatusOK {
+			resp.Status = StatusFail
+		}
+
+		resp.Checks[check.Name] = status
+	}
+
+	code := http.StatusOK
+	if resp.Status != StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+
+	response.JSONResponse(w, code, resp)
+}
+
+// runCheck runs the check with the timeout of the handler.
+func (h *Handler) runCheck(ctx context.Context, check Check) DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	if err := check.Fn(ctx); err != nil {
+		return DependencyStatus{Status: StatusFail, Error: err.Error()}
+	}
+
+	return DependencyStatus{Status: StatusOK}
+}
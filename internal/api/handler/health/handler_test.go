@@ -0,0 +1,92 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func probe(t *testing.T, handler http.HandlerFunc) (int, StatusResponse) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	var resp StatusResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+
+	return w.Code, resp
+}
+
+func TestHandleHealthz(t *testing.T) {
+	t.Parallel()
+	h := NewHandler(Check{Name: "database", Fn: func(context.Context) error {
+		return errors.New("unreachable")
+	}})
+
+	// The liveness doesn't depend on the dependencies.
+	code, resp := probe(t, h.HandleHealthz)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusResponse{Status: StatusOK}, resp)
+}
+
+func TestHandleReadyz(t *testing.T) {
+	t.Parallel()
+	dbErr := errors.New("unreachable")
+	var failing error
+	h := NewHandler(
+		Check{Name: "database", Fn: func(context.Context) error { return failing }},
+		Check{Name: "migrations", Fn: func(context.Context) error { return nil }},
+	)
+
+	code, resp := probe(t, h.HandleReadyz)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusResponse{
+		Status: StatusOK,
+		Checks: map[string]DependencyStatus{
+			"database":   {Status: StatusOK},
+			"migrations": {Status: StatusOK},
+		},
+	}, resp)
+
+	failing = dbErr
+	code, resp = probe(t, h.HandleReadyz)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusResponse{
+		Status: StatusFail,
+		Checks: map[string]DependencyStatus{
+			"database":   {Status: StatusFail, Error: dbErr.Error()},
+			"migrations": {Status: StatusOK},
+		},
+	}, resp)
+
+	failing = nil
+	h.ShutDown()
+	code, resp = probe(t, h.HandleReadyz)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusResponse{Status: StatusShuttingDown}, resp)
+}
+
+func TestHandleReadyzTimeout(t *testing.T) {
+	t.Parallel()
+	h := NewHandler(Check{Name: "database", Fn: func(ctx context.Context) error {
+		<-ctx.Done()
+
+		return ctx.Err()
+	}})
+	h.timeout = 10 * time.Millisecond
+
+	code, resp := probe(t, h.HandleReadyz)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, DependencyStatus{
+		Status: StatusFail,
+		Error:  context.DeadlineExceeded.Error(),
+	}, resp.Checks["database"])
+}
@@ -0,0 +1,314 @@
+// Package inventory implements the inventory handler for the API, with the stock of the
+// variants and the reservations of their units.
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/response"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/inventory"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/variant"
+)
+
+// Stock response from the API, with the units available to be reserved.
+type Stock struct {
+	UpdatedAt time.Time `json:"updated_at"`
+	SKU       string    `json:"sku"`
+	OnHand    int64     `json:"on_hand"`
+	Reserved  int64     `json:"reserved"`
+	Available int64     `json:"available"`
+	InStock   bool      `json:"in_stock"`
+}
+
+// StockResponse defines the API response for the stock of a variant.
+type StockResponse struct {
+	Stock Stock `json:"stock"`
+}
+
+// AdjustmentRequest defines the API request to add units to the stock of a variant, or
+// to remove them with a negative delta.
+type AdjustmentRequest struct {
+	Delta int64 `json:"delta"`
+}
+
+// Line of a reservation request and response.
+type Line struct {
+	SKU      string `json:"sku"`
+	Quantity int64  `json:"quantity"`
+}
+
+// ReservationRequest defines the API request to reserve the units of several variants.
+// The reservation expires after the ttl seconds, 15 minutes by default.
+type ReservationRequest struct {
+	Lines []Line `json:"lines"`
+	TTL   int64  `json:"ttl"`
+}
+
+// Reservation response from the API.
+type Reservation struct {
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Status    string    `json:"status"`
+	Lines     []Line    `json:"lines"`
+	ID        uint      `json:"id"`
+}
+
+// ReservationResponse defines the API response for a single reservation.
+type ReservationResponse struct {
+	Reservation Reservation `json:"reservation"`
+}
+
+// ExpiredResponse defines the API response of the expiration of the reservations.
+type ExpiredResponse struct {
+	Expired int64 `json:"expired"`
+}
+
+type inventoryRepository interface {
+	// GetStock obtains the stock of the variant with the given SKU.
+	GetStock(ctx context.Context, sku string) (*inventory.Stock, error)
+	// AdjustStock adds the delta to the units on hand of the variant with the given SKU.
+	// It fails with an insufficient stock error if there would be fewer units on hand
+	// than reserved.
+	AdjustStock(ctx context.Context, sku string, delta int64) (*inventory.Stock, error)
+	// CreateReservation reserves the units of all the lines of the reservation, or none
+	// of them, failing with an insufficient stock error, if any variant doesn't have
+	// enough units available.
+	CreateReservation(ctx context.Context, r *inventory.Reservation) error
+	// GetReservation obtains a reservation by its id.
+	GetReservation(ctx context.Context, id uint) (*inventory.Reservation, error)
+	// CommitReservation removes the reserved units from the stock on hand. It fails if
+	// the reservation isn't active or it's expired.
+	CommitReservation(ctx context.Context, id uint) (*inventory.Reservation, error)
+	// ReleaseReservation makes the reserved units available again. It fails if the
+	// reservation isn't active.
+	ReleaseReservation(ctx context.Context, id uint) (*inventory.Reservation, error)
+	// ExpireReservations releases the active reservations after their expiration time,
+	// and returns how many of them expired.
+	ExpireReservations(ctx context.Context) (int64, error)
+}
+
+type Handler struct {
+	repo inventoryRepository
+	// now returns the time of the requests, used to set the expiration of the
+	// reservations.
+	now func() time.Time
+}
+
+// NewHandler returns a new api handler.
+func NewHandler(r inventoryRepository) *Handler {
+	return &Handler{
+		repo: r,
+		now:  time.Now,
+	}
+}
+
+// HandleGetStock handles the get of the stock of a variant by its SKU.
+func (h *Handler) HandleGetStock(w http.ResponseWriter, req *http.Request) {
+	stock, err := h.repo.GetStock(req.Context(), req.PathValue("sku"))
+	if err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	response.OKResponse(w, StockResponse{Stock: newStock(stock)})
+}
+
+// HandlePostAdjustment handles the adjustment of the units on hand of a variant. The
+// adjustments are relative to the current stock, so the concurrent ones don't overwrite
+// each other.
+func (h *Handler) HandlePostAdjustment(w http.ResponseWriter, req *http.Request) {
+	var body AdjustmentRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	stock, err := h.repo.AdjustStock(req.Context(), req.PathValue("sku"), body.Delta)
+	if err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	response.OKResponse(w, StockResponse{Stock: newStock(stock)})
+}
+
+// HandlePostReservation handles the reservation of the units of several variants. All
+// the lines are reserved, or none of them if any variant doesn't have enough units
+// available.
+func (h *Handler) HandlePostReservation(w http.ResponseWriter, req *http.Request) {
+	var body ReservationRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	r, err := body.toModel(h.now())
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	if err := h.repo.CreateReservation(req.Context(), r); err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	response.CreatedResponse(w, fmt.Sprintf("/reservations/%d", r.ID),
+		ReservationResponse{Reservation: newReservation(r)})
+}
+
+// HandleGetReservation handles the get of a reservation by its id.
+func (h *Handler) HandleGetReservation(w http.ResponseWriter, req *http.Request) {
+	h.handleReservation(w, req, h.repo.GetReservation)
+}
+
+// HandleCommitReservation handles the commit of a reservation, removing its units from
+// the stock. It fails if the reservation isn't active or it's expired.
+func (h *Handler) HandleCommitReservation(w http.ResponseWriter, req *http.Request) {
+	h.handleReservation(w, req, h.repo.CommitReservation)
+}
+
+// HandleDeleteReservation handles the release of a reservation, making its units
+// available again. It fails if the reservation isn't active.
+func (h *Handler) HandleDeleteReservation(w http.ResponseWriter, req *http.Request) {
+	id, err := reservationID(req)
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	if _, err := h.repo.ReleaseReservation(req.Context(), id); err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	response.NoContentResponse(w)
+}
+
+// HandleExpireReservations handles the release of the reservations after their
+// expiration time. They are released periodically too, and before reserving their
+// variants again.
+func (h *Handler) HandleExpireReservations(w http.ResponseWriter, req *http.Request) {
+	expired, err := h.repo.ExpireReservations(req.Context())
+	if err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	response.OKResponse(w, ExpiredResponse{Expired: expired})
+}
+
+// handleReservation handles a request on the reservation of the path, responding with
+// the reservation returned by fn.
+func (h *Handler) handleReservation(w http.ResponseWriter, req *http.Request,
+	fn func(ctx context.Context, id uint) (*inventory.Reservation, error),
+) {
+	id, err := reservationID(req)
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	r, err := fn(req.Context(), id)
+	if err != nil {
+		writeError(w, err)
+
+		return
+	}
+
+	response.OKResponse(w, ReservationResponse{Reservation: newReservation(r)})
+}
+
+// reservationID returns the id of the reservation of the path.
+func reservationID(req *http.Request) (uint, error) {
+	id, err := strconv.ParseUint(req.PathValue("id"), 10, 0)
+	if err != nil {
+		return 0, fmt.Errorf("invalid reservation id %q", req.PathValue("id"))
+	}
+
+	return uint(id), nil
+}
+
+func newStock(s *inventory.Stock) Stock {
+	return Stock{
+		UpdatedAt: s.UpdatedAt,
+		SKU:       s.SKU,
+		OnHand:    s.OnHand,
+		Reserved:  s.Reserved,
+		Available: s.Available(),
+		InStock:   s.InStock(),
+	}
+}
+
+func newReservation(r *inventory.Reservation) Reservation {
+	lines := make([]Line, 0, len(r.Lines))
+	for _, l := range r.Lines {
+		lines = append(lines, Line{SKU: l.SKU, Quantity: l.Quantity})
+	}
+
+	return Reservation{
+		ExpiresAt: r.ExpiresAt,
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+		Status:    string(r.Status),
+		Lines:     lines,
+		ID:        r.ID,
+	}
+}
+
+// toModel returns the model reservation of the request, expiring after its ttl from now,
+// in UTC. The ttl can't be negative nor longer than the maximum one.
+func (r *ReservationRequest) toModel(now time.Time) (*inventory.Reservation, error) {
+	ttl := inventory.DefaultTTL
+	if r.TTL != 0 {
+		ttl = time.Duration(r.TTL) * time.Second
+	}
+
+	if ttl <= 0 || ttl > inventory.MaxTTL {
+		return nil, fmt.Errorf("%w: the ttl must be between 1 and %d seconds",
+			inventory.ErrInvalidReservation, int64(inventory.MaxTTL.Seconds()))
+	}
+
+	res := &inventory.Reservation{
+		ExpiresAt: now.Add(ttl).UTC(),
+		Lines:     make([]inventory.Line, 0, len(r.Lines)),
+	}
+	for _, l := range r.Lines {
+		res.Lines = append(res.Lines, inventory.Line{SKU: l.SKU, Quantity: l.Quantity})
+	}
+
+	return res, nil
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, inventory.ErrInvalidQuantity),
+		errors.Is(err, inventory.ErrInvalidReservation):
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, variant.ErrNotFound), errors.Is(err, inventory.ErrReservationNotFound):
+		response.ErrorResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, inventory.ErrInsufficientStock),
+		errors.Is(err, inventory.ErrReservationClosed),
+		errors.Is(err, inventory.ErrReservationExpired):
+		response.ErrorResponse(w, http.StatusConflict, err.Error())
+	default:
+		response.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+	}
+}
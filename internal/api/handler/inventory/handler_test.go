@@ -0,0 +1,406 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/handler/handlertest"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/inventory"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/variant"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/storage"
+)
+
+func TestInventoryHandler(t *testing.T) {
+	t.Parallel()
+	handlertest.Run(t, func(t *testing.T, kind handlertest.Kind) {
+		tests := inventoryHandlerTests()
+		for i := range tests {
+			tc := tests[i]
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+				ctx := context.TODO()
+				checkInventoryRequest(ctx, t, handlertest.New(ctx, t, kind), &tc)
+			})
+		}
+	})
+}
+
+// checkInventoryRequest adds 5 units of SKU001A to the stock, reserving 2 of them in the
+// reservation 1, does the request and checks its response, and the units of SKU001A
+// available after it.
+func checkInventoryRequest(ctx context.Context, t *testing.T, repo inventoryRepository,
+	tc *inventoryHandlerTest,
+) {
+	t.Helper()
+	if _, err := repo.AdjustStock(ctx, "SKU001A", 5); err != nil {
+		t.Fatal(err)
+	}
+
+	err := repo.CreateReservation(ctx, &inventory.Reservation{
+		ExpiresAt: time.Now().Add(time.Hour),
+		Lines:     []inventory.Line{{SKU: "SKU001A", Quantity: 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, tc.method, tc.request, strings.NewReader(tc.body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := doRequest(t, repo, req)
+	if recorder.Code != tc.expectedStatusCode {
+		t.Fatalf("Unexpected status code: expected %d, got %d: %s", tc.expectedStatusCode,
+			recorder.Code, recorder.Body)
+	}
+
+	if location := recorder.Header().Get("Location"); location != tc.location {
+		t.Errorf("Unexpected location: expected %q, got %q", tc.location, location)
+	}
+
+	req, err = http.NewRequestWithContext(ctx, "GET", "/inventory/SKU001A", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder = doRequest(t, repo, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Error doing the request: %d - %s", recorder.Code, recorder.Body)
+	}
+
+	var resp StockResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Stock.OnHand != tc.onHand || resp.Stock.Available != tc.available {
+		t.Errorf("Unexpected stock: expected %d on hand and %d available, got %+v",
+			tc.onHand, tc.available, resp.Stock)
+	}
+}
+
+type inventoryHandlerTest struct {
+	name     string
+	method   string
+	request  string
+	body     string
+	location string
+	// onHand and available are the expected units of SKU001A after the request.
+	onHand             int64
+	available          int64
+	expectedStatusCode int
+}
+
+// nolint: funlen
+func inventoryHandlerTests() []inventoryHandlerTest {
+	return []inventoryHandlerTest{
+		{
+			name:               "get stock",
+			method:             "GET",
+			request:            "/inventory/SKU001A",
+			onHand:             5,
+			available:          3,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "get stock of non existing variant",
+			method:             "GET",
+			request:            "/inventory/SKU009A",
+			onHand:             5,
+			available:          3,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "add stock",
+			method:             "POST",
+			request:            "/inventory/SKU001A/adjustments",
+			body:               `{"delta": 4}`,
+			onHand:             9,
+			available:          7,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "remove stock",
+			method:             "POST",
+			request:            "/inventory/SKU001A/adjustments",
+			body:               `{"delta": -3}`,
+			onHand:             2,
+			available:          0,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "remove reserved stock",
+			method:             "POST",
+			request:            "/inventory/SKU001A/adjustments",
+			body:               `{"delta": -4}`,
+			onHand:             5,
+			available:          3,
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:               "zero adjustment",
+			method:             "POST",
+			request:            "/inventory/SKU001A/adjustments",
+			body:               `{"delta": 0}`,
+			onHand:             5,
+			available:          3,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "create reservation",
+			method:             "POST",
+			request:            "/reservations",
+			body:               `{"lines": [{"sku": "SKU001A", "quantity": 3}], "ttl": 60}`,
+			location:           "/reservations/2",
+			onHand:             5,
+			available:          0,
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "create reservation without stock",
+			method:             "POST",
+			request:            "/reservations",
+			body:               `{"lines": [{"sku": "SKU001A", "quantity": 4}]}`,
+			onHand:             5,
+			available:          3,
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:    "create reservation with a line without stock",
+			method:  "POST",
+			request: "/reservations",
+			body: `{"lines": [{"sku": "SKU001A", "quantity": 1},
+				{"sku": "SKU001B", "quantity": 1}]}`,
+			onHand:             5,
+			available:          3,
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:               "create reservation of non existing variant",
+			method:             "POST",
+			request:            "/reservations",
+			body:               `{"lines": [{"sku": "SKU009A", "quantity": 1}]}`,
+			onHand:             5,
+			available:          3,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "create reservation without lines",
+			method:             "POST",
+			request:            "/reservations",
+			body:               `{"lines": []}`,
+			onHand:             5,
+			available:          3,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "create reservation with invalid ttl",
+			method:             "POST",
+			request:            "/reservations",
+			body:               `{"lines": [{"sku": "SKU001A", "quantity": 1}], "ttl": -1}`,
+			onHand:             5,
+			available:          3,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "get reservation",
+			method:             "GET",
+			request:            "/reservations/1",
+			onHand:             5,
+			available:          3,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "get non existing reservation",
+			method:             "GET",
+			request:            "/reservations/9",
+			onHand:             5,
+			available:          3,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "get reservation with invalid id",
+			method:             "GET",
+			request:            "/reservations/first",
+			onHand:             5,
+			available:          3,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "commit reservation",
+			method:             "POST",
+			request:            "/reservations/1/commit",
+			onHand:             3,
+			available:          3,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "release reservation",
+			method:             "DELETE",
+			request:            "/reservations/1",
+			onHand:             5,
+			available:          5,
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "expire reservations",
+			method:             "POST",
+			request:            "/reservations/expire",
+			onHand:             5,
+			available:          3,
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+}
+
+func TestReservationLifecycle(t *testing.T) {
+	t.Parallel()
+	handlertest.Run(t, func(t *testing.T, kind handlertest.Kind) {
+		ctx := context.TODO()
+		checkReservationLifecycle(ctx, t, handlertest.New(ctx, t, kind))
+	})
+}
+
+// checkReservationLifecycle creates a reservation of SKU001A, commits it and checks that
+// it can't be committed nor released again.
+func checkReservationLifecycle(ctx context.Context, t *testing.T, repo inventoryRepository) {
+	t.Helper()
+	if _, err := repo.AdjustStock(ctx, "SKU001A", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := doJSONRequest(ctx, t, repo, "POST", "/reservations",
+		`{"lines": [{"sku": "SKU001A", "quantity": 1}]}`)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Unable to create the reservation: %d - %s", recorder.Code, recorder.Body)
+	}
+
+	var resp ReservationResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if r := resp.Reservation; r.Status != "active" || len(r.Lines) != 1 ||
+		r.ExpiresAt.Sub(r.CreatedAt).Round(time.Minute) != inventory.DefaultTTL {
+		t.Errorf("Unexpected reservation: %+v", r)
+	}
+
+	recorder = doJSONRequest(ctx, t, repo, "POST", "/reservations/1/commit", "")
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "committed") {
+		t.Fatalf("Unable to commit the reservation: %d - %s", recorder.Code, recorder.Body)
+	}
+
+	// The closed reservations can't be committed nor released again.
+	recorder = doJSONRequest(ctx, t, repo, "POST", "/reservations/1/commit", "")
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("Unexpected response of the second commit: %d - %s", recorder.Code,
+			recorder.Body)
+	}
+
+	recorder = doJSONRequest(ctx, t, repo, "DELETE", "/reservations/1", "")
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("Unexpected response of the release: %d - %s", recorder.Code, recorder.Body)
+	}
+}
+
+func TestDeleteVariantReservations(t *testing.T) {
+	t.Parallel()
+	handlertest.Run(t, func(t *testing.T, kind handlertest.Kind) {
+		ctx := context.TODO()
+		checkDeleteVariantReservations(ctx, t, handlertest.New(ctx, t, kind))
+	})
+}
+
+// checkDeleteVariantReservations reserves units of SKU001A and SKU001B, removes SKU001A,
+// and checks that the reservation is released and that SKU001A is created again
+// without stock.
+func checkDeleteVariantReservations(ctx context.Context, t *testing.T, repo storage.Storage) {
+	t.Helper()
+	for sku, units := range map[string]int64{"SKU001A": 5, "SKU001B": 2} {
+		if _, err := repo.AdjustStock(ctx, sku, units); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := inventory.Reservation{ExpiresAt: time.Now().Add(time.Hour), Lines: []inventory.Line{
+		{SKU: "SKU001A", Quantity: 2}, {SKU: "SKU001B", Quantity: 1},
+	}}
+	if err := repo.CreateReservation(ctx, &r); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.DeleteVariant(ctx, "PROD001", "SKU001A", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := repo.GetReservation(ctx, r.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Status != inventory.StatusReleased {
+		t.Errorf("Unexpected status of the reservation: %s", res.Status)
+	}
+
+	stock, err := repo.GetStock(ctx, "SKU001B")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stock.OnHand != 2 || stock.Reserved != 0 {
+		t.Errorf("Unexpected stock of SKU001B: %+v", stock)
+	}
+
+	if err := repo.CreateVariant(ctx, "PROD001", &variant.Variant{
+		Name: "Variant A", SKU: "SKU001A", Price: decimal.RequireFromString("10.99"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if stock, err = repo.GetStock(ctx, "SKU001A"); err != nil {
+		t.Fatal(err)
+	}
+
+	if stock.OnHand != 0 || stock.Reserved != 0 {
+		t.Errorf("Unexpected stock of the new SKU001A: %+v", stock)
+	}
+}
+
+func doJSONRequest(ctx context.Context, t *testing.T, repo inventoryRepository, method,
+	request, body string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, method, request, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return doRequest(t, repo, req)
+}
+
+func doRequest(t *testing.T, repo inventoryRepository,
+	req *http.Request,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	stock := NewHandler(repo)
+	recorder := httptest.NewRecorder()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /inventory/{sku}", stock.HandleGetStock)
+	mux.HandleFunc("POST /inventory/{sku}/adjustments", stock.HandlePostAdjustment)
+	mux.HandleFunc("POST /reservations", stock.HandlePostReservation)
+	mux.HandleFunc("POST /reservations/expire", stock.HandleExpireReservations)
+	mux.HandleFunc("GET /reservations/{id}", stock.HandleGetReservation)
+	mux.HandleFunc("POST /reservations/{id}/commit", stock.HandleCommitReservation)
+	mux.HandleFunc("DELETE /reservations/{id}", stock.HandleDeleteReservation)
+	mux.ServeHTTP(recorder, req)
+
+	return recorder
+}
@@ -0,0 +1,117 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/etag"
+)
+
+// Validators of a response, used to answer the conditional requests without sending
+// the payload again.
+type Validators struct {
+	// LastModified is the time of the last change of the payload, or zero if unknown.
+	LastModified time.Time
+	// ETag is the strong entity tag of the payload. If it's empty, the hash of the
+	// encoded payload is used, with the version if it's set.
+	ETag string
+	// Version is the version of the entity of the payload, for the entities whose payload
+	// changes without a new version.
+	Version uint
+	// List is true if the payload is a list. The newest change of its items doesn't
+	// include their removals, so the If-Modified-Since header is ignored for it.
+	List bool
+}
+
+// CachedResponse returns the payload with its validators, or a not modified response
+// if the client already has it. The If-None-Match header has precedence over the
+// If-Modified-Since header, as the entity tags are more precise.
+func CachedResponse(w http.ResponseWriter, req *http.Request, payload any, v Validators) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(payload); err != nil {
+		ErrorResponse(w, http.StatusInternalServerError, err.Error())
+
+		return
+	}
+
+	tag := v.ETag
+	if tag == "" && v.Version != 0 {
+		tag = etag.FormatContent(v.Version, body.Bytes())
+	} else if tag == "" {
+		tag = etag.Hash(body.Bytes())
+	}
+
+	w.Header().Set("ETag", tag)
+	if !v.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", v.LastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(req, tag, &v) {
+		w.WriteHeader(http.StatusNotModified)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body.Bytes())
+}
+
+// notModified returns true if the client already has the payload with the given entity
+// tag and validators.
+func notModified(req *http.Request, tag string, v *Validators) bool {
+	if etag.HasNoneMatch(req) {
+		return etag.InNoneMatch(req, tag)
+	}
+
+	if v.List || v.LastModified.IsZero() {
+		return false
+	}
+
+	since, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+
+	// The HTTP dates don't have fractions of a second.
+	return !v.LastModified.Truncate(time.Second).After(since)
+}
+
+// CacheControl returns a handler setting the Cache-Control header of the successful
+// and not modified responses of next to the given value. The errors are not cached.
+func CacheControl(value string, next http.HandlerFunc) http.HandlerFunc {
+	if value == "" {
+		return next
+	}
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		next(&cacheControlWriter{ResponseWriter: w, value: value}, req)
+	}
+}
+
+// cacheControlWriter sets the Cache-Control header when the status code is written.
+type cacheControlWriter struct {
+	http.ResponseWriter
+	value       string
+	wroteHeader bool
+}
+
+func (w *cacheControlWriter) WriteHeader(code int) {
+	if !w.wroteHeader && (code == http.StatusOK || code == http.StatusNotModified) {
+		w.Header().Set("Cache-Control", w.value)
+	}
+
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *cacheControlWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return w.ResponseWriter.Write(b)
+}
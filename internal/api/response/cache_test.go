@@ -0,0 +1,138 @@
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/api/etag"
+)
+
+func TestCachedResponse(t *testing.T) {
+	t.Parallel()
+	modified := time.Date(2025, 3, 1, 10, 30, 0, 500, time.UTC)
+	payload := map[string]string{"code": "PROD001"}
+	hash := etag.Hash([]byte(`{"code":"PROD001"}` + "\n"))
+	tests := map[string]struct {
+		headers    map[string]string
+		validators Validators
+		code       int
+	}{
+		"without conditions": {
+			validators: Validators{ETag: `"1"`, LastModified: modified},
+			code:       http.StatusOK,
+		},
+		"matching entity tag": {
+			headers:    map[string]string{"If-None-Match": `"2", W/"1"`},
+			validators: Validators{ETag: `"1"`},
+			code:       http.StatusNotModified,
+		},
+		"any entity tag": {
+			headers:    map[string]string{"If-None-Match": "*"},
+			validators: Validators{ETag: `"1"`},
+			code:       http.StatusNotModified,
+		},
+		"other entity tag": {
+			headers: map[string]string{
+				"If-None-Match":     `"2"`,
+				"If-Modified-Since": modified.Format(http.TimeFormat),
+			},
+			validators: Validators{ETag: `"1"`, LastModified: modified},
+			code:       http.StatusOK,
+		},
+		"payload hash": {
+			headers:    map[string]string{"If-None-Match": hash},
+			validators: Validators{},
+			code:       http.StatusNotModified,
+		},
+		"version and payload hash": {
+			headers: map[string]string{
+				"If-None-Match": etag.FormatContent(2, []byte(`{"code":"PROD001"}`+"\n")),
+			},
+			validators: Validators{Version: 2},
+			code:       http.StatusNotModified,
+		},
+		"other payload with the version": {
+			headers:    map[string]string{"If-None-Match": `"2"`},
+			validators: Validators{Version: 2},
+			code:       http.StatusOK,
+		},
+		"not modified since": {
+			headers:    map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)},
+			validators: Validators{ETag: `"1"`, LastModified: modified},
+			code:       http.StatusNotModified,
+		},
+		"modified since": {
+			headers: map[string]string{
+				"If-Modified-Since": modified.Add(-time.Second).Format(http.TimeFormat),
+			},
+			validators: Validators{ETag: `"1"`, LastModified: modified},
+			code:       http.StatusOK,
+		},
+		"invalid modified since": {
+			headers:    map[string]string{"If-Modified-Since": "yesterday"},
+			validators: Validators{ETag: `"1"`, LastModified: modified},
+			code:       http.StatusOK,
+		},
+		"list not modified since": {
+			headers:    map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)},
+			validators: Validators{LastModified: modified, List: true},
+			code:       http.StatusOK,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodGet, "/catalog/PROD001", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+
+			recorder := httptest.NewRecorder()
+			CachedResponse(recorder, req, payload, tc.validators)
+			assert.Equal(t, tc.code, recorder.Code)
+			assert.NotEmpty(t, recorder.Header().Get("ETag"))
+			if tc.validators.ETag != "" {
+				assert.Equal(t, tc.validators.ETag, recorder.Header().Get("ETag"))
+			}
+
+			if !tc.validators.LastModified.IsZero() {
+				assert.Equal(t, "Sat, 01 Mar 2025 10:30:00 GMT",
+					recorder.Header().Get("Last-Modified"))
+			}
+
+			if tc.code == http.StatusOK {
+				assert.JSONEq(t, `{"code":"PROD001"}`, recorder.Body.String())
+			} else {
+				assert.Empty(t, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	t.Parallel()
+	handler := CacheControl("public, no-cache", func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/ok":
+			_, _ = w.Write([]byte("{}"))
+		case "/not-modified":
+			w.WriteHeader(http.StatusNotModified)
+		default:
+			ErrorResponse(w, http.StatusNotFound, "not found")
+		}
+	})
+	tests := map[string]string{
+		"/ok":           "public, no-cache",
+		"/not-modified": "public, no-cache",
+		"/missing":      "",
+	}
+	for path, expected := range tests {
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, expected, recorder.Header().Get("Cache-Control"), path)
+	}
+}
@@ -7,9 +7,25 @@ import (
 )
 
 func OKResponse(w http.ResponseWriter, payload any) {
+	JSONResponse(w, http.StatusOK, payload)
+}
+
+// CreatedResponse returns the created resource, with its location.
+func CreatedResponse(w http.ResponseWriter, location string, payload any) {
+	w.Header().Set("Location", location)
+	JSONResponse(w, http.StatusCreated, payload)
+}
+
+// NoContentResponse returns an empty response.
+func NoContentResponse(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// JSONResponse returns the payload with the given status code.
+func JSONResponse(w http.ResponseWriter, code int, payload any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(code)
 	if err := json.NewEncoder(w).Encode(payload); err != nil {
 		ErrorResponse(w, http.StatusInternalServerError, err.Error())
 	}
@@ -30,6 +30,27 @@ func TestOKResponse(t *testing.T) {
 	})
 }
 
+func TestCreatedResponse(t *testing.T) {
+	t.Parallel()
+	recorder := httptest.NewRecorder()
+	CreatedResponse(recorder, "/catalog/PROD001", map[string]string{"code": "PROD001"})
+
+	assert.Equal(t, http.StatusCreated, recorder.Code, "Expected status code 201 Created")
+	assert.Equal(t, "/catalog/PROD001", recorder.Header().Get("Location"),
+		"Expected the location of the created resource")
+	assert.JSONEq(t, `{"code":"PROD001"}`, recorder.Body.String(),
+		"Response body does not match expected")
+}
+
+func TestNoContentResponse(t *testing.T) {
+	t.Parallel()
+	recorder := httptest.NewRecorder()
+	NoContentResponse(recorder)
+
+	assert.Equal(t, http.StatusNoContent, recorder.Code, "Expected status code 204 No Content")
+	assert.Empty(t, recorder.Body.String(), "Expected an empty body")
+}
+
 func TestErrorResponse(t *testing.T) {
 	t.Parallel()
 	t.Run("json response for a given http status code", func(t *testing.T) {
@@ -0,0 +1,54 @@
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrInvalidConfig is returned when the exchange rates configuration is not valid.
+var ErrInvalidConfig = errors.New("invalid exchange rates configuration")
+
+// Config of the exchange rates.
+type Config struct {
+	// Source is the path of the file or the http url with the rates. Without source
+	// there are no rates, and the prices can only be returned in their own currency.
+	Source string
+	// Refresh is the interval between the loads of the rates (0 loads them only once).
+	Refresh time.Duration
+}
+
+// ConfigFromEnv returns the exchange rates configuration defined in the environment.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{Source: os.Getenv("EXCHANGE_RATES")}
+	if v := os.Getenv("EXCHANGE_RATES_REFRESH"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("%w: invalid EXCHANGE_RATES_REFRESH: %w",
+				ErrInvalidConfig, err)
+		}
+
+		cfg.Refresh = d
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// Validate checks the configuration is valid.
+func (c *Config) Validate() error {
+	errs := make([]error, 0)
+	if c.Refresh < 0 {
+		errs = append(errs, errors.New("the refresh interval can't be negative"))
+	}
+
+	if c.Refresh > 0 && c.Source == "" {
+		errs = append(errs, errors.New("the rates can't be refreshed without source"))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidConfig, errors.Join(errs...))
+	}
+
+	return nil
+}
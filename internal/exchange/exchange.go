@@ -0,0 +1,145 @@
+// Package exchange loads the exchange rates of the currencies from a file or an http
+// endpoint, and converts the prices with them.
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/currency"
+)
+
+const (
+	// requestTimeout is the maximum time to fetch the rates from an http endpoint.
+	requestTimeout = 10 * time.Second
+	// maxSize is the maximum size of the rates document.
+	maxSize = 1 << 20
+)
+
+// document is the json document of the rates, with the amount of every currency
+// equivalent to one unit of the base currency, like
+// {"base": "EUR", "rates": {"USD": 1.0842, "JPY": 162.51}}. The other fields, like the
+// date of the rates, are ignored.
+type document struct {
+	Rates map[string]decimal.Decimal `json:"rates"`
+	Base  string                     `json:"base"`
+}
+
+// Exchange converts the prices with the last rates loaded from its source. It's safe
+// for concurrent use.
+type Exchange struct {
+	rates  atomic.Pointer[currency.Rates]
+	client *http.Client
+	cfg    Config
+}
+
+// New returns an exchange loading the rates from the source of the configuration. The
+// rates aren't available until they are loaded.
+func New(cfg Config) *Exchange {
+	return &Exchange{
+		cfg:    cfg,
+		client: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Load loads the rates from the source. The previous rates are kept if it fails. It
+// doesn't do anything without source.
+func (e *Exchange) Load(ctx context.Context) error {
+	if e.cfg.Source == "" {
+		return nil
+	}
+
+	rates, err := e.fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to load the exchange rates from %s: %w", e.cfg.Source, err)
+	}
+
+	e.rates.Store(rates)
+
+	return nil
+}
+
+// Run reloads the rates on every refresh interval until the context is done. The
+// failed loads are logged, and the previous rates are used until the next load.
+func (e *Exchange) Run(ctx context.Context) {
+	if e.cfg.Refresh <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(e.cfg.Refresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.Load(ctx); err != nil {
+				slog.WarnContext(ctx, "Unable to refresh the exchange rates", "error", err)
+			}
+		}
+	}
+}
+
+// Rates returns the last loaded rates, or nil if they weren't loaded.
+func (e *Exchange) Rates() *currency.Rates {
+	return e.rates.Load()
+}
+
+// Convert returns the amount in the from currency converted to the to currency with
+// the last loaded rates, rounded to the decimals of the to currency.
+func (e *Exchange) Convert(amount decimal.Decimal, from, to string) (decimal.Decimal, error) {
+	return e.rates.Load().Convert(amount, from, to)
+}
+
+// fetch reads the rates from the file or the http url of the source.
+func (e *Exchange) fetch(ctx context.Context) (*currency.Rates, error) {
+	source := e.cfg.Source
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		return Parse(f)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return Parse(resp.Body)
+}
+
+// Parse reads the json document of the rates.
+func Parse(r io.Reader) (*currency.Rates, error) {
+	var doc document
+	if err := json.NewDecoder(io.LimitReader(r, maxSize)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", currency.ErrInvalidRates, err)
+	}
+
+	return currency.NewRates(doc.Base, doc.Rates)
+}
@@ -0,0 +1,121 @@
+package exchange
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/currency"
+)
+
+const rates = `{"base": "EUR", "date": "2025-03-01", "rates": {"USD": 1.0842, "JPY": "162.51"}}`
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	res, err := Parse(strings.NewReader(rates))
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", res.Base())
+
+	price, err := res.Convert(decimal.RequireFromString("10.99"), "EUR", "JPY")
+	require.NoError(t, err)
+	assert.Equal(t, "1786", price.String())
+
+	for name, doc := range map[string]string{
+		"invalid json":     `{"base": "EUR", "rates": [`,
+		"without base":     `{"rates": {"USD": 1.0842}}`,
+		"invalid currency": `{"base": "EUR", "rates": {"usd": 1.0842}}`,
+		"negative rate":    `{"base": "EUR", "rates": {"USD": -1.0842}}`,
+	} {
+		_, err := Parse(strings.NewReader(doc))
+		require.ErrorIs(t, err, currency.ErrInvalidRates, name)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+	ctx := context.TODO()
+	path := filepath.Join(t.TempDir(), "rates.json")
+	require.NoError(t, os.WriteFile(path, []byte(rates), 0o600))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/latest" {
+			http.NotFound(w, req)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(rates))
+	}))
+	t.Cleanup(srv.Close)
+
+	for name, source := range map[string]string{"file": path, "endpoint": srv.URL + "/latest"} {
+		e := New(Config{Source: source})
+		require.Nil(t, e.Rates(), name)
+		require.NoError(t, e.Load(ctx), name)
+
+		price, err := e.Convert(decimal.RequireFromString("10.99"), "EUR", "USD")
+		require.NoError(t, err, name)
+		assert.Equal(t, "11.92", price.String(), name)
+	}
+
+	// The failed loads keep the previous rates.
+	e := New(Config{Source: path})
+	require.NoError(t, e.Load(ctx))
+	e.cfg.Source = srv.URL + "/missing"
+	require.Error(t, e.Load(ctx))
+	require.NotNil(t, e.Rates())
+
+	e.cfg.Source = filepath.Join(t.TempDir(), "missing.json")
+	require.Error(t, e.Load(ctx))
+	require.NotNil(t, e.Rates())
+}
+
+func TestWithoutSource(t *testing.T) {
+	t.Parallel()
+	e := New(Config{})
+	require.NoError(t, e.Load(context.TODO()))
+
+	price, err := e.Convert(decimal.RequireFromString("10.99"), "EUR", "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, "10.99", price.String())
+
+	_, err = e.Convert(decimal.RequireFromString("10.99"), "EUR", "USD")
+	require.ErrorIs(t, err, currency.ErrUnknownRate)
+}
+
+func TestConfigValidate(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		cfg           Config
+		expectedError bool
+	}{
+		"without source": {cfg: Config{}},
+		"with source":    {cfg: Config{Source: "rates.json"}},
+		"with refresh":   {cfg: Config{Source: "rates.json", Refresh: time.Hour}},
+		"negative refresh": {
+			cfg:           Config{Source: "rates.json", Refresh: -time.Hour},
+			expectedError: true,
+		},
+		"refresh without source": {cfg: Config{Refresh: time.Hour}, expectedError: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			err := tc.cfg.Validate()
+			if tc.expectedError {
+				require.ErrorIs(t, err, ErrInvalidConfig)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
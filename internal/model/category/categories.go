@@ -1,16 +1,89 @@
 // Package category contains the category model.
 package category
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
-var ErrInvalidCategory = errors.New("category not valid")
+var (
+	ErrInvalidCategory = errors.New("category not valid")
+	// ErrNotFound is returned when a category doesn't exist.
+	ErrNotFound = errors.New("category not found")
+	// ErrDuplicated is returned when a category with the same code already exists.
+	ErrDuplicated = errors.New("category already exists")
+	// ErrInUse is returned when a category can't be removed because some products or
+	// subcategories belong to it.
+	ErrInUse = errors.New("category in use")
+	// ErrVersionMismatch is returned when a category was modified after the version the
+	// change is based on.
+	ErrVersionMismatch = errors.New("category version mismatch")
+	// ErrCycle is returned when a category is moved under itself or one of its
+	// descendants.
+	ErrCycle = errors.New("category cycle")
+)
 
 // Category of a product.
 type Category struct {
-	Code string
-	Name string
-	ID   uint64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Code      string
+	Name      string
+	// ParentCode is the code of the parent category, empty for the root categories.
+	ParentCode string
+	// Ancestors are the categories above this one, from the root to its parent. They are
+	// only set on the categories read by code and on the categories of the products.
+	Ancestors Categories
+	ID        uint64
+	// Version is increased on every update. If it's set on an update, the category is
+	// only updated if it still has the same version.
+	Version uint
+}
+
+// Path returns the categories from the root to this one, the breadcrumbs of its products.
+func (c *Category) Path() Categories {
+	path := make(Categories, 0, len(c.Ancestors)+1)
+	path = append(path, c.Ancestors...)
+
+	return append(path, *c)
 }
 
 // Categories contains a list of product categories.
 type Categories []Category
+
+// Node of the category tree, with its subcategories.
+type Node struct {
+	Children []Node
+	Category Category
+}
+
+// Tree returns the categories nested under their parents, keeping the order of the list.
+// The categories whose parent isn't in the list are roots of the tree.
+func (c Categories) Tree() []Node {
+	codes := make(map[string]bool, len(c))
+	for i := range c {
+		codes[c[i].Code] = true
+	}
+
+	children := make(map[string][]int, len(c))
+	roots := make([]int, 0)
+	for i := range c {
+		if parent := c[i].ParentCode; parent != "" && codes[parent] && parent != c[i].Code {
+			children[parent] = append(children[parent], i)
+		} else {
+			roots = append(roots, i)
+		}
+	}
+
+	var build func(indexes []int) []Node
+	build = func(indexes []int) []Node {
+		nodes := make([]Node, 0, len(indexes))
+		for _, i := range indexes {
+			nodes = append(nodes, Node{Category: c[i], Children: build(children[c[i].Code])})
+		}
+
+		return nodes
+	}
+
+	return build(roots)
+}
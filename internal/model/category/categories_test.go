@@ -0,0 +1,43 @@
+package category
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTree(t *testing.T) {
+	t.Parallel()
+	cats := Categories{
+		{Code: "CAT001", Name: "Clothing"},
+		{Code: "CAT002", Name: "Shirts", ParentCode: "CAT001"},
+		{Code: "CAT003", Name: "Accessories"},
+		{Code: "CAT004", Name: "Polos", ParentCode: "CAT002"},
+		{Code: "CAT005", Name: "Jeans", ParentCode: "CAT001"},
+		{Code: "CAT006", Name: "Hats", ParentCode: "CAT009"},
+	}
+
+	expected := []Node{
+		{Category: cats[0], Children: []Node{
+			{Category: cats[1], Children: []Node{{Category: cats[3], Children: []Node{}}}},
+			{Category: cats[4], Children: []Node{}},
+		}},
+		{Category: cats[2], Children: []Node{}},
+		// The categories without their parent in the list are roots.
+		{Category: cats[5], Children: []Node{}},
+	}
+	assert.Equal(t, expected, cats.Tree())
+}
+
+func TestPath(t *testing.T) {
+	t.Parallel()
+	root := Category{Code: "CAT001", Name: "Clothing"}
+	assert.Equal(t, Categories{root}, root.Path())
+
+	polos := Category{Code: "CAT004", Name: "Polos", ParentCode: "CAT002", Ancestors: Categories{
+		root, {Code: "CAT002", Name: "Shirts", ParentCode: "CAT001"},
+	}}
+	path := polos.Path()
+	assert.Equal(t, []string{"CAT001", "CAT002", "CAT004"},
+		[]string{path[0].Code, path[1].Code, path[2].Code})
+}
@@ -0,0 +1,171 @@
+// Package currency defines the ISO 4217 currencies of the prices and the exchange rates
+// to convert them.
+package currency
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrInvalidCurrency is returned when a currency isn't an ISO 4217 currency code.
+	ErrInvalidCurrency = errors.New("currency not valid")
+	// ErrInvalidRates is returned when the exchange rates are not valid.
+	ErrInvalidRates = errors.New("exchange rates not valid")
+	// ErrUnknownRate is returned when there is no exchange rate between two currencies.
+	ErrUnknownRate = errors.New("exchange rate not found")
+)
+
+const (
+	// Default is the currency of the prices stored without currency.
+	Default = "EUR"
+	// MaxDecimals is the maximum number of decimals of the currencies.
+	MaxDecimals = 4
+	// defaultDecimals is the number of decimals of most currencies.
+	defaultDecimals = 2
+)
+
+// codes are the ISO 4217 codes of the active currencies, without the precious metals
+// and the testing codes.
+var codes = newSet(`
+	AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND BOB BOV BRL
+	BSD BTN BWP BYN BZD CAD CDF CHE CHF CHW CLF CLP CNY COP COU CRC CUP CVE CZK DJF DKK
+	DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GNF GTQ GYD HKD HNL HTG HUF IDR
+	ILS INR IQD IRR ISK JMD JOD JPY KES KGS KHR KMF KPW KRW KWD KYD KZT LAK LBP LKR LRD
+	LSL LYD MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MXV MYR MZN NAD NGN NIO NOK
+	NPR NZD OMR PAB PEN PGK PHP PKR PLN PYG QAR RON RSD RUB RWF SAR SBD SCR SDG SEK SGD
+	SHP SLE SOS SRD SSP STN SVC SYP SZL THB TJS TMT TND TOP TRY TTD TWD TZS UAH UGX USD
+	USN UYI UYU UYW UZS VED VES VND VUV WST XAF XCD XCG XOF XPF YER ZAR ZMW ZWG`)
+
+// minorUnits are the number of decimals of the currencies without two decimals.
+var minorUnits = map[string]int32{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0,
+	"XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// Validate checks the code is an ISO 4217 currency code, in upper case.
+func Validate(code string) error {
+	if !codes[code] {
+		return fmt.Errorf("%w: %q", ErrInvalidCurrency, code)
+	}
+
+	return nil
+}
+
+// Decimals returns the number of decimals of the amounts of the currency (its minor
+// unit), like 2 for the euro or 0 for the yen.
+func Decimals(code string) int32 {
+	if d, ok := minorUnits[code]; ok {
+		return d
+	}
+
+	return defaultDecimals
+}
+
+// Round returns the amount rounded to the decimals of the currency, half away from
+// zero.
+func Round(amount decimal.Decimal, code string) decimal.Decimal {
+	return amount.Round(Decimals(code))
+}
+
+// Rates are the exchange rates of the currencies, as the amount of every currency
+// equivalent to one unit of the base currency.
+type Rates struct {
+	rates map[string]decimal.Decimal
+	base  string
+}
+
+// NewRates returns the exchange rates of the currencies relative to the base currency.
+// The currencies must be valid and the rates positive.
+func NewRates(base string, rates map[string]decimal.Decimal) (*Rates, error) {
+	if err := Validate(base); err != nil {
+		return nil, fmt.Errorf("%w: invalid base: %w", ErrInvalidRates, err)
+	}
+
+	res := &Rates{base: base, rates: make(map[string]decimal.Decimal, len(rates)+1)}
+	for code, rate := range rates {
+		if err := Validate(code); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRates, err)
+		}
+
+		if !rate.IsPositive() {
+			return nil, fmt.Errorf("%w: the rate of %s must be positive", ErrInvalidRates, code)
+		}
+
+		res.rates[code] = rate
+	}
+
+	res.rates[base] = decimal.NewFromInt(1)
+
+	return res, nil
+}
+
+// Base returns the base currency of the rates.
+func (r *Rates) Base() string {
+	return r.base
+}
+
+// Rate returns the amount of the currency equivalent to one unit of the base currency.
+// It returns false if there is no rate for the currency.
+func (r *Rates) Rate(code string) (decimal.Decimal, bool) {
+	if r == nil {
+		return decimal.Decimal{}, false
+	}
+
+	rate, ok := r.rates[code]
+
+	return rate, ok
+}
+
+// Currencies returns the currencies with a rate, including the base one, sorted by
+// code.
+func (r *Rates) Currencies() []string {
+	if r == nil {
+		return nil
+	}
+
+	return slices.Sorted(maps.Keys(r.rates))
+}
+
+// Convert returns the amount in the from currency converted to the to currency, rounded
+// to the decimals of the to currency. The amounts don't need any rate to be converted
+// to their own currency.
+func (r *Rates) Convert(amount decimal.Decimal, from, to string) (decimal.Decimal, error) {
+	if from == to {
+		return amount, nil
+	}
+
+	var fromRate, toRate decimal.Decimal
+	var ok bool
+	if r != nil {
+		fromRate, ok = r.rates[from]
+		if ok {
+			toRate, ok = r.rates[to]
+		}
+	}
+
+	if !ok {
+		return decimal.Decimal{}, fmt.Errorf("%w: from %s to %s", ErrUnknownRate, from, to)
+	}
+
+	// The amount is multiplied before dividing, so the division is the only inexact
+	// operation.
+	return Round(amount.Mul(toRate).Div(fromRate), to), nil
+}
+
+func newSet(words string) map[string]bool {
+	res := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		res[word] = true
+	}
+
+	return res
+}
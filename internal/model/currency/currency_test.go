@@ -0,0 +1,116 @@
+package currency
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	for _, code := range []string{"EUR", "USD", "JPY", "KWD"} {
+		require.NoError(t, Validate(code), code)
+	}
+
+	for _, code := range []string{"", "eur", "EURO", "XXX", "XAU", "ABC"} {
+		require.ErrorIs(t, Validate(code), ErrInvalidCurrency, code)
+	}
+}
+
+func TestDecimals(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, int32(2), Decimals("EUR"))
+	assert.Equal(t, int32(0), Decimals("JPY"))
+	assert.Equal(t, int32(3), Decimals("KWD"))
+	assert.Equal(t, int32(4), Decimals("CLF"))
+}
+
+func TestNewRates(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		rates map[string]decimal.Decimal
+		base  string
+	}{
+		"invalid base": {base: "EURO"},
+		"invalid currency": {
+			base:  "EUR",
+			rates: map[string]decimal.Decimal{"usd": decimal.NewFromInt(1)},
+		},
+		"zero rate": {
+			base:  "EUR",
+			rates: map[string]decimal.Decimal{"USD": decimal.Zero},
+		},
+		"negative rate": {
+			base:  "EUR",
+			rates: map[string]decimal.Decimal{"USD": decimal.NewFromInt(-1)},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewRates(tc.base, tc.rates)
+			require.ErrorIs(t, err, ErrInvalidRates)
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	t.Parallel()
+	rates, err := NewRates("EUR", map[string]decimal.Decimal{
+		"USD": decimal.RequireFromString("1.0842"),
+		"JPY": decimal.RequireFromString("162.51"),
+		"KWD": decimal.RequireFromString("0.33345"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", rates.Base())
+	assert.Equal(t, []string{"EUR", "JPY", "KWD", "USD"}, rates.Currencies())
+
+	rate, ok := rates.Rate("USD")
+	assert.True(t, ok)
+	assert.Equal(t, "1.0842", rate.String())
+
+	tests := map[string]struct {
+		amount   string
+		from     string
+		to       string
+		expected string
+	}{
+		"same currency":       {amount: "10.99", from: "USD", to: "USD", expected: "10.99"},
+		"from the base":       {amount: "10.99", from: "EUR", to: "USD", expected: "11.92"},
+		"to the base":         {amount: "11.92", from: "USD", to: "EUR", expected: "10.99"},
+		"between other":       {amount: "10.99", from: "USD", to: "JPY", expected: "1647"},
+		"without decimals":    {amount: "10.99", from: "EUR", to: "JPY", expected: "1786"},
+		"with three decimals": {amount: "10.99", from: "EUR", to: "KWD", expected: "3.665"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			res, err := rates.Convert(decimal.RequireFromString(tc.amount), tc.from, tc.to)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, res.String())
+		})
+	}
+
+	_, err = rates.Convert(decimal.NewFromInt(1), "EUR", "GBP")
+	require.ErrorIs(t, err, ErrUnknownRate)
+
+	_, err = rates.Convert(decimal.NewFromInt(1), "GBP", "EUR")
+	require.ErrorIs(t, err, ErrUnknownRate)
+
+	_, ok = rates.Rate("GBP")
+	assert.False(t, ok)
+
+	var missing *Rates
+	_, err = missing.Convert(decimal.NewFromInt(1), "EUR", "USD")
+	require.ErrorIs(t, err, ErrUnknownRate)
+	assert.Empty(t, missing.Currencies())
+}
+
+func TestRound(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "1648", Round(decimal.RequireFromString("1647.5"), "JPY").String())
+	assert.Equal(t, "-1.24", Round(decimal.RequireFromString("-1.235"), "EUR").String())
+	assert.Equal(t, "1.235", Round(decimal.RequireFromString("1.2345"), "KWD").String())
+}
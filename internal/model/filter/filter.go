@@ -1,6 +1,11 @@
 // Package filter defines the filter model to fetch data by the filter.
 package filter
 
+import (
+	"errors"
+	"fmt"
+)
+
 // OperationType defines the filter operation type for comparison (equal, less than,
 // great than, ...).
 type OperationType string
@@ -8,14 +13,133 @@ type OperationType string
 const (
 	// Equal comparison.
 	Equal OperationType = "="
+	// NotEqual comparison.
+	NotEqual OperationType = "!="
 	// LessThan comparison.
 	LessThan OperationType = "<"
+	// LessOrEqual comparison.
+	LessOrEqual OperationType = "<="
+	// GreaterThan comparison.
+	GreaterThan OperationType = ">"
+	// GreaterOrEqual comparison.
+	GreaterOrEqual OperationType = ">="
+	// In checks the value is one of the filter values.
+	In OperationType = "in"
+	// Between checks the value is between the two filter values, both included.
+	Between OperationType = "between"
+	// Prefix checks the value starts with the filter value.
+	Prefix OperationType = "prefix"
+	// Contains checks the value contains the filter value.
+	Contains OperationType = "contains"
 )
 
+// betweenValues is the number of values of the between operation.
+const betweenValues = 2
+
+// ErrInvalidFilter is returned when a filter is malformed.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// FieldError is returned when a filter uses a field that is not allowed.
+type FieldError struct {
+	Field string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("filter field %q not allowed", e.Field)
+}
+
 // Filter defiles a filter for a search, so we can rule out result that doesn't comply
 // with the filter.
+// A filter is either a comparison of the Key field with the Value (or Values for the In
+// and Between operations), or a group of filters (Or or And) where at least one or all
+// of them must match.
 type Filter struct {
 	Key       string
 	Value     string
 	Operation OperationType
+	Values    []string
+	Or        []Filter
+	And       []Filter
+}
+
+// AnyOf returns a filter matching if any of the filters match.
+func AnyOf(filters ...Filter) Filter {
+	return Filter{Or: filters}
+}
+
+// AllOf returns a filter matching if all the filters match.
+func AllOf(filters ...Filter) Filter {
+	return Filter{And: filters}
+}
+
+// IsGroup returns true if the filter is a group of filters.
+func (f *Filter) IsGroup() bool {
+	return len(f.Or) > 0 || len(f.And) > 0
+}
+
+// Keys returns the keys of the filters, including the ones in their groups, in order.
+func Keys(filters ...Filter) []string {
+	keys := make([]string, 0, len(filters))
+	for i := range filters {
+		if filters[i].IsGroup() {
+			keys = append(keys, Keys(filters[i].Or...)...)
+			keys = append(keys, Keys(filters[i].And...)...)
+
+			continue
+		}
+
+		keys = append(keys, filters[i].Key)
+	}
+
+	return keys
+}
+
+// Validate checks the filter is well formed. It doesn't check the fields are allowed,
+// as it depends on the data filtered.
+func (f *Filter) Validate() error {
+	if len(f.Or) > 0 && len(f.And) > 0 {
+		return fmt.Errorf("%w: a group can't be an and and an or at the same time",
+			ErrInvalidFilter)
+	}
+
+	if f.IsGroup() {
+		for _, group := range [][]Filter{f.Or, f.And} {
+			for i := range group {
+				if err := group[i].Validate(); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}
+
+	if f.Key == "" {
+		return fmt.Errorf("%w: empty key", ErrInvalidFilter)
+	}
+
+	switch f.Operation {
+	case Equal, NotEqual, LessThan, LessOrEqual, GreaterThan, GreaterOrEqual, Prefix,
+		Contains:
+		return nil
+	case In:
+		if len(f.Values) == 0 {
+			return fmt.Errorf("%w: %s needs at least one value", ErrInvalidFilter, f.Operation)
+		}
+
+		return nil
+	case Between:
+		if len(f.Values) != betweenValues {
+			return fmt.Errorf("%w: %s needs two values", ErrInvalidFilter, f.Operation)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: unknown operation %q", ErrInvalidFilter, f.Operation)
+}
+
+// IsTextOperation returns true if the operation can only be applied to text fields.
+func (o OperationType) IsTextOperation() bool {
+	return o == Prefix || o == Contains
 }
@@ -0,0 +1,85 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		filter        Filter
+		expectedError bool
+	}{
+		{name: "comparison", filter: Filter{Key: "price", Value: "1", Operation: LessThan}},
+		{name: "in", filter: Filter{Key: "code", Values: []string{"a"}, Operation: In}},
+		{
+			name:   "between",
+			filter: Filter{Key: "price", Values: []string{"1", "2"}, Operation: Between},
+		},
+		{
+			name: "group",
+			filter: AnyOf(
+				Filter{Key: "price", Value: "1", Operation: LessThan},
+				AllOf(Filter{Key: "code", Value: "a", Operation: Prefix}),
+			),
+		},
+		{name: "empty key", filter: Filter{Value: "1", Operation: Equal}, expectedError: true},
+		{
+			name:          "unknown operation",
+			filter:        Filter{Key: "price", Value: "1", Operation: "like"},
+			expectedError: true,
+		},
+		{
+			name:          "in without values",
+			filter:        Filter{Key: "code", Operation: In},
+			expectedError: true,
+		},
+		{
+			name:          "between with one value",
+			filter:        Filter{Key: "price", Values: []string{"1"}, Operation: Between},
+			expectedError: true,
+		},
+		{
+			name:          "invalid filter in group",
+			filter:        AnyOf(Filter{Key: "price", Operation: "like"}),
+			expectedError: true,
+		},
+		{
+			name: "and and or group",
+			filter: Filter{
+				Or:  []Filter{{Key: "price", Value: "1", Operation: Equal}},
+				And: []Filter{{Key: "price", Value: "1", Operation: Equal}},
+			},
+			expectedError: true,
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := tc.filter.Validate()
+			if tc.expectedError {
+				require.ErrorIs(t, err, ErrInvalidFilter)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestKeys(t *testing.T) {
+	t.Parallel()
+	filters := []Filter{
+		{Key: "code", Value: "a", Operation: Prefix},
+		AnyOf(
+			Filter{Key: "price", Value: "1", Operation: LessThan},
+			AllOf(Filter{Key: "category", Value: "1", Operation: Equal}),
+		),
+	}
+	require.Equal(t, []string{"code", "price", "category"}, Keys(filters...))
+	require.Empty(t, Keys())
+}
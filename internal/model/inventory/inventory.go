@@ -0,0 +1,155 @@
+// Package inventory defines the stock of the product variants, keyed by their SKU, and
+// the reservations holding part of it until they are committed, released or expired.
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrInvalidQuantity is returned when a stock adjustment is not valid.
+	ErrInvalidQuantity = errors.New("quantity not valid")
+	// ErrInsufficientStock is returned when there isn't enough stock available for a
+	// reservation, or an adjustment would leave less stock than the reserved one.
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrInvalidReservation is returned when a reservation is not valid.
+	ErrInvalidReservation = errors.New("reservation not valid")
+	// ErrReservationNotFound is returned when a reservation doesn't exist.
+	ErrReservationNotFound = errors.New("reservation not found")
+	// ErrReservationClosed is returned when a reservation was already committed,
+	// released or expired.
+	ErrReservationClosed = errors.New("reservation not active")
+	// ErrReservationExpired is returned when an active reservation is committed after its
+	// expiration time.
+	ErrReservationExpired = errors.New("reservation expired")
+)
+
+const (
+	// maxQuantity is the maximum quantity of an adjustment or a reservation line.
+	maxQuantity = 1_000_000_000
+	// maxLines is the maximum number of lines of a reservation.
+	maxLines = 100
+
+	// DefaultTTL is the time a reservation holds the stock if it doesn't have another one.
+	DefaultTTL = 15 * time.Minute
+	// MaxTTL is the maximum time a reservation can hold the stock.
+	MaxTTL = 24 * time.Hour
+)
+
+// Stock of a variant: the units on hand, and the units of them reserved by the active
+// reservations. The variants without stock have zero units.
+type Stock struct {
+	UpdatedAt time.Time
+	SKU       string
+	OnHand    int64
+	Reserved  int64
+}
+
+// Available returns the units on hand that aren't reserved.
+func (s *Stock) Available() int64 {
+	return max(s.OnHand-s.Reserved, 0)
+}
+
+// InStock returns true if some units are available.
+func (s *Stock) InStock() bool {
+	return s.Available() > 0
+}
+
+// ValidateAdjustment checks the change of the units on hand is not zero nor greater than
+// the maximum quantity.
+func ValidateAdjustment(delta int64) error {
+	if delta == 0 || delta > maxQuantity || delta < -maxQuantity {
+		return fmt.Errorf("%w: invalid adjustment %d", ErrInvalidQuantity, delta)
+	}
+
+	return nil
+}
+
+// Status of a reservation.
+type Status string
+
+const (
+	// StatusActive is the status of the reservations holding their stock.
+	StatusActive Status = "active"
+	// StatusCommitted is the status of the reservations whose units left the stock.
+	StatusCommitted Status = "committed"
+	// StatusReleased is the status of the reservations cancelled before their expiration.
+	StatusReleased Status = "released"
+	// StatusExpired is the status of the reservations not committed in time.
+	StatusExpired Status = "expired"
+)
+
+// Line of a reservation, with the units of a variant.
+type Line struct {
+	SKU      string
+	Quantity int64
+	// VariantID is set by the storage, so the line keeps its variant if the SKU is reused.
+	VariantID uint
+}
+
+// Reservation holds units of several variants until it's committed, when they leave the
+// stock, or released. The active reservations expire at their expiration time, and
+// their units are available again. All the lines are reserved at once, or none of them.
+type Reservation struct {
+	ExpiresAt time.Time
+	// CreatedAt and UpdatedAt are set by the storage on every write.
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Status    Status
+	Lines     []Line
+	ID        uint
+}
+
+// Validate checks the reservation can be stored: it must have between one and the
+// maximum number of lines, each one with a different SKU and a positive quantity, and
+// an expiration time.
+func (r *Reservation) Validate() error {
+	if len(r.Lines) == 0 || len(r.Lines) > maxLines {
+		return fmt.Errorf("%w: it must have between 1 and %d lines", ErrInvalidReservation,
+			maxLines)
+	}
+
+	seen := make(map[string]bool, len(r.Lines))
+	for _, l := range r.Lines {
+		if l.SKU == "" {
+			return fmt.Errorf("%w: the lines must have a SKU", ErrInvalidReservation)
+		}
+
+		if seen[l.SKU] {
+			return fmt.Errorf("%w: duplicated SKU %s", ErrInvalidReservation, l.SKU)
+		}
+
+		if l.Quantity <= 0 || l.Quantity > maxQuantity {
+			return fmt.Errorf("%w: invalid quantity %d of %s", ErrInvalidReservation,
+				l.Quantity, l.SKU)
+		}
+
+		seen[l.SKU] = true
+	}
+
+	if r.ExpiresAt.IsZero() {
+		return fmt.Errorf("%w: the expiration time is required", ErrInvalidReservation)
+	}
+
+	return nil
+}
+
+// Expired returns true if the reservation is still active after its expiration time.
+func (r *Reservation) Expired(now time.Time) bool {
+	return r.Status == StatusActive && !r.ExpiresAt.After(now)
+}
+
+// ValidateCommit checks the reservation can be committed at the given time: it must be
+// active and not expired.
+func (r *Reservation) ValidateCommit(now time.Time) error {
+	switch {
+	case r.Status != StatusActive:
+		return fmt.Errorf("%w: it's %s", ErrReservationClosed, r.Status)
+	case r.Expired(now):
+		return ErrReservationExpired
+	default:
+		return nil
+	}
+}
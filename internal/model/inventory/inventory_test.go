@@ -0,0 +1,93 @@
+package inventory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAvailable(t *testing.T) {
+	t.Parallel()
+	s := Stock{OnHand: 5, Reserved: 3}
+	assert.Equal(t, int64(2), s.Available())
+	assert.True(t, s.InStock())
+
+	s.Reserved = 5
+	assert.Equal(t, int64(0), s.Available())
+	assert.False(t, s.InStock())
+}
+
+func TestValidateAdjustment(t *testing.T) {
+	t.Parallel()
+	require.NoError(t, ValidateAdjustment(5))
+	require.NoError(t, ValidateAdjustment(-5))
+	require.ErrorIs(t, ValidateAdjustment(0), ErrInvalidQuantity)
+	require.ErrorIs(t, ValidateAdjustment(maxQuantity+1), ErrInvalidQuantity)
+	require.ErrorIs(t, ValidateAdjustment(-maxQuantity-1), ErrInvalidQuantity)
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	expires := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		reservation   Reservation
+		expectedError bool
+	}{
+		"valid": {
+			reservation: Reservation{ExpiresAt: expires, Lines: []Line{
+				{SKU: "SKU001A", Quantity: 2}, {SKU: "SKU001B", Quantity: 1},
+			}},
+		},
+		"without lines": {
+			reservation:   Reservation{ExpiresAt: expires},
+			expectedError: true,
+		},
+		"without sku": {
+			reservation:   Reservation{ExpiresAt: expires, Lines: []Line{{Quantity: 1}}},
+			expectedError: true,
+		},
+		"duplicated sku": {
+			reservation: Reservation{ExpiresAt: expires, Lines: []Line{
+				{SKU: "SKU001A", Quantity: 2}, {SKU: "SKU001A", Quantity: 1},
+			}},
+			expectedError: true,
+		},
+		"zero quantity": {
+			reservation: Reservation{ExpiresAt: expires,
+				Lines: []Line{{SKU: "SKU001A"}}},
+			expectedError: true,
+		},
+		"without expiration": {
+			reservation:   Reservation{Lines: []Line{{SKU: "SKU001A", Quantity: 1}}},
+			expectedError: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			err := tc.reservation.Validate()
+			if tc.expectedError {
+				require.ErrorIs(t, err, ErrInvalidReservation)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateCommit(t *testing.T) {
+	t.Parallel()
+	expires := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	r := Reservation{ExpiresAt: expires, Status: StatusActive}
+	require.NoError(t, r.ValidateCommit(expires.Add(-time.Second)))
+	assert.False(t, r.Expired(expires.Add(-time.Second)))
+
+	require.ErrorIs(t, r.ValidateCommit(expires), ErrReservationExpired)
+	assert.True(t, r.Expired(expires))
+
+	r.Status = StatusReleased
+	require.ErrorIs(t, r.ValidateCommit(expires.Add(-time.Second)), ErrReservationClosed)
+	assert.False(t, r.Expired(expires))
+}
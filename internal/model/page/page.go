@@ -0,0 +1,27 @@
+// Package page defines the pagination model to fetch a page of a list of elements,
+// either by offset or by cursor (keyset pagination).
+package page
+
+import "errors"
+
+// ErrInvalidCursor is returned when a cursor is not valid.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor points to an element of a sorted list, so we can fetch the elements after or
+// before it regardless of the elements inserted or removed before it.
+type Cursor struct {
+	// Values of the sort fields of the element, in the same order as the sort.
+	Values []string
+	// ID of the element, used to break the ties of the sort fields.
+	ID uint
+	// Backward is true to fetch the elements before the cursor, and false to fetch the
+	// elements after it.
+	Backward bool
+}
+
+// Page defines the page of a list to fetch. If the cursor is set the offset is ignored.
+type Page struct {
+	Cursor *Cursor
+	Limit  int
+	Offset int
+}
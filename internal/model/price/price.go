@@ -0,0 +1,149 @@
+// Package price defines the scheduled prices of the products and their variants, with
+// their validity windows and their sale prices.
+package price
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/currency"
+)
+
+var (
+	// ErrNotFound is returned when a price doesn't exist.
+	ErrNotFound = errors.New("price not found")
+	// ErrInvalidPrice is returned when a price is not valid.
+	ErrInvalidPrice = errors.New("price not valid")
+)
+
+// maxAmount is the first amount not allowed, as the amounts are stored with 12 digits,
+// four of them decimals.
+var maxAmount = decimal.New(1, 8)
+
+// Price is a price of a product, or of one of its variants, valid from a time and
+// optionally until another one. The prices can't be modified, a new price replaces the
+// previous ones from its start.
+type Price struct {
+	// EffectiveFrom is the start of the validity of the price, and EffectiveTo its end,
+	// not included. A zero EffectiveTo means the price doesn't expire.
+	EffectiveFrom time.Time
+	EffectiveTo   time.Time
+	CreatedAt     time.Time
+	// SKU is the variant of the price, or empty for the price of the product.
+	SKU    string
+	Amount decimal.Decimal
+	// CompareAt is the original price of a sale, shown next to the amount. It's zero if
+	// the price isn't a sale.
+	CompareAt decimal.Decimal
+	// Currency is the ISO 4217 code of the currency of the amounts.
+	Currency string
+	ID       uint
+}
+
+// Validate checks the price can be stored: the amount must be positive with at most
+// the decimals of its currency, the original price of a sale must be greater than the
+// amount, and the validity must have a start and end after it.
+func (p *Price) Validate() error {
+	if err := currency.Validate(p.Currency); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPrice, err)
+	}
+
+	if err := validAmount(p.Amount, p.Currency); err != nil {
+		return err
+	}
+
+	if !p.CompareAt.IsZero() {
+		if err := validAmount(p.CompareAt, p.Currency); err != nil {
+			return err
+		}
+
+		if !p.CompareAt.GreaterThan(p.Amount) {
+			return fmt.Errorf("%w: the original price must be greater than the price",
+				ErrInvalidPrice)
+		}
+	}
+
+	if p.EffectiveFrom.IsZero() {
+		return fmt.Errorf("%w: the start of the validity is required", ErrInvalidPrice)
+	}
+
+	if !p.EffectiveTo.IsZero() && !p.EffectiveTo.After(p.EffectiveFrom) {
+		return fmt.Errorf("%w: the end of the validity must be after its start",
+			ErrInvalidPrice)
+	}
+
+	return nil
+}
+
+// SetDefaultCurrency sets the currency of the product to the price without currency.
+func (p *Price) SetDefaultCurrency(productCurrency string) {
+	if p.Currency == "" {
+		p.Currency = productCurrency
+	}
+}
+
+// Active returns true if the price is valid at the given time.
+func (p *Price) Active(now time.Time) bool {
+	return !p.EffectiveFrom.After(now) && (p.EffectiveTo.IsZero() || p.EffectiveTo.After(now))
+}
+
+// OnSale returns true if the price is a sale, with an original price.
+func (p *Price) OnSale() bool {
+	return !p.CompareAt.IsZero()
+}
+
+// Prices of a product and its variants.
+type Prices []Price
+
+// Active returns the price of the SKU valid at the given time, or nil if there isn't
+// any. An empty SKU returns the price of the product. When several prices are valid, the
+// one starting later is used, and on the same start the one created later.
+func (p Prices) Active(sku string, now time.Time) *Price {
+	var res *Price
+	for i := range p {
+		if p[i].SKU != sku || !p[i].Active(now) {
+			continue
+		}
+
+		if res == nil || p[i].EffectiveFrom.After(res.EffectiveFrom) ||
+			(p[i].EffectiveFrom.Equal(res.EffectiveFrom) && p[i].ID > res.ID) {
+			res = &p[i]
+		}
+	}
+
+	return res
+}
+
+// NextChange returns the first time after the given one when a price starts or ends, so
+// the active prices can change. It returns false if the active prices don't change
+// anymore.
+func (p Prices) NextChange(now time.Time) (time.Time, bool) {
+	var next time.Time
+	for i := range p {
+		for _, t := range []time.Time{p[i].EffectiveFrom, p[i].EffectiveTo} {
+			if t.After(now) && (next.IsZero() || t.Before(next)) {
+				next = t
+			}
+		}
+	}
+
+	return next, !next.IsZero()
+}
+
+// validAmount checks the amount is positive, lower than the maximum and it has at most
+// the decimals of the currency.
+func validAmount(amount decimal.Decimal, code string) error {
+	if !amount.IsPositive() || amount.GreaterThanOrEqual(maxAmount) {
+		return fmt.Errorf("%w: invalid amount %s", ErrInvalidPrice, amount)
+	}
+
+	if decimals := currency.Decimals(code); !amount.Equal(amount.Truncate(decimals)) {
+		return fmt.Errorf("%w: the amount in %s can't have more than %d decimals",
+			ErrInvalidPrice, code, decimals)
+	}
+
+	return nil
+}
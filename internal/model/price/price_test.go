@@ -0,0 +1,121 @@
+package price
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		price         Price
+		expectedError bool
+	}{
+		"valid": {
+			price: Price{Amount: decimal.New(5, 0), Currency: "EUR", EffectiveFrom: start},
+		},
+		"sale with end": {
+			price: Price{Amount: decimal.New(5, 0), CompareAt: decimal.New(8, 0), Currency: "EUR",
+				EffectiveFrom: start, EffectiveTo: start.Add(time.Hour)},
+		},
+		"without currency": {
+			price:         Price{Amount: decimal.New(5, 0), EffectiveFrom: start},
+			expectedError: true,
+		},
+		"zero amount": {
+			price:         Price{Currency: "EUR", EffectiveFrom: start},
+			expectedError: true,
+		},
+		"too many decimals": {
+			price:         Price{Amount: decimal.New(1, -1), Currency: "JPY", EffectiveFrom: start},
+			expectedError: true,
+		},
+		"original price lower than the price": {
+			price: Price{Amount: decimal.New(5, 0), CompareAt: decimal.New(4, 0), Currency: "EUR",
+				EffectiveFrom: start},
+			expectedError: true,
+		},
+		"original price with too many decimals": {
+			price: Price{Amount: decimal.New(5, 0), CompareAt: decimal.New(8001, -3),
+				Currency: "EUR", EffectiveFrom: start},
+			expectedError: true,
+		},
+		"without start": {
+			price:         Price{Amount: decimal.New(5, 0), Currency: "EUR"},
+			expectedError: true,
+		},
+		"end before the start": {
+			price: Price{Amount: decimal.New(5, 0), Currency: "EUR", EffectiveFrom: start,
+				EffectiveTo: start},
+			expectedError: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			err := tc.price.Validate()
+			if tc.expectedError {
+				require.ErrorIs(t, err, ErrInvalidPrice)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestActive(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	prices := Prices{
+		{ID: 1, Amount: decimal.New(10, 0), EffectiveFrom: start},
+		{ID: 2, Amount: decimal.New(8, 0), CompareAt: decimal.New(10, 0),
+			EffectiveFrom: start.Add(24 * time.Hour), EffectiveTo: start.Add(48 * time.Hour)},
+		{ID: 3, Amount: decimal.New(12, 0), EffectiveFrom: start.Add(72 * time.Hour)},
+		{ID: 4, Amount: decimal.New(11, 0), EffectiveFrom: start.Add(72 * time.Hour)},
+		{ID: 5, SKU: "SKU001A", Amount: decimal.New(9, 0), EffectiveFrom: start},
+	}
+
+	assert.Nil(t, prices.Active("", start.Add(-time.Second)))
+	assert.Equal(t, uint(1), prices.Active("", start).ID)
+	assert.Equal(t, uint(2), prices.Active("", start.Add(24*time.Hour)).ID)
+	assert.True(t, prices.Active("", start.Add(24*time.Hour)).OnSale())
+	assert.Equal(t, uint(1), prices.Active("", start.Add(48*time.Hour)).ID)
+	assert.Equal(t, uint(4), prices.Active("", start.Add(72*time.Hour)).ID)
+	assert.Equal(t, uint(5), prices.Active("SKU001A", start).ID)
+	assert.Nil(t, prices.Active("SKU001B", start))
+}
+
+func TestNextChange(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	prices := Prices{
+		{ID: 1, EffectiveFrom: start},
+		{ID: 2, EffectiveFrom: start.Add(24 * time.Hour), EffectiveTo: start.Add(48 * time.Hour)},
+	}
+
+	next, ok := prices.NextChange(start)
+	require.True(t, ok)
+	assert.Equal(t, start.Add(24*time.Hour), next)
+
+	next, ok = prices.NextChange(start.Add(24 * time.Hour))
+	require.True(t, ok)
+	assert.Equal(t, start.Add(48*time.Hour), next)
+
+	_, ok = prices.NextChange(start.Add(48 * time.Hour))
+	assert.False(t, ok)
+}
+
+func TestSetDefaultCurrency(t *testing.T) {
+	t.Parallel()
+	p := Price{Amount: decimal.New(5, 0)}
+	p.SetDefaultCurrency("USD")
+	assert.Equal(t, "USD", p.Currency)
+
+	p.SetDefaultCurrency("EUR")
+	assert.Equal(t, "USD", p.Currency)
+}
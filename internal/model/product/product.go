@@ -3,20 +3,240 @@ package product
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/shopspring/decimal"
 
 	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/category"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/currency"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/price"
 	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/variant"
 )
 
-var ErrNotFound = errors.New("product not found")
+var (
+	ErrNotFound = errors.New("product not found")
+	// ErrInvalidProduct is returned when a product is not valid.
+	ErrInvalidProduct = errors.New("product not valid")
+	// ErrDuplicated is returned when a product with the same code already exists.
+	ErrDuplicated = errors.New("product already exists")
+	// ErrVersionMismatch is returned when a product was modified after the version the
+	// change is based on.
+	ErrVersionMismatch = errors.New("product version mismatch")
+)
+
+const (
+	// maxCodeLength is the maximum length of the product codes.
+	maxCodeLength = 32
+	// maxNameLength is the maximum length of the product names.
+	maxNameLength = 256
+	// maxDescriptionLength is the maximum length of the product descriptions.
+	maxDescriptionLength = 4096
+	// maxAttributeKeyLength and maxAttributeValueLength are the maximum lengths of the
+	// keys and the values of the product attributes.
+	maxAttributeKeyLength   = 64
+	maxAttributeValueLength = 256
+	// attributePrefix is the prefix of the filter fields of the product attributes.
+	attributePrefix = "attr."
+	// pricePrefix is the prefix of the filter and sort fields of the prices converted to
+	// a currency.
+	pricePrefix = "price."
+)
+
+// maxPrice is the first price not allowed, as the prices are stored with 12 digits, four
+// of them decimals.
+var maxPrice = decimal.New(1, 8)
 
 // Product represents a product in the catalog.
 type Product struct {
 	Category *category.Category
 	Code     string
-	Price    decimal.Decimal
+	// Name and Description are the human readable name and description of the product.
+	Name        string
+	Description string
+	// Attributes are the structured properties of the product, like its material.
+	Attributes map[string]string
+	Price      decimal.Decimal
+	// Currency is the ISO 4217 code of the currency of the price.
+	Currency string
 	Variants []variant.Variant
-	ID       uint
+	// Prices are the scheduled prices of the product and its variants, replacing the
+	// price above while they are valid.
+	Prices price.Prices
+	// CreatedAt and UpdatedAt are set by the storage on every write. The changes of the
+	// variants update the product too.
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ID        uint
+	// Version is increased on every change of the product, its variants or its scheduled
+	// prices, and when its category changes. If it's set on an update, the product is only
+	// updated if it still has the same version.
+	Version uint
+}
+
+// Validate checks the product can be stored: the code can't be empty, longer than 32
+// characters or contain spaces, the name and the description can't be longer than 256
+// and 4096 characters, the attributes must be valid, the price must be positive with at
+// most the decimals of its currency, it must have a category code and its variants must
+// be valid.
+func (p *Product) Validate() error {
+	if p.Code == "" || len(p.Code) > maxCodeLength || strings.ContainsFunc(p.Code, unicode.IsSpace) {
+		return fmt.Errorf("%w: invalid code %q", ErrInvalidProduct, p.Code)
+	}
+
+	if utf8.RuneCountInString(p.Name) > maxNameLength {
+		return fmt.Errorf("%w: the name can't be longer than %d characters", ErrInvalidProduct,
+			maxNameLength)
+	}
+
+	if utf8.RuneCountInString(p.Description) > maxDescriptionLength {
+		return fmt.Errorf("%w: the description can't be longer than %d characters",
+			ErrInvalidProduct, maxDescriptionLength)
+	}
+
+	for key, value := range p.Attributes {
+		if !ValidAttributeKey(key) {
+			return fmt.Errorf("%w: invalid attribute %q", ErrInvalidProduct, key)
+		}
+
+		if utf8.RuneCountInString(value) > maxAttributeValueLength {
+			return fmt.Errorf("%w: the attribute %s can't be longer than %d characters",
+				ErrInvalidProduct, key, maxAttributeValueLength)
+		}
+	}
+
+	if err := currency.Validate(p.Currency); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidProduct, err)
+	}
+
+	if !p.Price.IsPositive() || p.Price.GreaterThanOrEqual(maxPrice) {
+		return fmt.Errorf("%w: invalid price %s", ErrInvalidProduct, p.Price)
+	}
+
+	if decimals := currency.Decimals(p.Currency); !p.Price.Equal(p.Price.Truncate(decimals)) {
+		return fmt.Errorf("%w: the price in %s can't have more than %d decimals",
+			ErrInvalidProduct, p.Currency, decimals)
+	}
+
+	if p.Category == nil || p.Category.Code == "" {
+		return fmt.Errorf("%w: the category is required", ErrInvalidProduct)
+	}
+
+	for i := range p.Variants {
+		if err := p.Variants[i].Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetDefaultCurrency sets the default currency to the product without currency, and
+// the currency of the product to the prices of its variants without currency.
+func (p *Product) SetDefaultCurrency() {
+	if p.Currency == "" {
+		p.Currency = currency.Default
+	}
+
+	for i := range p.Variants {
+		p.Variants[i].SetDefaultCurrency(p.Currency)
+	}
+}
+
+// ActivePrice returns the price of the product valid at the given time, or its base
+// price if there isn't any scheduled price valid.
+func (p *Product) ActivePrice(now time.Time) price.Price {
+	if active := p.Prices.Active("", now); active != nil {
+		return *active
+	}
+
+	return price.Price{Amount: p.Price, Currency: p.Currency}
+}
+
+// VariantPrice returns the price of the variant valid at the given time: its scheduled
+// price, its base price or otherwise the active price of the product.
+func (p *Product) VariantPrice(v *variant.Variant, now time.Time) price.Price {
+	if active := p.Prices.Active(v.SKU, now); active != nil {
+		return *active
+	}
+
+	if !v.Price.IsZero() {
+		return price.Price{SKU: v.SKU, Amount: v.Price, Currency: v.Currency}
+	}
+
+	res := p.ActivePrice(now)
+	res.SKU = v.SKU
+
+	return res
+}
+
+// ValidAttributeKey returns true if the key can be used as an attribute key. The keys
+// have between 1 and 64 ascii letters, digits, underscores or dashes, so they can be
+// used in the query parameters and the database queries as they are.
+func ValidAttributeKey(key string) bool {
+	if key == "" || len(key) > maxAttributeKeyLength {
+		return false
+	}
+
+	for _, r := range key {
+		if r > unicode.MaxASCII || (!unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' &&
+			r != '-') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// AttributeField returns the filter field of the attribute with the given key.
+func AttributeField(key string) string {
+	return attributePrefix + key
+}
+
+// AttributeKey returns the attribute key of a filter field. It returns false if the
+// field isn't a valid attribute field.
+func AttributeKey(field string) (string, bool) {
+	key, ok := strings.CutPrefix(field, attributePrefix)
+
+	return key, ok && ValidAttributeKey(key)
+}
+
+// PriceField returns the filter and sort field of the price valid now converted to the
+// currency, so the prices in different currencies can be compared.
+func PriceField(code string) string {
+	return pricePrefix + code
+}
+
+// PriceCurrency returns the currency of a converted price field. It returns false if the
+// field isn't a valid converted price field.
+func PriceCurrency(field string) (string, bool) {
+	code, ok := strings.CutPrefix(field, pricePrefix)
+
+	return code, ok && currency.Validate(code) == nil
+}
+
+// SortValue returns the value of a sortable field of the product, used to build the
+// pagination cursors, with the price valid at the given time. It returns false if the
+// field is not sortable.
+func (p *Product) SortValue(field string, now time.Time) (string, bool) {
+	switch field {
+	case "id":
+		return strconv.FormatUint(uint64(p.ID), 10), true
+	case "code":
+		return p.Code, true
+	case "price":
+		return p.ActivePrice(now).Amount.String(), true
+	case "category":
+		if p.Category == nil {
+			return "", false
+		}
+
+		return strconv.FormatUint(p.Category.ID, 10), true
+	}
+
+	return "", false
 }
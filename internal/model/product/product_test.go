@@ -0,0 +1,226 @@
+package product
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/category"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/currency"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/price"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/variant"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		product       Product
+		expectedError bool
+	}{
+		"valid": {
+			product: Product{Code: "PROD001", Price: decimal.RequireFromString("10.99"),
+				Currency: "EUR", Category: &category.Category{Code: "CAT001"}},
+		},
+		"valid in yens": {
+			product: Product{Code: "PROD001", Price: decimal.RequireFromString("1500"),
+				Currency: "JPY", Category: &category.Category{Code: "CAT001"}},
+		},
+		"valid in dinars": {
+			product: Product{Code: "PROD001", Price: decimal.RequireFromString("3.665"),
+				Currency: "KWD", Category: &category.Category{Code: "CAT001"}},
+		},
+		"without currency": {
+			product: Product{Code: "PROD001", Price: decimal.RequireFromString("10.99"),
+				Category: &category.Category{Code: "CAT001"}},
+			expectedError: true,
+		},
+		"invalid currency": {
+			product: Product{Code: "PROD001", Price: decimal.RequireFromString("10.99"),
+				Currency: "eur", Category: &category.Category{Code: "CAT001"}},
+			expectedError: true,
+		},
+		"decimals in yens": {
+			product: Product{Code: "PROD001", Price: decimal.RequireFromString("1500.5"),
+				Currency: "JPY", Category: &category.Category{Code: "CAT001"}},
+			expectedError: true,
+		},
+		"empty code": {
+			product: Product{Price: decimal.RequireFromString("10.99"),
+				Category: &category.Category{Code: "CAT001"}},
+			expectedError: true,
+		},
+		"code with spaces": {
+			product: Product{Code: "PROD 001", Price: decimal.RequireFromString("10.99"),
+				Category: &category.Category{Code: "CAT001"}},
+			expectedError: true,
+		},
+		"long code": {
+			product: Product{Code: "PROD0000000000000000000000000000001",
+				Price: decimal.RequireFromString("10.99"), Category: &category.Category{Code: "CAT001"}},
+			expectedError: true,
+		},
+		"zero price": {
+			product:       Product{Code: "PROD001", Category: &category.Category{Code: "CAT001"}},
+			expectedError: true,
+		},
+		"price too big": {
+			product: Product{Code: "PROD001", Price: decimal.RequireFromString("100000000"),
+				Category: &category.Category{Code: "CAT001"}},
+			expectedError: true,
+		},
+		"price with three decimals": {
+			product: Product{Code: "PROD001", Price: decimal.RequireFromString("10.999"),
+				Currency: "EUR", Category: &category.Category{Code: "CAT001"}},
+			expectedError: true,
+		},
+		"with name, description and attributes": {
+			product: Product{Code: "PROD001", Name: "T-Shirt", Description: "Cotton t-shirt",
+				Attributes: map[string]string{"material": "cotton", "fit_type-2": "slim"},
+				Price:      decimal.RequireFromString("10.99"), Currency: "EUR",
+				Category: &category.Category{Code: "CAT001"}},
+		},
+		"long name": {
+			product: Product{Code: "PROD001", Name: strings.Repeat("a", 257),
+				Price: decimal.RequireFromString("10.99"), Category: &category.Category{Code: "CAT001"}},
+			expectedError: true,
+		},
+		"long description": {
+			product: Product{Code: "PROD001", Description: strings.Repeat("a", 4097),
+				Price: decimal.RequireFromString("10.99"), Category: &category.Category{Code: "CAT001"}},
+			expectedError: true,
+		},
+		"invalid attribute key": {
+			product: Product{Code: "PROD001", Attributes: map[string]string{"the material": "cotton"},
+				Price: decimal.RequireFromString("10.99"), Category: &category.Category{Code: "CAT001"}},
+			expectedError: true,
+		},
+		"long attribute value": {
+			product: Product{Code: "PROD001",
+				Attributes: map[string]string{"material": strings.Repeat("a", 257)},
+				Price:      decimal.RequireFromString("10.99"), Category: &category.Category{Code: "CAT001"}},
+			expectedError: true,
+		},
+		"without category": {
+			product:       Product{Code: "PROD001", Price: decimal.RequireFromString("10.99")},
+			expectedError: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			err := tc.product.Validate()
+			if tc.expectedError {
+				require.ErrorIs(t, err, ErrInvalidProduct)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSetDefaultCurrency(t *testing.T) {
+	t.Parallel()
+	prod := Product{Variants: []variant.Variant{
+		{SKU: "SKU001A", Price: decimal.RequireFromString("11.99")},
+		{SKU: "SKU001B", Price: decimal.RequireFromString("12.99"), Currency: "USD"},
+		{SKU: "SKU001C", Currency: "USD"},
+	}}
+	prod.SetDefaultCurrency()
+	require.Equal(t, currency.Default, prod.Currency)
+	require.Equal(t, currency.Default, prod.Variants[0].Currency)
+	require.Equal(t, "USD", prod.Variants[1].Currency)
+	require.Empty(t, prod.Variants[2].Currency)
+
+	prod = Product{Currency: "JPY", Variants: []variant.Variant{
+		{SKU: "SKU001A", Price: decimal.RequireFromString("1200")},
+	}}
+	prod.SetDefaultCurrency()
+	require.Equal(t, "JPY", prod.Currency)
+	require.Equal(t, "JPY", prod.Variants[0].Currency)
+}
+
+func TestActivePrice(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	prod := Product{
+		Price:    decimal.RequireFromString("10.99"),
+		Currency: "EUR",
+		Variants: []variant.Variant{
+			{SKU: "SKU001A"},
+			{SKU: "SKU001B", Price: decimal.RequireFromString("12.99"), Currency: "EUR"},
+			{SKU: "SKU001C", Price: decimal.RequireFromString("13.99"), Currency: "EUR"},
+		},
+	}
+	require.Equal(t, "10.99", prod.ActivePrice(now).Amount.String())
+	require.Equal(t, "10.99", prod.VariantPrice(&prod.Variants[0], now).Amount.String())
+
+	prod.Prices = price.Prices{
+		{ID: 1, Amount: decimal.RequireFromString("8.99"),
+			CompareAt: decimal.RequireFromString("10.99"), Currency: "EUR",
+			EffectiveFrom: now.Add(-time.Hour), EffectiveTo: now.Add(time.Hour)},
+		{ID: 2, SKU: "SKU001C", Amount: decimal.RequireFromString("11.99"), Currency: "USD",
+			EffectiveFrom: now},
+	}
+	active := prod.ActivePrice(now)
+	require.Equal(t, "8.99", active.Amount.String())
+	require.True(t, active.OnSale())
+
+	inherited := prod.VariantPrice(&prod.Variants[0], now)
+	require.Equal(t, "8.99", inherited.Amount.String())
+	require.Equal(t, "SKU001A", inherited.SKU)
+	require.Equal(t, "12.99", prod.VariantPrice(&prod.Variants[1], now).Amount.String())
+	require.Equal(t, "USD", prod.VariantPrice(&prod.Variants[2], now).Currency)
+	require.Equal(t, "10.99", prod.ActivePrice(now.Add(time.Hour)).Amount.String())
+}
+
+func TestAttributeKey(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		field       string
+		expectedKey string
+		expectedOK  bool
+	}{
+		"attribute":           {field: "attr.material", expectedKey: "material", expectedOK: true},
+		"not an attribute":    {field: "material", expectedKey: "material"},
+		"empty key":           {field: "attr."},
+		"key with a quote":    {field: "attr.mat'erial", expectedKey: "mat'erial"},
+		"key with non ascii":  {field: "attr.matérial", expectedKey: "matérial"},
+		"key with underscore": {field: "attr.fit_type", expectedKey: "fit_type", expectedOK: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			key, ok := AttributeKey(tc.field)
+			require.Equal(t, tc.expectedOK, ok)
+			require.Equal(t, tc.expectedKey, key)
+		})
+	}
+}
+
+func TestPriceCurrency(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		field        string
+		expectedCode string
+		expectedOK   bool
+	}{
+		"converted price":   {field: "price.USD", expectedCode: "USD", expectedOK: true},
+		"not a price":       {field: "USD", expectedCode: "USD"},
+		"base price":        {field: "price", expectedCode: "price"},
+		"invalid currency":  {field: "price.usd", expectedCode: "usd"},
+		"currency with sql": {field: "price.EUR'", expectedCode: "EUR'"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			code, ok := PriceCurrency(tc.field)
+			require.Equal(t, tc.expectedOK, ok)
+			require.Equal(t, tc.expectedCode, code)
+		})
+	}
+
+	require.Equal(t, "price.JPY", PriceField("JPY"))
+}
@@ -0,0 +1,44 @@
+// Package search defines the full text search model.
+package search
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/product"
+)
+
+const (
+	// HighlightStart and HighlightStop enclose the matching words of the snippets.
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+
+	// maxQueryLength is the maximum length of the search queries.
+	maxQueryLength = 256
+)
+
+// ErrInvalidQuery is returned when a search query is not valid.
+var ErrInvalidQuery = errors.New("invalid search query")
+
+// Result is a product matching a search, with the fragment of its name and description
+// highlighting the matching words.
+type Result struct {
+	Snippet string
+	Product product.Product
+}
+
+// ValidateQuery checks the search query isn't blank or longer than 256 characters.
+func ValidateQuery(query string) error {
+	if strings.TrimSpace(query) == "" {
+		return fmt.Errorf("%w: empty query", ErrInvalidQuery)
+	}
+
+	if utf8.RuneCountInString(query) > maxQueryLength {
+		return fmt.Errorf("%w: the query can't be longer than %d characters", ErrInvalidQuery,
+			maxQueryLength)
+	}
+
+	return nil
+}
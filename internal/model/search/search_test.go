@@ -0,0 +1,35 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateQuery(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		query         string
+		expectedError bool
+	}{
+		"word":       {query: "shoes"},
+		"web search": {query: `"running shoes" or sandals -leather`},
+		"longest":    {query: strings.Repeat("á", 256)},
+		"empty":      {query: "", expectedError: true},
+		"blank":      {query: " \t", expectedError: true},
+		"too long":   {query: strings.Repeat("a", 257), expectedError: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			err := ValidateQuery(tc.query)
+			if tc.expectedError {
+				require.ErrorIs(t, err, ErrInvalidQuery)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Package sorting defines the sort model to fetch data sorted by some fields.
+package sorting
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// descendingPrefix is the prefix of the fields sorted in descending order.
+const descendingPrefix = "-"
+
+// ErrInvalidSort is returned when a sort is malformed.
+var ErrInvalidSort = errors.New("invalid sort")
+
+// FieldError is returned when a sort uses a field that is not allowed.
+type FieldError struct {
+	Field string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("sort field %q not allowed", e.Field)
+}
+
+// Sort defines the sort of the results by a field.
+type Sort struct {
+	Field      string
+	Descending bool
+}
+
+// String returns the sort in the format parsed by Parse.
+func (s Sort) String() string {
+	if s.Descending {
+		return descendingPrefix + s.Field
+	}
+
+	return s.Field
+}
+
+// Parse parses a comma separated list of fields, the fields prefixed by - are sorted
+// in descending order (e.g. "price,-code").
+func Parse(value string) ([]Sort, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	fields := strings.Split(value, ",")
+	sorts := make([]Sort, 0, len(fields))
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		s := Sort{Field: strings.TrimSpace(field)}
+		if strings.HasPrefix(s.Field, descendingPrefix) {
+			s.Field = strings.TrimPrefix(s.Field, descendingPrefix)
+			s.Descending = true
+		}
+
+		if s.Field == "" {
+			return nil, fmt.Errorf("%w: empty field in %q", ErrInvalidSort, value)
+		}
+
+		if seen[s.Field] {
+			return nil, fmt.Errorf("%w: duplicated field %q", ErrInvalidSort, s.Field)
+		}
+
+		seen[s.Field] = true
+		sorts = append(sorts, s)
+	}
+
+	return sorts, nil
+}
+
+// Format returns the sorts in the format parsed by Parse.
+func Format(sorts []Sort) string {
+	fields := make([]string, 0, len(sorts))
+	for i := range sorts {
+		fields = append(fields, sorts[i].String())
+	}
+
+	return strings.Join(fields, ",")
+}
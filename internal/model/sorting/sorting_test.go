@@ -0,0 +1,54 @@
+package sorting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		value         string
+		expected      []Sort
+		expectedError bool
+	}{
+		{name: "empty"},
+		{name: "one field", value: "price", expected: []Sort{{Field: "price"}}},
+		{
+			name:     "several fields",
+			value:    "price, -code",
+			expected: []Sort{{Field: "price"}, {Field: "code", Descending: true}},
+		},
+		{name: "empty field", value: "price,,code", expectedError: true},
+		{name: "only prefix", value: "-", expectedError: true},
+		{name: "duplicated field", value: "price,-price", expectedError: true},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			sorts, err := Parse(tc.value)
+			if tc.expectedError {
+				require.ErrorIs(t, err, ErrInvalidSort)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, sorts)
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "price,-code", Format([]Sort{
+		{Field: "price"},
+		{Field: "code", Descending: true},
+	}))
+	assert.Empty(t, Format(nil))
+}
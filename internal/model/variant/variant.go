@@ -3,15 +3,115 @@
 package variant
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
 	"github.com/shopspring/decimal"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/currency"
+)
+
+var (
+	// ErrNotFound is returned when a variant doesn't exist.
+	ErrNotFound = errors.New("variant not found")
+	// ErrInvalidVariant is returned when a variant is not valid.
+	ErrInvalidVariant = errors.New("variant not valid")
+	// ErrDuplicated is returned when a variant with the same SKU already exists.
+	ErrDuplicated = errors.New("variant already exists")
+	// ErrVersionMismatch is returned when a variant was modified after the version the
+	// change is based on.
+	ErrVersionMismatch = errors.New("variant version mismatch")
+)
+
+const (
+	// maxNameLength is the maximum length of the variant names.
+	maxNameLength = 256
+	// maxSKULength is the maximum length of the variant SKUs.
+	maxSKULength = 32
 )
 
 // Variant represents a product variant.
 // Variants can be used to represent different configurations or options for a product.
+// A variant without price (zero) inherits the price of its product.
 type Variant struct {
-	Name      string
-	SKU       string
-	Price     decimal.Decimal
+	Name  string
+	SKU   string
+	Price decimal.Decimal
+	// Currency is the ISO 4217 code of the currency of the price. The variants without
+	// price don't have currency, as they inherit the currency of the product too.
+	Currency string
+	// CreatedAt and UpdatedAt are set by the storage on every write.
+	CreatedAt time.Time
+	UpdatedAt time.Time
 	ID        uint
 	ProductID uint
+	// Version is increased on every update. If it's set on an update, the variant is only
+	// updated if it still has the same version.
+	Version uint
+}
+
+// Validate checks the variant can be stored: the name and the SKU can't be empty or
+// too long, the SKU can't contain spaces, and the price, if any, must be positive with
+// at most the decimals of its currency.
+func (v *Variant) Validate() error {
+	if v.Name == "" || len(v.Name) > maxNameLength {
+		return fmt.Errorf("%w: invalid name %q", ErrInvalidVariant, v.Name)
+	}
+
+	if v.SKU == "" || len(v.SKU) > maxSKULength || strings.ContainsFunc(v.SKU, unicode.IsSpace) {
+		return fmt.Errorf("%w: invalid SKU %q", ErrInvalidVariant, v.SKU)
+	}
+
+	if v.Price.IsNegative() {
+		return fmt.Errorf("%w: invalid price %s", ErrInvalidVariant, v.Price)
+	}
+
+	if v.Price.IsZero() {
+		return nil
+	}
+
+	if err := currency.Validate(v.Currency); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidVariant, err)
+	}
+
+	if decimals := currency.Decimals(v.Currency); !v.Price.Equal(v.Price.Truncate(decimals)) {
+		return fmt.Errorf("%w: the price in %s can't have more than %d decimals",
+			ErrInvalidVariant, v.Currency, decimals)
+	}
+
+	return nil
+}
+
+// SetDefaultCurrency sets the currency of the product to the price of the variant if it
+// doesn't have one, and removes the currency of the variant without price.
+func (v *Variant) SetDefaultCurrency(productCurrency string) {
+	switch {
+	case v.Price.IsZero():
+		v.Currency = ""
+	case v.Currency == "":
+		v.Currency = productCurrency
+	}
+}
+
+// EffectivePrice returns the price of the variant, or the price of the product if the
+// variant doesn't have one.
+func (v *Variant) EffectivePrice(productPrice decimal.Decimal) decimal.Decimal {
+	if v.Price.IsZero() {
+		return productPrice
+	}
+
+	return v.Price
+}
+
+// EffectiveCurrency returns the currency of the price of the variant, or the currency of
+// the product if the variant doesn't have price or currency.
+func (v *Variant) EffectiveCurrency(productCurrency string) string {
+	if v.Price.IsZero() || v.Currency == "" {
+		return productCurrency
+	}
+
+	return v.Currency
 }
@@ -0,0 +1,89 @@
+package variant
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		variant       Variant
+		expectedError bool
+	}{
+		"valid": {variant: Variant{Name: "A", SKU: "SKU001A"}},
+		"with price": {
+			variant: Variant{Name: "A", SKU: "SKU001A", Price: decimal.New(5, 0), Currency: "EUR"},
+		},
+		"with price in yens": {
+			variant: Variant{Name: "A", SKU: "SKU001A", Price: decimal.New(500, 0), Currency: "JPY"},
+		},
+		"empty name":      {variant: Variant{SKU: "SKU001A"}, expectedError: true},
+		"empty sku":       {variant: Variant{Name: "A"}, expectedError: true},
+		"sku with spaces": {variant: Variant{Name: "A", SKU: "SKU 001A"}, expectedError: true},
+		"negative price": {
+			variant:       Variant{Name: "A", SKU: "SKU001A", Price: decimal.New(-1, 0)},
+			expectedError: true,
+		},
+		"too many decimals": {
+			variant: Variant{Name: "A", SKU: "SKU001A", Price: decimal.New(1, -3),
+				Currency: "EUR"},
+			expectedError: true,
+		},
+		"decimals in yens": {
+			variant: Variant{Name: "A", SKU: "SKU001A", Price: decimal.New(1, -1),
+				Currency: "JPY"},
+			expectedError: true,
+		},
+		"price without currency": {
+			variant:       Variant{Name: "A", SKU: "SKU001A", Price: decimal.New(5, 0)},
+			expectedError: true,
+		},
+		"invalid currency": {
+			variant: Variant{Name: "A", SKU: "SKU001A", Price: decimal.New(5, 0),
+				Currency: "EURO"},
+			expectedError: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			err := tc.variant.Validate()
+			if tc.expectedError {
+				require.ErrorIs(t, err, ErrInvalidVariant)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestEffectivePrice(t *testing.T) {
+	t.Parallel()
+	productPrice := decimal.RequireFromString("10.99")
+	v := Variant{Name: "A", SKU: "SKU001A"}
+	assert.Equal(t, productPrice, v.EffectivePrice(productPrice))
+
+	v.Price = decimal.RequireFromString("11.99")
+	assert.Equal(t, v.Price, v.EffectivePrice(productPrice))
+}
+
+func TestSetDefaultCurrency(t *testing.T) {
+	t.Parallel()
+	v := Variant{Name: "A", SKU: "SKU001A", Currency: "USD"}
+	v.SetDefaultCurrency("EUR")
+	assert.Empty(t, v.Currency)
+	assert.Equal(t, "EUR", v.EffectiveCurrency("EUR"))
+
+	v.Price = decimal.RequireFromString("11.99")
+	v.SetDefaultCurrency("EUR")
+	assert.Equal(t, "EUR", v.Currency)
+
+	v.Currency = "USD"
+	v.SetDefaultCurrency("EUR")
+	assert.Equal(t, "USD", v.Currency)
+	assert.Equal(t, "USD", v.EffectiveCurrency("EUR"))
+}
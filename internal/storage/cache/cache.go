@@ -0,0 +1,385 @@
+// Package cache implements a storage decorator caching the reads of the products and
+// the categories by code, and the list of categories.
+package cache
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/category"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/price"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/product"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/variant"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/storage"
+)
+
+const (
+	productPrefix  = "product/"
+	categoryPrefix = "category/"
+	categoriesKey  = "categories"
+)
+
+var (
+	// allProducts invalidates every cached product.
+	allProducts = invalidation{key: productPrefix, prefix: true}
+	// allCategories invalidates every cached category.
+	allCategories = invalidation{key: categoryPrefix, prefix: true}
+)
+
+// Stats of the cache usage.
+type Stats struct {
+	// Hits and Misses are the number of reads found and not found in the cache.
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	// Evictions is the number of entries removed to make room for new ones.
+	Evictions uint64 `json:"evictions"`
+	// Entries is the number of cached entries.
+	Entries int `json:"entries"`
+}
+
+// Cache is a storage caching the reads of the wrapped storage. The entries expire after
+// the configured TTL, and the writes invalidate the entries they modify. The concurrent
+// misses of the same entry are collapsed into a single read of the storage. The other
+// methods go directly to the wrapped storage.
+type Cache struct {
+	storage.Storage
+	state *state
+	// pending has the invalidations of a transaction, applied when it finishes. It's
+	// nil outside the transactions.
+	pending *pending
+}
+
+// state of the cache, shared with its transactions.
+type state struct {
+	entries *lru
+	now     func() time.Time
+	group   singleflight.Group
+	ttl     time.Duration
+	mu      sync.Mutex
+	// generation is increased on every invalidation, so the reads started before it
+	// are not cached.
+	generation uint64
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+// invalidation of the entries with a key, or with a key prefix.
+type invalidation struct {
+	key    string
+	prefix bool
+}
+
+// pending invalidations of a transaction.
+type pending struct {
+	invalidations []invalidation
+	mu            sync.Mutex
+}
+
+// New returns a cache of the storage with the given configuration.
+func New(st storage.Storage, cfg Config) *Cache {
+	return &Cache{
+		Storage: st,
+		state: &state{
+			entries: newLRU(cfg.Size),
+			ttl:     cfg.TTL,
+			now:     time.Now,
+		},
+	}
+}
+
+// Stats returns the usage statistics of the cache.
+func (c *Cache) Stats() Stats {
+	s := c.state
+	s.mu.Lock()
+	entries := s.entries.len()
+	s.mu.Unlock()
+
+	return Stats{
+		Hits:      s.hits.Load(),
+		Misses:    s.misses.Load(),
+		Evictions: s.evictions.Load(),
+		Entries:   entries,
+	}
+}
+
+// GetProduct obtains a product by its code from the cache, or from the storage if it
+// isn't cached.
+func (c *Cache) GetProduct(ctx context.Context, productCode string) (*product.Product, error) {
+	if c.pending != nil {
+		return c.Storage.GetProduct(ctx, productCode)
+	}
+
+	v, err := c.load(ctx, productPrefix+productCode, func(ctx context.Context) (any, error) {
+		return c.Storage.GetProduct(ctx, productCode)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cloneProduct(v.(*product.Product)), nil
+}
+
+// CreateProduct adds a new product to the storage.
+func (c *Cache) CreateProduct(ctx context.Context, prod *product.Product) error {
+	err := c.Storage.CreateProduct(ctx, prod)
+	c.invalidate(invalidation{key: productPrefix + prod.Code})
+
+	return err
+}
+
+// UpdateProduct updates the product with the same code, invalidating its cached entry.
+func (c *Cache) UpdateProduct(ctx context.Context, prod *product.Product) error {
+	err := c.Storage.UpdateProduct(ctx, prod)
+	c.invalidate(invalidation{key: productPrefix + prod.Code})
+
+	return err
+}
+
+// DeleteProduct removes a product, invalidating its cached entry.
+func (c *Cache) DeleteProduct(ctx context.Context, productCode string, version uint) error {
+	err := c.Storage.DeleteProduct(ctx, productCode, version)
+	c.invalidate(invalidation{key: productPrefix + productCode})
+
+	return err
+}
+
+// CreateVariant adds a new variant to the product, invalidating its cached entry.
+func (c *Cache) CreateVariant(ctx context.Context, productCode string, v *variant.Variant) error {
+	err := c.Storage.CreateVariant(ctx, productCode, v)
+	c.invalidate(invalidation{key: productPrefix + productCode})
+
+	return err
+}
+
+// UpdateVariant updates the variant of the product, invalidating its cached entry.
+func (c *Cache) UpdateVariant(ctx context.Context, productCode string, v *variant.Variant) error {
+	err := c.Storage.UpdateVariant(ctx, productCode, v)
+	c.invalidate(invalidation{key: productPrefix + productCode})
+
+	return err
+}
+
+// DeleteVariant removes the variant of the product, invalidating its cached entry.
+func (c *Cache) DeleteVariant(ctx context.Context, productCode, sku string, version uint) error {
+	err := c.Storage.DeleteVariant(ctx, productCode, sku, version)
+	c.invalidate(invalidation{key: productPrefix + productCode})
+
+	return err
+}
+
+// CreatePrice adds a scheduled price to the product, invalidating its cached entry.
+func (c *Cache) CreatePrice(ctx context.Context, productCode string, p *price.Price) error {
+	err := c.Storage.CreatePrice(ctx, productCode, p)
+	c.invalidate(invalidation{key: productPrefix + productCode})
+
+	return err
+}
+
+// DeletePrice removes the scheduled price of the product, invalidating its cached entry.
+func (c *Cache) DeletePrice(ctx context.Context, productCode string, id uint) error {
+	err := c.Storage.DeletePrice(ctx, productCode, id)
+	c.invalidate(invalidation{key: productPrefix + productCode})
+
+	return err
+}
+
+// GetAllCategories gets the list of categories from the cache, or from the storage if
+// it isn't cached.
+func (c *Cache) GetAllCategories(ctx context.Context) (category.Categories, error) {
+	if c.pending != nil {
+		return c.Storage.GetAllCategories(ctx)
+	}
+
+	v, err := c.load(ctx, categoriesKey, func(ctx context.Context) (any, error) {
+		return c.Storage.GetAllCategories(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.Clone(v.(category.Categories)), nil
+}
+
+// AddCategory adds a new category to the storage, invalidating the list of categories.
+func (c *Cache) AddCategory(ctx context.Context, cat *category.Category) error {
+	err := c.Storage.AddCategory(ctx, cat)
+	c.invalidate(invalidation{key: categoriesKey}, invalidation{key: categoryPrefix + cat.Code})
+
+	return err
+}
+
+// GetCategory obtains a category by its code from the cache, or from the storage if it
+// isn't cached.
+func (c *Cache) GetCategory(ctx context.Context, code string) (*category.Category, error) {
+	if c.pending != nil {
+		return c.Storage.GetCategory(ctx, code)
+	}
+
+	v, err := c.load(ctx, categoryPrefix+code, func(ctx context.Context) (any, error) {
+		return c.Storage.GetCategory(ctx, code)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cat := *v.(*category.Category)
+	cat.Ancestors = slices.Clone(cat.Ancestors)
+
+	return &cat, nil
+}
+
+// UpdateCategory updates the name and the parent of the category. The products embed
+// their category and its ancestors, and the categories embed their ancestors, so all of
+// them are invalidated with the list of categories.
+func (c *Cache) UpdateCategory(ctx context.Context, cat *category.Category) error {
+	err := c.Storage.UpdateCategory(ctx, cat)
+	c.invalidate(invalidation{key: categoriesKey}, allCategories, allProducts)
+
+	return err
+}
+
+// DeleteCategory removes a category, moving its products to the reassignTo category.
+// All the products are invalidated with the category and the list of categories.
+func (c *Cache) DeleteCategory(ctx context.Context, code, reassignTo string,
+	version uint,
+) error {
+	err := c.Storage.DeleteCategory(ctx, code, reassignTo, version)
+	c.invalidate(invalidation{key: categoriesKey}, invalidation{key: categoryPrefix + code},
+		allProducts)
+
+	return err
+}
+
+// WithTx runs fn in a transaction of the wrapped storage. The reads inside the
+// transaction are not cached, as they can see uncommitted data, and the entries
+// modified by the transaction are invalidated when it finishes.
+func (c *Cache) WithTx(ctx context.Context, fn func(tx storage.Storage) error) error {
+	p := c.pending
+	if p == nil {
+		// Nested transactions are invalidated with the outermost one.
+		p = &pending{}
+		defer func() {
+			c.state.invalidate(p.invalidations...)
+		}()
+	}
+
+	return c.Storage.WithTx(ctx, func(tx storage.Storage) error {
+		return fn(&Cache{Storage: tx, state: c.state, pending: p})
+	})
+}
+
+// invalidate removes the cached entries, or records them to be removed when the
+// transaction finishes.
+func (c *Cache) invalidate(invalidations ...invalidation) {
+	if c.pending == nil {
+		c.state.invalidate(invalidations...)
+
+		return
+	}
+
+	c.pending.mu.Lock()
+	c.pending.invalidations = append(c.pending.invalidations, invalidations...)
+	c.pending.mu.Unlock()
+}
+
+// load returns the cached value of the key, or reads it with fetch and caches it. The
+// reads of the same key are shared by the concurrent callers, and they go on even if
+// the caller starting them is cancelled, as other callers can be waiting for them.
+func (c *Cache) load(ctx context.Context, key string,
+	fetch func(ctx context.Context) (any, error),
+) (any, error) {
+	s := c.state
+	v, generation, ok := s.get(key)
+	if ok {
+		s.hits.Add(1)
+
+		return v, nil
+	}
+
+	// The callers arriving after an invalidation don't join the reads started before
+	// it, so they read the changes.
+	flight := key + "@" + strconv.FormatUint(generation, 10)
+	ch := s.group.DoChan(flight, func() (any, error) {
+		v, err := fetch(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+
+		s.add(key, v, generation)
+
+		return v, nil
+	})
+	s.misses.Add(1)
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		return res.Val, res.Err
+	}
+}
+
+// get returns the cached value of the key, and the current generation.
+func (s *state) get(key string) (any, uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.entries.get(key, s.now())
+
+	return v, s.generation, ok
+}
+
+// add caches the value of the key, unless an invalidation happened after the given
+// generation, as the value could be stale.
+func (s *state) add(key string, v any, generation uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.generation != generation {
+		return
+	}
+
+	if s.entries.add(key, v, s.now().Add(s.ttl)) {
+		s.evictions.Add(1)
+	}
+}
+
+// invalidate removes the cached entries.
+func (s *state) invalidate(invalidations ...invalidation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generation++
+	for _, inv := range invalidations {
+		if inv.prefix {
+			s.entries.removePrefix(inv.key)
+		} else {
+			s.entries.remove(inv.key)
+		}
+	}
+}
+
+// cloneProduct returns a copy of the product not sharing its category, with its
+// ancestors, attributes and variants.
+func cloneProduct(prod *product.Product) *product.Product {
+	p := *prod
+	p.Attributes = maps.Clone(prod.Attributes)
+	p.Variants = slices.Clone(prod.Variants)
+	p.Prices = slices.Clone(prod.Prices)
+	if prod.Category != nil {
+		cat := *prod.Category
+		cat.Ancestors = slices.Clone(prod.Category.Ancestors)
+		p.Category = &cat
+	}
+
+	return &p
+}
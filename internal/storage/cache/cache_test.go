@@ -0,0 +1,311 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/category"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/product"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/variant"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/storage"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/storage/memory"
+)
+
+var _ storage.Storage = (*Cache)(nil)
+
+// countingStorage counts the product reads of the storage. If block is set, the reads
+// wait until it's closed.
+type countingStorage struct {
+	storage.Storage
+	block chan struct{}
+	reads atomic.Int32
+}
+
+func (s *countingStorage) GetProduct(ctx context.Context, code string) (*product.Product,
+	error,
+) {
+	s.reads.Add(1)
+	if s.block != nil {
+		<-s.block
+	}
+
+	return s.Storage.GetProduct(ctx, code)
+}
+
+func newTestCache(t *testing.T, cfg Config) (*Cache, *countingStorage) {
+	t.Helper()
+	m, err := memory.NewWithSampleData(context.Background())
+	require.NoError(t, err)
+
+	st := &countingStorage{Storage: m}
+
+	return New(st, cfg), st
+}
+
+func TestGetProduct(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c, st := newTestCache(t, DefaultConfig())
+
+	prod, err := c.GetProduct(ctx, "PROD001")
+	require.NoError(t, err)
+	// The returned products are copies, so changing them doesn't change the cache.
+	prod.Price = decimal.NewFromInt(1)
+	prod.Category.Name = "changed"
+	prod.Variants[0].Name = "changed"
+
+	prod, err = c.GetProduct(ctx, "PROD001")
+	require.NoError(t, err)
+	assert.Equal(t, "10.99", prod.Price.String())
+	assert.Equal(t, "Clothing", prod.Category.Name)
+	assert.Equal(t, "Variant A", prod.Variants[0].Name)
+
+	// The errors are not cached.
+	_, err = c.GetProduct(ctx, "UNKNOWN")
+	require.ErrorIs(t, err, product.ErrNotFound)
+	_, err = c.GetProduct(ctx, "UNKNOWN")
+	require.ErrorIs(t, err, product.ErrNotFound)
+
+	assert.Equal(t, int32(3), st.reads.Load())
+	assert.Equal(t, Stats{Hits: 1, Misses: 3, Entries: 1}, c.Stats())
+}
+
+func TestExpiration(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c, st := newTestCache(t, Config{Size: 10, TTL: time.Minute})
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.state.now = func() time.Time { return now }
+
+	_, err := c.GetProduct(ctx, "PROD001")
+	require.NoError(t, err)
+
+	now = now.Add(time.Minute - time.Second)
+	_, err = c.GetProduct(ctx, "PROD001")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), st.reads.Load())
+
+	now = now.Add(time.Second)
+	_, err = c.GetProduct(ctx, "PROD001")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), st.reads.Load())
+}
+
+func TestEviction(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c, st := newTestCache(t, Config{Size: 2, TTL: time.Minute})
+
+	for _, code := range []string{"PROD001", "PROD002", "PROD001", "PROD003", "PROD001"} {
+		_, err := c.GetProduct(ctx, code)
+		require.NoError(t, err)
+	}
+
+	// PROD002 is the least recently used product when PROD003 is added.
+	assert.Equal(t, int32(3), st.reads.Load())
+	assert.Equal(t, Stats{Hits: 2, Misses: 3, Evictions: 1, Entries: 2}, c.Stats())
+
+	_, err := c.GetProduct(ctx, "PROD002")
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), st.reads.Load())
+}
+
+// nolint: funlen
+func TestInvalidation(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		write func(ctx context.Context, st storage.Storage) error
+		check func(t *testing.T, prod *product.Product, cats category.Categories)
+		name  string
+	}{
+		{
+			name: "update product",
+			write: func(ctx context.Context, st storage.Storage) error {
+				return st.UpdateProduct(ctx, &product.Product{
+					Code:     "PROD001",
+					Price:    decimal.RequireFromString("9.99"),
+					Category: &category.Category{Code: "CAT002"},
+				})
+			},
+			check: func(t *testing.T, prod *product.Product, _ category.Categories) {
+				t.Helper()
+				assert.Equal(t, "9.99", prod.Price.String())
+				assert.Equal(t, "CAT002", prod.Category.Code)
+			},
+		},
+		{
+			name: "create variant",
+			write: func(ctx context.Context, st storage.Storage) error {
+				return st.CreateVariant(ctx, "PROD001", &variant.Variant{Name: "D", SKU: "SKU001D"})
+			},
+			check: func(t *testing.T, prod *product.Product, _ category.Categories) {
+				t.Helper()
+				assert.Len(t, prod.Variants, 4)
+			},
+		},
+		{
+			name: "delete variant",
+			write: func(ctx context.Context, st storage.Storage) error {
+				return st.DeleteVariant(ctx, "PROD001", "SKU001A", 0)
+			},
+			check: func(t *testing.T, prod *product.Product, _ category.Categories) {
+				t.Helper()
+				assert.Len(t, prod.Variants, 2)
+			},
+		},
+		{
+			name: "add category",
+			write: func(ctx context.Context, st storage.Storage) error {
+				return st.AddCategory(ctx, &category.Category{Code: "CAT004", Name: "Hats"})
+			},
+			check: func(t *testing.T, _ *product.Product, cats category.Categories) {
+				t.Helper()
+				assert.Len(t, cats, 4)
+			},
+		},
+		{
+			name: "update category",
+			write: func(ctx context.Context, st storage.Storage) error {
+				return st.UpdateCategory(ctx, &category.Category{Code: "CAT001", Name: "Clothes"})
+			},
+			check: func(t *testing.T, prod *product.Product, cats category.Categories) {
+				t.Helper()
+				assert.Equal(t, "Clothes", prod.Category.Name)
+				assert.Equal(t, "Clothes", cats[0].Name)
+			},
+		},
+		{
+			name: "delete category",
+			write: func(ctx context.Context, st storage.Storage) error {
+				return st.DeleteCategory(ctx, "CAT001", "CAT003", 0)
+			},
+			check: func(t *testing.T, prod *product.Product, cats category.Categories) {
+				t.Helper()
+				assert.Equal(t, "CAT003", prod.Category.Code)
+				assert.Len(t, cats, 2)
+			},
+		},
+		{
+			name: "transaction",
+			write: func(ctx context.Context, st storage.Storage) error {
+				return st.WithTx(ctx, func(tx storage.Storage) error {
+					return tx.WithTx(ctx, func(tx storage.Storage) error {
+						return tx.UpdateCategory(ctx, &category.Category{Code: "CAT001", Name: "Clothes"})
+					})
+				})
+			},
+			check: func(t *testing.T, prod *product.Product, cats category.Categories) {
+				t.Helper()
+				assert.Equal(t, "Clothes", prod.Category.Name)
+				assert.Equal(t, "Clothes", cats[0].Name)
+			},
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			c, _ := newTestCache(t, DefaultConfig())
+
+			// Cache the entries before the write.
+			_, err := c.GetProduct(ctx, "PROD001")
+			require.NoError(t, err)
+			_, err = c.GetAllCategories(ctx)
+			require.NoError(t, err)
+
+			require.NoError(t, tc.write(ctx, c))
+
+			prod, err := c.GetProduct(ctx, "PROD001")
+			require.NoError(t, err)
+			cats, err := c.GetAllCategories(ctx)
+			require.NoError(t, err)
+			tc.check(t, prod, cats)
+		})
+	}
+}
+
+func TestTransactionReads(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c, st := newTestCache(t, DefaultConfig())
+	errRollback := errors.New("rollback")
+
+	err := c.WithTx(ctx, func(tx storage.Storage) error {
+		err := tx.UpdateProduct(ctx, &product.Product{
+			Code:     "PROD001",
+			Price:    decimal.RequireFromString("9.99"),
+			Category: &category.Category{Code: "CAT001"},
+		})
+		require.NoError(t, err)
+
+		// The uncommitted changes are read, but not cached.
+		prod, err := tx.GetProduct(ctx, "PROD001")
+		require.NoError(t, err)
+		assert.Equal(t, "9.99", prod.Price.String())
+
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+
+	prod, err := c.GetProduct(ctx, "PROD001")
+	require.NoError(t, err)
+	assert.Equal(t, "10.99", prod.Price.String())
+	assert.Equal(t, int32(1), st.reads.Load())
+	assert.Equal(t, Stats{Misses: 1, Entries: 1}, c.Stats())
+}
+
+func TestConcurrentMisses(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c, st := newTestCache(t, DefaultConfig())
+	st.block = make(chan struct{})
+
+	const readers = 10
+	var wg sync.WaitGroup
+	for range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			prod, err := c.GetProduct(ctx, "PROD001")
+			assert.NoError(t, err)
+			assert.Equal(t, "PROD001", prod.Code)
+		}()
+	}
+
+	// Wait until all the readers missed the cache before unblocking the shared read.
+	require.Eventually(t, func() bool {
+		return c.Stats().Misses == readers
+	}, time.Second, time.Millisecond)
+	close(st.block)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), st.reads.Load())
+}
+
+func TestCancelledRead(t *testing.T) {
+	t.Parallel()
+	c, st := newTestCache(t, DefaultConfig())
+	st.block = make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := c.GetProduct(ctx, "PROD001")
+	require.ErrorIs(t, err, context.Canceled)
+
+	// The read goes on for the other callers, and it's cached when it finishes.
+	close(st.block)
+	prod, err := c.GetProduct(context.Background(), "PROD001")
+	require.NoError(t, err)
+	assert.Equal(t, "PROD001", prod.Code)
+	assert.Equal(t, int32(1), st.reads.Load())
+}
@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultSize = 1000
+	defaultTTL  = time.Minute
+)
+
+// ErrInvalidConfig is returned when the cache configuration is not valid.
+var ErrInvalidConfig = errors.New("invalid cache configuration")
+
+// Config of the storage cache.
+type Config struct {
+	// Size is the maximum number of cached entries. The least recently used entry is
+	// evicted when the cache is full (0 disables the cache).
+	Size int
+	// TTL is the time an entry is cached, bounding how stale a read can be when the
+	// storage is modified by another instance.
+	TTL time.Duration
+}
+
+// DefaultConfig returns a configuration with the default values.
+func DefaultConfig() Config {
+	return Config{
+		Size: defaultSize,
+		TTL:  defaultTTL,
+	}
+}
+
+// ConfigFromEnv returns the cache configuration defined in the environment. The values
+// not defined in the environment get the default value.
+func ConfigFromEnv() (Config, error) {
+	cfg := DefaultConfig()
+	err := errors.Join(
+		setInt(&cfg.Size, "STORAGE_CACHE_SIZE"),
+		setDuration(&cfg.TTL, "STORAGE_CACHE_TTL"),
+	)
+	if err != nil {
+		return Config{}, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// Enabled returns true if the configuration caches any entry.
+func (c *Config) Enabled() bool {
+	return c.Size > 0
+}
+
+// Validate checks the configuration is valid.
+func (c *Config) Validate() error {
+	errs := make([]error, 0)
+	if c.Size < 0 {
+		errs = append(errs, errors.New("the size can't be negative"))
+	}
+
+	if c.Enabled() && c.TTL <= 0 {
+		errs = append(errs, errors.New("the ttl must be positive"))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidConfig, errors.Join(errs...))
+	}
+
+	return nil
+}
+
+func setInt(value *int, name string) error {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+
+	*value = i
+
+	return nil
+}
+
+func setDuration(value *time.Duration, name string) error {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+
+	*value = d
+
+	return nil
+}
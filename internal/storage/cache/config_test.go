@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigValidate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		update        func(cfg *Config)
+		name          string
+		expectedError bool
+	}{
+		{
+			name:   "default config",
+			update: func(_ *Config) {},
+		},
+		{
+			name:   "disabled cache",
+			update: func(cfg *Config) { cfg.Size = 0; cfg.TTL = 0 },
+		},
+		{
+			name:          "negative size",
+			update:        func(cfg *Config) { cfg.Size = -1 },
+			expectedError: true,
+		},
+		{
+			name:          "zero ttl",
+			update:        func(cfg *Config) { cfg.TTL = 0 },
+			expectedError: true,
+		},
+		{
+			name:          "negative ttl",
+			update:        func(cfg *Config) { cfg.TTL = -time.Second },
+			expectedError: true,
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := DefaultConfig()
+			tc.update(&cfg)
+			err := cfg.Validate()
+			if tc.expectedError {
+				require.ErrorIs(t, err, ErrInvalidConfig)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"container/list"
+	"strings"
+	"time"
+)
+
+// entry of the lru list.
+type entry struct {
+	expires time.Time
+	value   any
+	key     string
+}
+
+// lru is a size bounded list of entries, evicting the least recently used one when
+// it's full. The entries expire after their expiration time. It isn't safe for
+// concurrent use.
+type lru struct {
+	items map[string]*list.Element
+	order *list.List
+	size  int
+}
+
+func newLRU(size int) *lru {
+	return &lru{
+		items: make(map[string]*list.Element, size),
+		order: list.New(),
+		size:  size,
+	}
+}
+
+// get returns the value of the key if it's cached and not expired at now.
+func (l *lru) get(key string, now time.Time) (any, bool) {
+	elem, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := elem.Value.(*entry)
+	if !now.Before(e.expires) {
+		l.removeElement(elem)
+
+		return nil, false
+	}
+
+	l.order.MoveToFront(elem)
+
+	return e.value, true
+}
+
+// add caches the value of the key until expires. It returns true if an entry was
+// evicted to make room for it.
+func (l *lru) add(key string, value any, expires time.Time) bool {
+	if elem, ok := l.items[key]; ok {
+		e := elem.Value.(*entry)
+		e.value = value
+		e.expires = expires
+		l.order.MoveToFront(elem)
+
+		return false
+	}
+
+	l.items[key] = l.order.PushFront(&entry{key: key, value: value, expires: expires})
+	if l.order.Len() <= l.size {
+		return false
+	}
+
+	l.removeElement(l.order.Back())
+
+	return true
+}
+
+// remove removes the entry of the key.
+func (l *lru) remove(key string) {
+	if elem, ok := l.items[key]; ok {
+		l.removeElement(elem)
+	}
+}
+
+// removePrefix removes the entries with the keys starting with prefix.
+func (l *lru) removePrefix(prefix string) {
+	for key, elem := range l.items {
+		if strings.HasPrefix(key, prefix) {
+			l.removeElement(elem)
+		}
+	}
+}
+
+// len returns the number of entries, including the expired ones not removed yet.
+func (l *lru) len() int {
+	return l.order.Len()
+}
+
+func (l *lru) removeElement(elem *list.Element) {
+	l.order.Remove(elem)
+	delete(l.items, elem.Value.(*entry).key)
+}
@@ -1,22 +1,79 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
 	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/category"
 )
 
-func (db *Database) GetAllCategories() (category.Categories, error) {
+// categoryTreeLock is the key of the advisory lock serializing the moves of the
+// categories, so two concurrent moves can't create a cycle.
+const categoryTreeLock = 7_140_022
+
+// descendantsQuery selects the ids of the categories and all their descendants.
+const descendantsQuery = `WITH RECURSIVE descendants AS (
+	SELECT id FROM category WHERE id IN ?
+	UNION
+	SELECT c.id FROM category c JOIN descendants d ON c.parent_id = d.id
+	WHERE c.deleted_at IS NULL
+) SELECT id FROM descendants`
+
+// ancestorsQuery selects the ancestors of the categories, from the root to their parent.
+const ancestorsQuery = `WITH RECURSIVE ancestors AS (
+	SELECT id AS category_id, parent_id AS ancestor_id, 1 AS depth
+	FROM category WHERE id IN ?
+	UNION ALL
+	SELECT a.category_id, c.parent_id, a.depth + 1
+	FROM ancestors a JOIN category c ON c.id = a.ancestor_id
+) SELECT a.category_id, c.id, c.code, c.name
+FROM ancestors a JOIN category c ON c.id = a.ancestor_id
+ORDER BY a.category_id, a.depth DESC`
+
+// cycleQuery checks if a category is the parent category or one of its ancestors.
+const cycleQuery = `WITH RECURSIVE ancestors AS (
+	SELECT id, parent_id FROM category WHERE id = ?
+	UNION
+	SELECT c.id, c.parent_id FROM category c JOIN ancestors a ON c.id = a.parent_id
+) SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = ?)`
+
+func (db *Database) GetAllCategories(ctx context.Context) (category.Categories, error) {
 	categories := make(Categories, 0)
-	res := db.session.Find(&categories)
-	if res.Error != nil {
-		return nil, fmt.Errorf("unable to fetch the categories: %w", res.Error)
+	err := db.read(ctx, func(tx *gorm.DB) error {
+		categories = categories[:0]
+		if res := tx.Find(&categories); res.Error != nil {
+			return fmt.Errorf("unable to fetch the categories: %w", res.Error)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The parents can't be removed while they have subcategories, so all of them are in
+	// the list.
+	codes := make(map[uint64]string, len(categories))
+	for i := range categories {
+		codes[categories[i].ID] = categories[i].Code
+	}
+
+	for i := range categories {
+		if parent := categories[i].ParentID; parent != nil {
+			categories[i].ParentCode = codes[*parent]
+		}
 	}
 
 	return categories.toModel(), nil
 }
 
-func (db *Database) AddCategory(cat *category.Category) error {
+// AddCategory adds a new category to the database, under the parent category if it has
+// one. The category is updated with the stored data.
+func (db *Database) AddCategory(ctx context.Context, cat *category.Category) error {
 	if cat.Name == "" {
 		return category.ErrInvalidCategory
 	}
@@ -26,12 +83,314 @@ func (db *Database) AddCategory(cat *category.Category) error {
 	}
 
 	c := Category{
-		Name: cat.Name,
-		Code: cat.Code,
+		Name:       cat.Name,
+		Code:       cat.Code,
+		ParentCode: cat.ParentCode,
+	}
+	err := db.transaction(ctx, func(tx *gorm.DB) error {
+		if cat.ParentCode != "" {
+			parent, err := parentCategory(tx, cat.ParentCode)
+			if err != nil {
+				return err
+			}
+
+			c.ParentID = &parent.ID
+		}
+
+		if res := tx.Create(&c); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrDuplicatedKey) {
+				return fmt.Errorf("unable to create the category %s: %w", c.Code,
+					category.ErrDuplicated)
+			}
+
+			return fmt.Errorf("unable to create the category: %w", res.Error)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	*cat = *c.toModel()
+
+	return nil
+}
+
+// parentCategory returns the parent category with the given code, or an invalid category
+// error if it doesn't exist. The parent is locked until the end of the transaction, so
+// it can't be removed while a subcategory is added to it.
+func parentCategory(tx *gorm.DB, code string) (*Category, error) {
+	var parent Category
+	res := tx.Clauses(clause.Locking{Strength: "SHARE"}).Find(&parent,
+		map[string]any{"code": code})
+	if res.Error != nil {
+		return nil, fmt.Errorf("unable to fetch the category %s: %w", code, res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w: unknown parent category %s", category.ErrInvalidCategory,
+			code)
+	}
+
+	return &parent, nil
+}
+
+// loadAncestors sets the ancestors of the categories, and the code of their parent.
+func loadAncestors(tx *gorm.DB, categories ...*Category) error {
+	ids := make([]uint64, 0, len(categories))
+	for _, cat := range categories {
+		if cat.ParentID != nil {
+			ids = append(ids, cat.ID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var rows []struct {
+		Code       string
+		Name       string
+		CategoryID uint64
+		ID         uint64
+	}
+	if res := tx.Raw(ancestorsQuery, ids).Scan(&rows); res.Error != nil {
+		return fmt.Errorf("unable to fetch the ancestors of the categories: %w", res.Error)
+	}
+
+	ancestors := make(map[uint64]Categories, len(ids))
+	for _, row := range rows {
+		ancestors[row.CategoryID] = append(ancestors[row.CategoryID],
+			Category{ID: row.ID, Code: row.Code, Name: row.Name})
+	}
+
+	for _, cat := range categories {
+		cat.Ancestors = ancestors[cat.ID]
+		if len(cat.Ancestors) > 0 {
+			cat.ParentCode = cat.Ancestors[len(cat.Ancestors)-1].Code
+		}
+	}
+
+	return nil
+}
+
+// categoryByCode returns the category with the given code, or an invalid category error
+// if it doesn't exist.
+func categoryByCode(tx *gorm.DB, code string) (*Category, error) {
+	var cat Category
+	res := tx.Find(&cat, map[string]any{"code": code})
+	if res.Error != nil {
+		return nil, fmt.Errorf("unable to fetch the category %s: %w", code, res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w: unknown category %s", category.ErrInvalidCategory, code)
+	}
+
+	return &cat, nil
+}
+
+// GetCategory returns a category stored in the database by its code.
+func (db *Database) GetCategory(ctx context.Context, code string) (*category.Category, error) {
+	var cat Category
+	err := db.read(ctx, func(tx *gorm.DB) error {
+		res := tx.Find(&cat, map[string]any{"code": code})
+		if res.Error != nil {
+			return fmt.Errorf("unable to fetch the category %s: %w", code, res.Error)
+		}
+
+		if res.RowsAffected == 0 {
+			return category.ErrNotFound
+		}
+
+		return loadAncestors(tx, &cat)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cat.toModel(), nil
+}
+
+// UpdateCategory updates the name and the parent of the category with the same code. If
+// the category has a version, it's only updated if the stored one has the same version.
+// It fails with a cycle error if the parent is the category or one of its descendants.
+// The products of the category and its descendants are changed too, as they include its
+// name in their breadcrumbs.
+func (db *Database) UpdateCategory(ctx context.Context, cat *category.Category) error {
+	if cat.Name == "" || cat.Code == "" {
+		return category.ErrInvalidCategory
+	}
+
+	if cat.ParentCode == cat.Code {
+		return fmt.Errorf("%w: %s can't be its own parent", category.ErrCycle, cat.Code)
 	}
-	res := db.session.Create(&c)
+
+	var updated Category
+	err := db.transaction(ctx, func(tx *gorm.DB) error {
+		var parent *Category
+		if cat.ParentCode != "" {
+			res := tx.Exec("SELECT pg_advisory_xact_lock(?)", categoryTreeLock)
+			if res.Error != nil {
+				return fmt.Errorf("unable to lock the categories: %w", res.Error)
+			}
+
+			var err error
+			if parent, err = parentCategory(tx, cat.ParentCode); err != nil {
+				return err
+			}
+		}
+
+		values := map[string]any{"name": cat.Name, "parent_id": nil, "version": nextVersion}
+		if parent != nil {
+			values["parent_id"] = parent.ID
+		}
+
+		query := tx.Model(&updated).Clauses(clause.Returning{}).Where("code = ?", cat.Code)
+		res := whereVersion(query, cat.Version).Updates(values)
+		if res.Error != nil {
+			return fmt.Errorf("unable to update the category %s: %w", cat.Code, res.Error)
+		}
+
+		if res.RowsAffected == 0 {
+			return missingOrStale(tx, &Category{}, map[string]any{"code": cat.Code},
+				category.ErrNotFound, category.ErrVersionMismatch)
+		}
+
+		if parent != nil {
+			// The update is rolled back if the category is now one of its own ancestors.
+			var cycle bool
+			if res := tx.Raw(cycleQuery, parent.ID, updated.ID).Scan(&cycle); res.Error != nil {
+				return fmt.Errorf("unable to check the parent of the category %s: %w",
+					cat.Code, res.Error)
+			}
+
+			if cycle {
+				return fmt.Errorf("%w: %s can't be moved under %s", category.ErrCycle,
+					cat.Code, cat.ParentCode)
+			}
+		}
+
+		return touchTreeProducts(tx, updated.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	updated.ParentCode = cat.ParentCode
+	*cat = *updated.toModel()
+
+	return nil
+}
+
+// DeleteCategory removes a category from the database. If some products belong to it
+// they are moved to the reassignTo category in the same transaction, and it fails with
+// an in use error if reassignTo is empty or the category has subcategories. The category
+// is kept with its removal time (soft delete), so the code can be reused. If the version
+// isn't zero, the category is only removed if it has the same version.
+func (db *Database) DeleteCategory(ctx context.Context, code, reassignTo string,
+	version uint,
+) error {
+	if code == reassignTo {
+		return fmt.Errorf("%w: the category can't be reassigned to itself",
+			category.ErrInvalidCategory)
+	}
+
+	return db.transaction(ctx, func(tx *gorm.DB) error {
+		// The category is locked, so it can't be updated, removed or get subcategories
+		// while it's removed.
+		var cat Category
+		res := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Find(&cat,
+			map[string]any{"code": code})
+		if res.Error != nil {
+			return fmt.Errorf("unable to fetch the category %s: %w", code, res.Error)
+		}
+
+		if res.RowsAffected == 0 {
+			return category.ErrNotFound
+		}
+
+		// The category is locked, so its version can't change after being checked.
+		if version != 0 && cat.Version != version {
+			return category.ErrVersionMismatch
+		}
+
+		// The subcategories lock their parent when they are added or moved, so none can
+		// be added after the check.
+		var children int64
+		res = tx.Model(&Category{}).Where("parent_id = ?", cat.ID).Count(&children)
+		if res.Error != nil {
+			return fmt.Errorf("unable to count the subcategories of the category %s: %w",
+				code, res.Error)
+		}
+
+		if children > 0 {
+			return fmt.Errorf("unable to delete the category %s: %w: it has subcategories",
+				code, category.ErrInUse)
+		}
+
+		if reassignTo != "" {
+			// The target is locked like the parent categories, so it can't be removed
+			// before the products are moved to it.
+			target, err := categoryByCode(tx.Clauses(clause.Locking{Strength: "SHARE"}),
+				reassignTo)
+			if err != nil {
+				return err
+			}
+
+			if err := touchProducts(tx, cat.ID, &target.ID); err != nil {
+				return err
+			}
+		} else {
+			var products int64
+			res = tx.Model(&Product{}).Where("category = ?", cat.ID).Count(&products)
+			if res.Error != nil {
+				return fmt.Errorf("unable to count the products of the category %s: %w",
+					code, res.Error)
+			}
+
+			if products > 0 {
+				return fmt.Errorf("unable to delete the category %s: %w", code,
+					category.ErrInUse)
+			}
+		}
+
+		res = tx.Delete(&cat)
+		if res.Error != nil {
+			return fmt.Errorf("unable to delete the category %s: %w", code, res.Error)
+		}
+
+		return nil
+	})
+}
+
+// touchTreeProducts updates the update time and the version of the products of the
+// category and its descendants.
+func touchTreeProducts(tx *gorm.DB, categoryID uint64) error {
+	values := map[string]any{"updated_at": tx.NowFunc(), "version": nextVersion}
+	res := tx.Model(&Product{}).Where("category IN ("+descendantsQuery+")",
+		[]uint64{categoryID}).Updates(values)
+	if res.Error != nil {
+		return fmt.Errorf("unable to update the products of the category %d: %w",
+			categoryID, res.Error)
+	}
+
+	return nil
+}
+
+// touchProducts updates the update time and the version of the products of the
+// category, and moves them to the target category if it's not nil.
+func touchProducts(tx *gorm.DB, categoryID uint64, target *uint64) error {
+	values := map[string]any{"updated_at": tx.NowFunc(), "version": nextVersion}
+	if target != nil {
+		values["category"] = *target
+	}
+
+	res := tx.Model(&Product{}).Where("category = ?", categoryID).Updates(values)
 	if res.Error != nil {
-		return fmt.Errorf("unable to create the category: %w", res.Error)
+		return fmt.Errorf("unable to update the products of the category %d: %w",
+			categoryID, res.Error)
 	}
 
 	return nil
@@ -0,0 +1,305 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultHost            = "localhost"
+	defaultPort            = "5432"
+	defaultSSLMode         = "disable"
+	defaultMaxOpenConns    = 10
+	defaultMaxIdleConns    = 5
+	defaultConnMaxLifetime = 30 * time.Minute
+	defaultConnMaxIdleTime = 5 * time.Minute
+	defaultStmtTimeout     = 30 * time.Second
+	defaultReplicaRetry    = 30 * time.Second
+	defaultConnectAttempts = 10
+	defaultConnectBackoff  = 500 * time.Millisecond
+	defaultMaxBackoff      = 10 * time.Second
+	defaultHealthInterval  = 15 * time.Second
+	maxPort                = 65535
+)
+
+// ErrInvalidConfig is returned when the database configuration is not valid.
+var ErrInvalidConfig = errors.New("invalid database configuration")
+
+// Config of the database connection and its connection pool.
+type Config struct {
+	// URL to connect to the database. If set it overrides the host, port, user,
+	// password, database name and sslmode.
+	URL string
+	// Host of the database. It can be a hostname, an IP address or the directory of a
+	// unix socket.
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Name     string
+	// SSLMode is the postgres sslmode (disable, allow, prefer, require, verify-ca or
+	// verify-full).
+	SSLMode string
+	// MaxOpenConns is the maximum number of open connections (0 means unlimited).
+	MaxOpenConns int
+	// MaxIdleConns is the maximum number of idle connections in the pool.
+	MaxIdleConns int
+	// ConnMaxLifetime is the maximum amount of time a connection may be reused.
+	ConnMaxLifetime time.Duration
+	// ConnMaxIdleTime is the maximum amount of time a connection may be idle.
+	ConnMaxIdleTime time.Duration
+	// StatementTimeout aborts any statement that takes more than the given time
+	// (0 means no timeout).
+	StatementTimeout time.Duration
+	// ReplicaURLs are the urls of the read replicas. The reads of the catalog are
+	// distributed among them, and the writes always go to the primary database.
+	ReplicaURLs []string
+	// ReplicaRetryInterval is the time a failing replica is not used before trying it
+	// again.
+	ReplicaRetryInterval time.Duration
+	// ConnectAttempts is the maximum number of attempts to connect to the database.
+	ConnectAttempts int
+	// ConnectBackoff is the wait after the first failed attempt to connect. It's doubled
+	// after every failed attempt, up to ConnectMaxBackoff.
+	ConnectBackoff    time.Duration
+	ConnectMaxBackoff time.Duration
+	// HealthCheckInterval is the time between the checks of the database connections
+	// (0 means no checks).
+	HealthCheckInterval time.Duration
+}
+
+// DefaultConfig returns a configuration with the default values.
+func DefaultConfig() Config {
+	return Config{
+		Host:             defaultHost,
+		Port:             defaultPort,
+		SSLMode:          defaultSSLMode,
+		MaxOpenConns:     defaultMaxOpenConns,
+		MaxIdleConns:     defaultMaxIdleConns,
+		ConnMaxLifetime:  defaultConnMaxLifetime,
+		ConnMaxIdleTime:  defaultConnMaxIdleTime,
+		StatementTimeout: defaultStmtTimeout,
+
+		ReplicaRetryInterval: defaultReplicaRetry,
+		ConnectAttempts:      defaultConnectAttempts,
+		ConnectBackoff:       defaultConnectBackoff,
+		ConnectMaxBackoff:    defaultMaxBackoff,
+		HealthCheckInterval:  defaultHealthInterval,
+	}
+}
+
+// ConfigFromEnv returns the database configuration defined in the environment. The
+// values not defined in the environment get the default value.
+func ConfigFromEnv() (Config, error) {
+	cfg := DefaultConfig()
+	cfg.URL = os.Getenv("DATABASE_URL")
+	cfg.User = os.Getenv("POSTGRES_USER")
+	cfg.Password = os.Getenv("POSTGRES_PASSWORD")
+	cfg.Name = os.Getenv("POSTGRES_DB")
+	setString(&cfg.Host, "POSTGRES_HOST")
+	setString(&cfg.Port, "POSTGRES_PORT")
+	setString(&cfg.SSLMode, "POSTGRES_SSLMODE")
+	if replicas := os.Getenv("DATABASE_REPLICA_URLS"); replicas != "" {
+		cfg.ReplicaURLs = strings.Split(replicas, ",")
+	}
+
+	err := errors.Join(
+		setInt(&cfg.MaxOpenConns, "POSTGRES_MAX_OPEN_CONNS"),
+		setInt(&cfg.MaxIdleConns, "POSTGRES_MAX_IDLE_CONNS"),
+		setDuration(&cfg.ConnMaxLifetime, "POSTGRES_CONN_MAX_LIFETIME"),
+		setDuration(&cfg.ConnMaxIdleTime, "POSTGRES_CONN_MAX_IDLE_TIME"),
+		setDuration(&cfg.StatementTimeout, "POSTGRES_STATEMENT_TIMEOUT"),
+		setDuration(&cfg.ReplicaRetryInterval, "DATABASE_REPLICA_RETRY_INTERVAL"),
+		setInt(&cfg.ConnectAttempts, "POSTGRES_CONNECT_ATTEMPTS"),
+		setDuration(&cfg.ConnectBackoff, "POSTGRES_CONNECT_BACKOFF"),
+		setDuration(&cfg.ConnectMaxBackoff, "POSTGRES_CONNECT_MAX_BACKOFF"),
+		setDuration(&cfg.HealthCheckInterval, "POSTGRES_HEALTH_CHECK_INTERVAL"),
+	)
+	if err != nil {
+		return Config{}, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// Validate checks the configuration is valid.
+func (c *Config) Validate() error {
+	errs := make([]error, 0)
+	if c.URL != "" {
+		if err := validateURL(c.URL); err != nil {
+			errs = append(errs, err)
+		}
+	} else {
+		errs = append(errs, c.validateConnection()...)
+	}
+
+	for _, replica := range c.ReplicaURLs {
+		if err := validateURL(replica); err != nil {
+			errs = append(errs, fmt.Errorf("replica: %w", err))
+		}
+	}
+
+	if c.MaxOpenConns < 0 || c.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("the number of connections can't be negative"))
+	}
+
+	if c.MaxOpenConns > 0 && c.MaxIdleConns > c.MaxOpenConns {
+		errs = append(errs, errors.New("max idle connections can't exceed max open connections"))
+	}
+
+	if c.ConnMaxLifetime < 0 || c.ConnMaxIdleTime < 0 || c.StatementTimeout < 0 ||
+		c.ReplicaRetryInterval < 0 || c.ConnectBackoff < 0 || c.ConnectMaxBackoff < 0 ||
+		c.HealthCheckInterval < 0 {
+		errs = append(errs, errors.New("the timeouts can't be negative"))
+	}
+
+	if c.ConnectAttempts < 1 {
+		errs = append(errs, errors.New("there must be at least one connection attempt"))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidConfig, errors.Join(errs...))
+	}
+
+	return nil
+}
+
+func validateURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+
+	if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+		return fmt.Errorf("invalid url scheme %q", u.Scheme)
+	}
+
+	return nil
+}
+
+func (c *Config) validateConnection() []error {
+	errs := make([]error, 0)
+	if c.Host == "" {
+		errs = append(errs, errors.New("empty host"))
+	}
+
+	port, err := strconv.Atoi(c.Port)
+	if err != nil || port <= 0 || port > maxPort {
+		errs = append(errs, fmt.Errorf("invalid port %q", c.Port))
+	}
+
+	if c.User == "" {
+		errs = append(errs, errors.New("empty user"))
+	}
+
+	if c.Name == "" {
+		errs = append(errs, errors.New("empty database name"))
+	}
+
+	sslModes := []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	if !slices.Contains(sslModes, c.SSLMode) {
+		errs = append(errs, fmt.Errorf("invalid sslmode %q", c.SSLMode))
+	}
+
+	return errs
+}
+
+// DSN returns the data source name to connect to the database.
+func (c *Config) DSN() string {
+	var u *url.URL
+	if c.URL != "" {
+		// The url is already validated.
+		u, _ = url.Parse(c.URL)
+	} else {
+		u = &url.URL{
+			Scheme: "postgres",
+			User:   url.UserPassword(c.User, c.Password),
+			Path:   "/" + c.Name,
+		}
+
+		query := url.Values{}
+		query.Set("sslmode", c.SSLMode)
+		if strings.HasPrefix(c.Host, "/") {
+			// Unix socket directory.
+			query.Set("host", c.Host)
+			query.Set("port", c.Port)
+		} else {
+			u.Host = net.JoinHostPort(c.Host, c.Port)
+		}
+
+		u.RawQuery = query.Encode()
+	}
+
+	return c.withStatementTimeout(u)
+}
+
+// ReplicaDSNs returns the data source names to connect to the read replicas.
+func (c *Config) ReplicaDSNs() []string {
+	dsns := make([]string, 0, len(c.ReplicaURLs))
+	for _, replica := range c.ReplicaURLs {
+		// The urls are already validated.
+		u, _ := url.Parse(replica)
+		dsns = append(dsns, c.withStatementTimeout(u))
+	}
+
+	return dsns
+}
+
+// withStatementTimeout returns the url with the statement timeout, unless the url
+// already has one.
+func (c *Config) withStatementTimeout(u *url.URL) string {
+	if c.StatementTimeout > 0 {
+		query := u.Query()
+		if !query.Has("statement_timeout") {
+			query.Set("statement_timeout", strconv.FormatInt(c.StatementTimeout.Milliseconds(), 10))
+			u.RawQuery = query.Encode()
+		}
+	}
+
+	return u.String()
+}
+
+func setString(value *string, name string) {
+	if v := os.Getenv(name); v != "" {
+		*value = v
+	}
+}
+
+func setInt(value *int, name string) error {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+
+	*value = i
+
+	return nil
+}
+
+func setDuration(value *time.Duration, name string) error {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+
+	*value = d
+
+	return nil
+}
@@ -2,82 +2,280 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
 
 	// Postgres driver to connect to the database.
 	_ "github.com/lib/pq"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/currency"
 )
 
+// errNotConnected is returned when the database is used before connecting to it.
+var errNotConnected = errors.New("no connection to the database available")
+
 // Database type to connect to the database.
 type Database struct {
-	session      *gorm.DB
-	conn         *sql.DB
-	user         string
-	password     string
-	port         string
-	databaseName string
+	// primary is the pool of the primary database, or nil if it isn't connected. It's
+	// replaced by the health check when the connection can't recover from an outage.
+	primary atomic.Pointer[pool]
+	config  Config
+	// replicas are the read replicas, and next is the index of the next replica to
+	// read from.
+	replicas []*replica
+	next     atomic.Uint64
+	// stopHealthCheck stops the background health check, and healthDone is closed when
+	// it's finished.
+	stopHealthCheck context.CancelFunc
+	healthDone      chan struct{}
+	// rates returns the exchange rates used to compare the prices in different
+	// currencies.
+	rates func() *currency.Rates
+	// inTx is true when the database is bound to a transaction, so the connection
+	// belongs to the database that started it.
+	inTx bool
 }
 
-// New returns a new database object.
-func New(user, password, dbname, port string) *Database {
-	return &Database{
-		user:         user,
-		password:     password,
-		port:         port,
-		databaseName: dbname,
+// pool is a connection pool to a database, with the session to run its queries.
+type pool struct {
+	session *gorm.DB
+	conn    *sql.DB
+}
+
+// New returns a new database object with the given configuration.
+func New(cfg Config) (*Database, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
+
+	return &Database{
+		config: cfg,
+	}, nil
 }
 
-func (db *Database) newSession() error {
-	dsn := fmt.Sprintf("postgres://%s:%s@localhost:%s/%s?sslmode=disable", db.user,
-		db.password, db.port, db.databaseName)
-	session, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// openSession opens a session to the database with the given data source name. The
+// connections are opened lazily, so it doesn't check the database is reachable.
+func (db *Database) openSession(dsn string) (*gorm.DB, *sql.DB, error) {
+	// The ping is done by the caller with the context, so it can be cancelled.
+	session, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		DisableAutomaticPing: true,
+		// The errors are translated, so we can detect the duplicated keys.
+		TranslateError: true,
+		// The timestamps are stored with the precision of the database, so the written
+		// entities match the stored ones.
+		NowFunc: func() time.Time {
+			return time.Now().UTC().Truncate(time.Microsecond)
+		},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to start the database session: %w", err)
+	}
+
+	sqlDB, err := session.DB()
 	if err != nil {
-		return fmt.Errorf("unable to start the database session: %w", err)
+		return nil, nil, fmt.Errorf("unable to get the database connection: %w", err)
 	}
 
-	db.session = session
+	// Connection pool tuning.
+	sqlDB.SetMaxOpenConns(db.config.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(db.config.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(db.config.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(db.config.ConnMaxIdleTime)
+
+	return session, sqlDB, nil
+}
+
+// Connect to the database and to its read replicas. The connection to the database is
+// retried with an exponential backoff while it fails with a transient error, so the
+// server can start before the database is ready. A replica that can't be reached is not
+// used until the retry interval passes, but it doesn't make the connection fail.
+func (db *Database) Connect(ctx context.Context) error {
+	if db.inTx {
+		return nil
+	}
+
+	if db.primary.Load() == nil {
+		if err := db.connectPrimary(ctx); err != nil {
+			return err
+		}
+	}
+
+	if db.replicas == nil {
+		if err := db.connectReplicas(ctx); err != nil {
+			return err
+		}
+	}
+
+	if db.stopHealthCheck == nil && db.config.HealthCheckInterval > 0 {
+		// The health check outlives the context of the connection, and it's stopped on
+		// disconnection.
+		hctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		db.stopHealthCheck = cancel
+		db.healthDone = make(chan struct{})
+		go db.healthCheck(hctx, db.config.HealthCheckInterval)
+	}
 
 	return nil
 }
 
-// Connect to the database.
-func (db *Database) Connect() error {
-	if db.session == nil {
-		if err := db.newSession(); err != nil {
+// connectPrimary connects to the primary database, retrying the transient failures up to
+// the configured number of attempts.
+func (db *Database) connectPrimary(ctx context.Context) error {
+	var err error
+	for attempt := range db.config.ConnectAttempts {
+		if attempt > 0 {
+			wait := backoff(attempt-1, db.config.ConnectBackoff, db.config.ConnectMaxBackoff)
+			slog.Warn("unable to connect to the database, retrying", "attempt", attempt,
+				"wait", wait, "error", err)
+			if err := sleep(ctx, wait); err != nil {
+				return fmt.Errorf("unable to reach the database: %w", err)
+			}
+		}
+
+		err = db.tryConnect(ctx)
+		if err == nil || !isTransientError(ctx, err) {
 			return err
 		}
 	}
 
-	sqlDB, err := db.session.DB()
+	return err
+}
+
+// tryConnect opens a session to the primary database and checks it's reachable.
+func (db *Database) tryConnect(ctx context.Context) error {
+	session, conn, err := db.openSession(db.config.DSN())
 	if err != nil {
-		return fmt.Errorf("unable to get the database connection: %w", err)
+		return err
+	}
+
+	if err := conn.PingContext(ctx); err != nil {
+		_ = conn.Close()
+
+		return fmt.Errorf("unable to reach the database: %w", err)
+	}
+
+	db.setPrimary(session, conn)
+
+	return nil
+}
+
+// setPrimary sets the pool of the primary database.
+func (db *Database) setPrimary(session *gorm.DB, conn *sql.DB) {
+	db.primary.Store(&pool{session: session, conn: conn})
+}
+
+// conn returns the connection pool of the primary database, or nil if it isn't
+// connected.
+func (db *Database) conn() *sql.DB {
+	if p := db.primary.Load(); p != nil {
+		return p.conn
 	}
 
-	db.conn = sqlDB
+	return nil
+}
+
+// connectReplicas opens the sessions to the read replicas. The replicas that can't be
+// reached are marked as failed, and they are tried again after the retry interval.
+func (db *Database) connectReplicas(ctx context.Context) error {
+	dsns := db.config.ReplicaDSNs()
+	replicas := make([]*replica, 0, len(dsns))
+	for i, dsn := range dsns {
+		session, conn, err := db.openSession(dsn)
+		if err != nil {
+			for _, r := range replicas {
+				_ = r.conn.Close()
+			}
+
+			return fmt.Errorf("unable to open the replica %d: %w", i, err)
+		}
+
+		r := &replica{session: session, conn: conn}
+		if err := conn.PingContext(ctx); err != nil {
+			slog.Warn("unable to reach the replica", "replica", i, "error", err)
+			r.markFailed(time.Now())
+		}
+
+		replicas = append(replicas, r)
+	}
+
+	db.replicas = replicas
+
+	return nil
+}
+
+// Ping checks the primary database is reachable.
+func (db *Database) Ping(ctx context.Context) error {
+	conn := db.conn()
+	if conn == nil {
+		return errNotConnected
+	}
+
+	if err := conn.PingContext(ctx); err != nil {
+		return fmt.Errorf("unable to reach the database: %w", err)
+	}
 
 	return nil
 }
 
 // Disconnect from the database.
-func (db *Database) Disconnect() error {
+func (db *Database) Disconnect(_ context.Context) error {
 	if db == nil {
 		return nil
 	}
 
-	if db.conn == nil {
+	p := db.primary.Load()
+	if p == nil || db.inTx {
 		return nil
 	}
 
-	if err := db.conn.Close(); err != nil {
-		return err
+	if db.stopHealthCheck != nil {
+		db.stopHealthCheck()
+		<-db.healthDone
+		db.stopHealthCheck = nil
+	}
+
+	errs := make([]error, 0, len(db.replicas)+1)
+	for _, r := range db.replicas {
+		errs = append(errs, r.conn.Close())
 	}
 
-	db.conn = nil
-	db.session = nil
+	errs = append(errs, p.conn.Close())
+	db.primary.Store(nil)
+	db.replicas = nil
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
 
 	return nil
 }
+
+// session returns a session of the primary database bound to the given context, so the
+// queries are cancelled as soon as the context is done. It fails if the database isn't
+// connected.
+func (db *Database) session(ctx context.Context) (*gorm.DB, error) {
+	p := db.primary.Load()
+	if p == nil {
+		return nil, errNotConnected
+	}
+
+	return p.session.WithContext(ctx), nil
+}
+
+// transaction runs fn in a transaction of the primary database, committed if fn
+// succeeds and rolled back otherwise.
+func (db *Database) transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	session, err := db.session(ctx)
+	if err != nil {
+		return err
+	}
+
+	return session.Transaction(fn)
+}
@@ -0,0 +1,101 @@
+// Package dbtest starts the postgres databases of the tests, with the schema of the
+// migrations and the sample data of the seed files.
+package dbtest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/storage/database"
+)
+
+const (
+	image      = "postgres:17-alpine"
+	dbName     = "users"
+	dbUser     = "user"
+	dbPassword = "password"
+)
+
+// New starts a postgres container, applies all the migrations and loads the seed files,
+// and returns a database connected to it. The connection and the container are removed
+// at the end of the test, and the test is skipped if docker isn't available.
+func New(ctx context.Context, t *testing.T) *database.Database {
+	t.Helper()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+	container, err := postgres.Run(ctx, image,
+		postgres.WithDatabase(dbName),
+		postgres.WithUsername(dbUser),
+		postgres.WithPassword(dbPassword),
+		postgres.BasicWaitStrategies(),
+	)
+	if err != nil {
+		t.Fatalf("unable to start the postgres container: %s", err)
+	}
+
+	t.Cleanup(func() {
+		if err := testcontainers.TerminateContainer(container); err != nil {
+			t.Errorf("failed to terminate container: %s", err)
+		}
+	})
+
+	port, err := container.MappedPort(ctx, "5432")
+	if err != nil {
+		t.Fatalf("unable to inspect the postgres container: %s", err)
+	}
+
+	cfg := database.DefaultConfig()
+	cfg.User = dbUser
+	cfg.Password = dbPassword
+	cfg.Name = dbName
+	cfg.Port = port.Port()
+	db, err := database.New(cfg)
+	if err != nil {
+		t.Fatalf("unable to initialize the database: %s", err)
+	}
+
+	if err := db.Connect(ctx); err != nil {
+		t.Fatalf("unable to connect to the database: %s", err)
+	}
+
+	t.Cleanup(func() {
+		if err := db.Disconnect(ctx); err != nil {
+			t.Errorf("unable to disconnect from the database: %s", err)
+		}
+	})
+
+	migrate(ctx, t, db)
+
+	return db
+}
+
+// migrate applies the migrations and loads the seed files of the sql directory.
+func migrate(ctx context.Context, t *testing.T, db *database.Database) {
+	t.Helper()
+	dir := sqlDir()
+	migrations, err := database.LoadMigrations(os.DirFS(dir))
+	if err != nil {
+		t.Fatalf("unable to load the migrations: %s", err)
+	}
+
+	if _, err := db.MigrateUp(ctx, migrations, 0); err != nil {
+		t.Fatalf("unable to apply the migrations: %s", err)
+	}
+
+	if _, err := db.Seed(ctx, os.DirFS(filepath.Join(dir, "seed"))); err != nil {
+		t.Fatalf("unable to load the seed files: %s", err)
+	}
+}
+
+// sqlDir returns the sql directory of the repository, as the tests run in the directory
+// of their package.
+func sqlDir() string {
+	_, file, _, _ := runtime.Caller(0)
+
+	return filepath.Join(filepath.Dir(file), "..", "..", "..", "..", "sql")
+}
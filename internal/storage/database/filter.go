@@ -0,0 +1,218 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/currency"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/filter"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/product"
+)
+
+// column of a table that can be used in the filters.
+type column struct {
+	name string
+	// attribute is the key of the product attribute of the column, if any.
+	attribute string
+	numeric   bool
+	timestamp bool
+	boolean   bool
+	// tree is set on the category column matching the descendant categories too.
+	tree bool
+}
+
+// productColumn maps the logical fields of a product allowed in the filters to their
+// columns. Any other field is rejected, so the filters can't inject sql. The attribute
+// keys only have letters, digits, underscores and dashes, so they are safe to quote.
+// The converted prices use the exchange rates, if any.
+func productColumn(field string, rates *currency.Rates) (column, bool) {
+	if key, ok := product.AttributeKey(field); ok {
+		return column{name: fmt.Sprintf("(products.attributes ->> '%s')", key), attribute: key},
+			true
+	}
+
+	if code, ok := product.PriceCurrency(field); ok {
+		return column{name: convertedPriceQuery(rates, code), numeric: true}, true
+	}
+
+	switch field {
+	case "id":
+		return column{name: "products.id", numeric: true}, true
+	case "code":
+		return column{name: "products.code"}, true
+	case "price":
+		return column{name: activePriceQuery, numeric: true}, true
+	case "currency":
+		return column{name: activeCurrencyQuery}, true
+	case "category":
+		return column{name: "products.category", numeric: true}, true
+	case "category_tree":
+		return column{name: "products.category", numeric: true, tree: true}, true
+	case "updated_at":
+		return column{name: "products.updated_at", timestamp: true}, true
+	case "on_sale":
+		return column{name: onSaleQuery, boolean: true}, true
+	case "in_stock":
+		return column{name: inStockQuery, boolean: true}, true
+	}
+
+	return column{}, false
+}
+
+// filterClause returns the where clause of the filters, all of them must match, with
+// its values. The converted prices are compared with the exchange rates.
+func filterClause(filters []filter.Filter, rates *currency.Rates) (string, []any, error) {
+	return groupClause(filters, " AND ", rates)
+}
+
+func groupClause(filters []filter.Filter, operator string,
+	rates *currency.Rates,
+) (string, []any, error) {
+	clauses := make([]string, 0, len(filters))
+	values := make([]any, 0, len(filters))
+	for i := range filters {
+		clause, v, err := buildClause(&filters[i], rates)
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		values = append(values, v...)
+	}
+
+	if len(clauses) > 1 {
+		return "(" + strings.Join(clauses, operator) + ")", values, nil
+	}
+
+	return strings.Join(clauses, operator), values, nil
+}
+
+func buildClause(f *filter.Filter, rates *currency.Rates) (string, []any, error) {
+	if err := f.Validate(); err != nil {
+		return "", nil, err
+	}
+
+	if len(f.Or) > 0 {
+		return groupClause(f.Or, " OR ", rates)
+	}
+
+	if len(f.And) > 0 {
+		return groupClause(f.And, " AND ", rates)
+	}
+
+	col, ok := productColumn(f.Key, rates)
+	if !ok {
+		return "", nil, &filter.FieldError{Field: f.Key}
+	}
+
+	values, err := columnValues(col, f)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// The equality of an attribute is checked with a containment, as it uses the index
+	// of the attributes.
+	if col.attribute != "" && f.Operation == filter.Equal {
+		doc, err := json.Marshal(map[string]string{col.attribute: f.Value})
+		if err != nil {
+			return "", nil, err
+		}
+
+		return "products.attributes @> ?", []any{string(doc)}, nil
+	}
+
+	if col.boolean && f.Operation != filter.Equal && f.Operation != filter.NotEqual {
+		return "", nil, fmt.Errorf("%w: %s can't be applied to %s", filter.ErrInvalidFilter,
+			f.Operation, f.Key)
+	}
+
+	if col.tree {
+		if f.Operation != filter.Equal && f.Operation != filter.In {
+			return "", nil, fmt.Errorf("%w: %s can't be applied to %s", filter.ErrInvalidFilter,
+				f.Operation, f.Key)
+		}
+
+		return col.name + " IN (" + descendantsQuery + ")", []any{values}, nil
+	}
+
+	switch f.Operation {
+	case filter.Equal, filter.NotEqual, filter.LessThan, filter.LessOrEqual,
+		filter.GreaterThan, filter.GreaterOrEqual:
+		// The operation is one of the known ones, so it's safe to add it to the clause.
+		return fmt.Sprintf("%s %s ?", col.name, f.Operation), values, nil
+	case filter.In:
+		return col.name + " IN ?", []any{values}, nil
+	case filter.Between:
+		return col.name + " BETWEEN ? AND ?", values, nil
+	case filter.Prefix:
+		return col.name + ` LIKE ? ESCAPE '\'`, []any{escapeLike(f.Value) + "%"}, nil
+	case filter.Contains:
+		return col.name + ` LIKE ? ESCAPE '\'`, []any{"%" + escapeLike(f.Value) + "%"}, nil
+	}
+
+	return "", nil, fmt.Errorf("%w: unknown operation %q", filter.ErrInvalidFilter,
+		f.Operation)
+}
+
+// columnValues returns the values of the filter checking they are valid for the column.
+func columnValues(col column, f *filter.Filter) ([]any, error) {
+	if f.Operation.IsTextOperation() && (col.numeric || col.timestamp || col.boolean) {
+		return nil, fmt.Errorf("%w: %s can't be applied to %s", filter.ErrInvalidFilter,
+			f.Operation, f.Key)
+	}
+
+	values := f.Values
+	if f.Operation != filter.In && f.Operation != filter.Between {
+		values = []string{f.Value}
+	}
+
+	res := make([]any, 0, len(values))
+	for _, v := range values {
+		if col.timestamp {
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid %s %q", filter.ErrInvalidFilter, f.Key, v)
+			}
+
+			res = append(res, t)
+
+			continue
+		}
+
+		if col.boolean {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid %s %q", filter.ErrInvalidFilter, f.Key, v)
+			}
+
+			res = append(res, b)
+
+			continue
+		}
+
+		if !col.numeric {
+			res = append(res, v)
+
+			continue
+		}
+
+		d, err := decimal.NewFromString(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid %s %q", filter.ErrInvalidFilter, f.Key, v)
+		}
+
+		res = append(res, d)
+	}
+
+	return res, nil
+}
+
+// escapeLike escapes the wildcards of a like pattern.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,196 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/currency"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/filter"
+)
+
+// nolint: funlen
+func TestFilterClause(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name           string
+		expectedClause string
+		filters        []filter.Filter
+		expectedValues []any
+	}{
+		{
+			name:           "no filters",
+			expectedValues: []any{},
+		},
+		{
+			name: "comparisons",
+			filters: []filter.Filter{
+				{Key: "category", Value: "1", Operation: filter.Equal},
+				{Key: "price", Value: "10", Operation: filter.LessThan},
+			},
+			expectedClause: "(products.category = ? AND " + activePriceQuery + " < ?)",
+			expectedValues: []any{decimal.RequireFromString("1"), decimal.RequireFromString("10")},
+		},
+		{
+			name: "price in a currency",
+			filters: []filter.Filter{
+				{Key: "price", Value: "10", Operation: filter.LessThan},
+				{Key: "currency", Value: "USD", Operation: filter.Equal},
+			},
+			expectedClause: "(" + activePriceQuery + " < ? AND " + activeCurrencyQuery + " = ?)",
+			expectedValues: []any{decimal.RequireFromString("10"), "USD"},
+		},
+		{
+			name: "in and between",
+			filters: []filter.Filter{
+				{Key: "code", Values: []string{"A", "B"}, Operation: filter.In},
+				{Key: "price", Values: []string{"1", "2"}, Operation: filter.Between},
+			},
+			expectedClause: "(products.code IN ? AND " + activePriceQuery + " BETWEEN ? AND ?)",
+			expectedValues: []any{
+				[]any{"A", "B"}, decimal.RequireFromString("1"), decimal.RequireFromString("2"),
+			},
+		},
+		{
+			name: "or group with escaped pattern",
+			filters: []filter.Filter{
+				filter.AnyOf(
+					filter.Filter{Key: "code", Value: "10%_", Operation: filter.Prefix},
+					filter.Filter{Key: "code", Value: "x", Operation: filter.Contains},
+				),
+			},
+			expectedClause: `(products.code LIKE ? ESCAPE '\' OR products.code LIKE ? ESCAPE '\')`,
+			expectedValues: []any{`10\%\_%`, "%x%"},
+		},
+		{
+			name: "attributes",
+			filters: []filter.Filter{
+				{Key: "attr.material", Value: "cotton", Operation: filter.Equal},
+				{Key: "attr.fit_type", Values: []string{"slim", "regular"}, Operation: filter.In},
+			},
+			expectedClause: "(products.attributes @> ? AND " +
+				"(products.attributes ->> 'fit_type') IN ?)",
+			expectedValues: []any{`{"material":"cotton"}`, []any{"slim", "regular"}},
+		},
+		{
+			name: "updated since",
+			filters: []filter.Filter{
+				{Key: "updated_at", Value: "2025-01-02T03:04:05Z", Operation: filter.GreaterOrEqual},
+			},
+			expectedClause: "products.updated_at >= ?",
+			expectedValues: []any{time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)},
+		},
+		{
+			name: "category tree",
+			filters: []filter.Filter{
+				{Key: "category_tree", Value: "1", Operation: filter.Equal},
+			},
+			expectedClause: "products.category IN (" + descendantsQuery + ")",
+			expectedValues: []any{[]any{decimal.NewFromInt(1)}},
+		},
+		{
+			name: "on sale",
+			filters: []filter.Filter{
+				{Key: "on_sale", Value: "true", Operation: filter.Equal},
+			},
+			expectedClause: onSaleQuery + " = ?",
+			expectedValues: []any{true},
+		},
+		{
+			name: "not in stock",
+			filters: []filter.Filter{
+				{Key: "in_stock", Value: "true", Operation: filter.NotEqual},
+			},
+			expectedClause: inStockQuery + " != ?",
+			expectedValues: []any{true},
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			clause, values, err := filterClause(tc.filters, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedClause, clause)
+			assert.Equal(t, tc.expectedValues, values)
+		})
+	}
+}
+
+func TestConvertedPriceClause(t *testing.T) {
+	t.Parallel()
+	rates, err := currency.NewRates("EUR", map[string]decimal.Decimal{
+		"USD": decimal.RequireFromString("1.0842"),
+		"JPY": decimal.RequireFromString("162.51"),
+	})
+	require.NoError(t, err)
+
+	filters := []filter.Filter{{Key: "price.JPY", Value: "1000", Operation: filter.LessThan}}
+	clause, values, err := filterClause(filters, rates)
+	require.NoError(t, err)
+	assert.Equal(t, "ROUND("+activePriceQuery+" * 162.51 / (CASE "+activeCurrencyQuery+
+		" WHEN 'EUR' THEN 1 WHEN 'JPY' THEN 162.51 WHEN 'USD' THEN 1.0842 END), 0) < ?", clause)
+	assert.Equal(t, []any{decimal.NewFromInt(1000)}, values)
+
+	// Without a rate for the currency only its own prices can be compared.
+	clause, _, err = filterClause(filters, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "(CASE "+activeCurrencyQuery+" WHEN 'JPY' THEN "+activePriceQuery+
+		" END) < ?", clause)
+
+	_, _, err = filterClause([]filter.Filter{
+		{Key: "price.jpy", Value: "1000", Operation: filter.LessThan},
+	}, rates)
+	var fieldErr *filter.FieldError
+	require.True(t, errors.As(err, &fieldErr))
+}
+
+func TestFilterClauseErrors(t *testing.T) {
+	t.Parallel()
+	_, _, err := filterClause([]filter.Filter{
+		{Key: "price; DROP TABLE products", Value: "1", Operation: filter.Equal},
+	}, nil)
+	var fieldErr *filter.FieldError
+	require.True(t, errors.As(err, &fieldErr))
+	assert.Equal(t, "price; DROP TABLE products", fieldErr.Field)
+
+	_, _, err = filterClause([]filter.Filter{
+		{Key: "attr.a') = 'a' OR ('a", Value: "1", Operation: filter.NotEqual},
+	}, nil)
+	require.True(t, errors.As(err, &fieldErr))
+
+	_, _, err = filterClause([]filter.Filter{
+		{Key: "price", Value: "1", Operation: "; DROP TABLE products"},
+	}, nil)
+	require.ErrorIs(t, err, filter.ErrInvalidFilter)
+
+	_, _, err = filterClause([]filter.Filter{
+		{Key: "price", Value: "1", Operation: filter.Contains},
+	}, nil)
+	require.ErrorIs(t, err, filter.ErrInvalidFilter)
+
+	_, _, err = filterClause([]filter.Filter{
+		{Key: "updated_at", Value: "yesterday", Operation: filter.GreaterOrEqual},
+	}, nil)
+	require.ErrorIs(t, err, filter.ErrInvalidFilter)
+
+	_, _, err = filterClause([]filter.Filter{
+		{Key: "category_tree", Value: "1", Operation: filter.LessThan},
+	}, nil)
+	require.ErrorIs(t, err, filter.ErrInvalidFilter)
+
+	_, _, err = filterClause([]filter.Filter{
+		{Key: "on_sale", Value: "maybe", Operation: filter.Equal},
+	}, nil)
+	require.ErrorIs(t, err, filter.ErrInvalidFilter)
+
+	_, _, err = filterClause([]filter.Filter{
+		{Key: "on_sale", Value: "true", Operation: filter.GreaterThan},
+	}, nil)
+	require.ErrorIs(t, err, filter.ErrInvalidFilter)
+}
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+// healthCheck checks the connections to the primary database and to the replicas every
+// interval, until the context is done.
+func (db *Database) healthCheck(ctx context.Context, interval time.Duration) {
+	defer close(db.healthDone)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	healthy := true
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		healthy = db.checkPrimary(ctx, interval, healthy)
+		db.checkReplicas(ctx, interval)
+	}
+}
+
+// checkPrimary pings the primary database and returns true if it's reachable. The idle
+// connections are closed after a failure, so the stale ones are not reused after an
+// outage, and while the failure persists the pool is reopened until it reaches the
+// database again.
+func (db *Database) checkPrimary(ctx context.Context, timeout time.Duration, healthy bool) bool {
+	conn := db.conn()
+	if err := ping(ctx, conn, timeout); err != nil {
+		if healthy {
+			slog.Error("lost the connection to the database", "error", err)
+			resetPool(conn, db.config.MaxIdleConns)
+
+			return false
+		}
+
+		return db.reopenPrimary(ctx, timeout)
+	}
+
+	if !healthy {
+		slog.Info("restored the connection to the database")
+	}
+
+	return true
+}
+
+// reopenPrimary opens a new pool to the primary database and replaces the current one
+// with it if it reaches the database. The old pool is closed in the background, so the
+// queries running in it can finish.
+func (db *Database) reopenPrimary(ctx context.Context, timeout time.Duration) bool {
+	session, conn, err := db.openSession(db.config.DSN())
+	if err != nil {
+		slog.Warn("unable to reopen the connection to the database", "error", err)
+
+		return false
+	}
+
+	if err := ping(ctx, conn, timeout); err != nil {
+		_ = conn.Close()
+
+		return false
+	}
+
+	old := db.primary.Swap(&pool{session: session, conn: conn})
+	slog.Info("reopened the connection to the database")
+	go func() {
+		if err := old.conn.Close(); err != nil {
+			slog.Warn("unable to close the old connection to the database", "error", err)
+		}
+	}()
+
+	return true
+}
+
+// checkReplicas pings the replicas, so the failed ones are used again as soon as they
+// are reachable, and the broken ones are not used until the retry interval passes.
+func (db *Database) checkReplicas(ctx context.Context, timeout time.Duration) {
+	for i, r := range db.replicas {
+		if err := ping(ctx, r.conn, timeout); err != nil {
+			if r.failedAt.Load() == 0 {
+				slog.Warn("lost the connection to the replica", "replica", i, "error", err)
+			}
+
+			r.markFailed(time.Now())
+			resetPool(r.conn, db.config.MaxIdleConns)
+
+			continue
+		}
+
+		r.failedAt.Store(0)
+	}
+}
+
+func ping(ctx context.Context, conn *sql.DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return conn.PingContext(ctx)
+}
+
+// resetPool closes the idle connections of the pool, so the new queries open new
+// connections.
+func resetPool(conn *sql.DB, maxIdleConns int) {
+	conn.SetMaxIdleConns(0)
+	conn.SetMaxIdleConns(maxIdleConns)
+}
@@ -0,0 +1,29 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckPrimary(t *testing.T) {
+	t.Parallel()
+	db := unreachableDatabase(t)
+	session, conn, err := db.openSession(db.config.DSN())
+	require.NoError(t, err)
+	db.setPrimary(session, conn)
+	t.Cleanup(func() { assert.NoError(t, db.Disconnect(context.Background())) })
+
+	// The first failure only closes the idle connections.
+	p := db.primary.Load()
+	assert.False(t, db.checkPrimary(context.Background(), 100*time.Millisecond, true))
+	assert.Same(t, p, db.primary.Load())
+
+	// While the failure persists the pool is reopened, but it's only replaced by a pool
+	// reaching the database.
+	assert.False(t, db.checkPrimary(context.Background(), 100*time.Millisecond, false))
+	assert.Same(t, p, db.primary.Load())
+}
@@ -0,0 +1,496 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/inventory"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/variant"
+)
+
+const (
+	// inStockQuery selects if some variant of the product has units available.
+	inStockQuery = `EXISTS (SELECT 1 FROM product_variants pv
+	JOIN inventory_levels il ON il.variant_id = pv.id
+	WHERE pv.product_id = products.id AND pv.deleted_at IS NULL AND il.on_hand > il.reserved)`
+
+	// stockQuery selects the stock of the variants with the given SKUs, with zero units
+	// if they don't have stock.
+	stockQuery = `SELECT pv.id AS variant_id, pv.sku, COALESCE(il.on_hand, 0) AS on_hand,
+	COALESCE(il.reserved, 0) AS reserved, COALESCE(il.updated_at, pv.created_at) AS updated_at
+	FROM product_variants pv LEFT JOIN inventory_levels il ON il.variant_id = pv.id
+	WHERE pv.deleted_at IS NULL AND pv.sku IN ? ORDER BY pv.sku`
+
+	// releaseStock and commitStock are the changes of the stock of the lines of a
+	// reservation when it's released and committed.
+	releaseStock = "reserved = il.reserved - l.quantity"
+	commitStock  = "on_hand = il.on_hand - l.quantity, reserved = il.reserved - l.quantity"
+)
+
+// GetStock obtains the stock of the variant with the given SKU.
+func (db *Database) GetStock(ctx context.Context, sku string) (*inventory.Stock, error) {
+	stocks, err := db.GetStocks(ctx, []string{sku})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(stocks) == 0 {
+		return nil, variant.ErrNotFound
+	}
+
+	return &stocks[0], nil
+}
+
+// GetStocks obtains the stock of the variants with the given SKUs, ignoring the unknown
+// ones.
+func (db *Database) GetStocks(ctx context.Context, skus []string) ([]inventory.Stock, error) {
+	if len(skus) == 0 {
+		return []inventory.Stock{}, nil
+	}
+
+	var rows []stockRow
+	err := db.read(ctx, func(tx *gorm.DB) error {
+		if res := tx.Raw(stockQuery, skus).Scan(&rows); res.Error != nil {
+			return fmt.Errorf("unable to fetch the stock: %w", res.Error)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]inventory.Stock, 0, len(rows))
+	for i := range rows {
+		res = append(res, rows[i].toModel())
+	}
+
+	return res, nil
+}
+
+// AdjustStock adds the delta, positive or negative, to the units on hand of the variant
+// with the given SKU. It fails with an insufficient stock error if there would be fewer
+// units on hand than reserved, once the expired reservations of the variant are
+// released.
+func (db *Database) AdjustStock(ctx context.Context, sku string,
+	delta int64,
+) (*inventory.Stock, error) {
+	if err := inventory.ValidateAdjustment(delta); err != nil {
+		return nil, err
+	}
+
+	var stock Stock
+	err := db.transaction(ctx, func(tx *gorm.DB) error {
+		ids, err := variantIDs(tx, []string{sku})
+		if err != nil {
+			return err
+		}
+
+		id, ok := ids[sku]
+		if !ok {
+			return variant.ErrNotFound
+		}
+
+		now := tx.NowFunc()
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&Stock{VariantID: id, UpdatedAt: now})
+		if res.Error != nil {
+			return fmt.Errorf("unable to create the stock of %s: %w", sku, res.Error)
+		}
+
+		if err := expireOverdue(tx, now, []uint{id}); err != nil {
+			return err
+		}
+
+		res = tx.Model(&stock).Clauses(clause.Returning{}).
+			Where("variant_id = ? AND on_hand + ? >= reserved", id, delta).
+			Updates(map[string]any{
+				"on_hand":    gorm.Expr("on_hand + ?", delta),
+				"updated_at": now,
+			})
+		if res.Error != nil {
+			return fmt.Errorf("unable to adjust the stock of %s: %w", sku, res.Error)
+		}
+
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("%w: %s has units reserved", inventory.ErrInsufficientStock, sku)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &inventory.Stock{
+		UpdatedAt: stock.UpdatedAt,
+		SKU:       sku,
+		OnHand:    stock.OnHand,
+		Reserved:  stock.Reserved,
+	}, nil
+}
+
+// CreateReservation reserves the units of all the lines of the reservation, or none of
+// them if any variant doesn't have enough units available, once the expired
+// reservations of the variants are released. The stock is only reserved if it's still
+// available when it's updated, so the concurrent reservations can't reserve the same
+// units. The reservation is updated with the stored data.
+func (db *Database) CreateReservation(ctx context.Context, r *inventory.Reservation) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
+	rec := newReservation(r)
+	slices.SortFunc(rec.Lines, func(a, b ReservationLine) int {
+		return cmp.Compare(a.SKU, b.SKU)
+	})
+
+	err := db.transaction(ctx, func(tx *gorm.DB) error {
+		now := tx.NowFunc()
+		if !r.ExpiresAt.After(now) {
+			return fmt.Errorf("%w: it's already expired", inventory.ErrInvalidReservation)
+		}
+
+		skus := make([]string, 0, len(rec.Lines))
+		for i := range rec.Lines {
+			skus = append(skus, rec.Lines[i].SKU)
+		}
+
+		ids, err := variantIDs(tx, skus)
+		if err != nil {
+			return err
+		}
+
+		variants := make([]uint, 0, len(rec.Lines))
+		for i := range rec.Lines {
+			id, ok := ids[rec.Lines[i].SKU]
+			if !ok {
+				return fmt.Errorf("unable to reserve %s: %w", rec.Lines[i].SKU,
+					variant.ErrNotFound)
+			}
+
+			rec.Lines[i].VariantID = id
+			variants = append(variants, id)
+		}
+
+		if err := expireOverdue(tx, now, variants); err != nil {
+			return err
+		}
+
+		if err := reserveLines(tx, now, rec.Lines); err != nil {
+			return err
+		}
+
+		if res := tx.Create(&rec); res.Error != nil {
+			return fmt.Errorf("unable to create the reservation: %w", res.Error)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	*r = rec.toModel()
+
+	return nil
+}
+
+// GetReservation obtains a reservation by its id.
+func (db *Database) GetReservation(ctx context.Context,
+	id uint,
+) (*inventory.Reservation, error) {
+	var rec Reservation
+	err := db.read(ctx, func(tx *gorm.DB) error {
+		res := tx.Preload("Lines", orderLines).Limit(1).Find(&rec, id)
+		if res.Error != nil {
+			return fmt.Errorf("unable to fetch the reservation %d: %w", id, res.Error)
+		}
+
+		if res.RowsAffected == 0 {
+			return inventory.ErrReservationNotFound
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r := rec.toModel()
+
+	return &r, nil
+}
+
+// CommitReservation removes the reserved units from the stock on hand. It fails if the
+// reservation isn't active or it's expired.
+func (db *Database) CommitReservation(ctx context.Context,
+	id uint,
+) (*inventory.Reservation, error) {
+	return db.closeReservation(ctx, id, inventory.StatusCommitted, commitStock,
+		func(r *inventory.Reservation, now time.Time) error {
+			return r.ValidateCommit(now)
+		})
+}
+
+// ReleaseReservation makes the reserved units available again. It fails if the
+// reservation isn't active.
+func (db *Database) ReleaseReservation(ctx context.Context,
+	id uint,
+) (*inventory.Reservation, error) {
+	return db.closeReservation(ctx, id, inventory.StatusReleased, releaseStock,
+		func(r *inventory.Reservation, _ time.Time) error {
+			if r.Status != inventory.StatusActive {
+				return fmt.Errorf("%w: it's %s", inventory.ErrReservationClosed, r.Status)
+			}
+
+			return nil
+		})
+}
+
+// ExpireReservations releases the active reservations after their expiration time, and
+// returns how many of them expired. The reservations being closed concurrently are
+// skipped.
+func (db *Database) ExpireReservations(ctx context.Context) (int64, error) {
+	var expired int64
+	err := db.transaction(ctx, func(tx *gorm.DB) error {
+		now := tx.NowFunc()
+		ids, err := overdueReservations(tx, now, nil)
+		if err != nil {
+			return err
+		}
+
+		if err := lockStock(tx, nil, ids); err != nil {
+			return err
+		}
+
+		expired = int64(len(ids))
+
+		return releaseReservations(tx, now, ids, inventory.StatusExpired, releaseStock)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return expired, nil
+}
+
+// closeReservation locks the reservation, checks it can be closed, and applies the change
+// to the stock of its lines, closing it with the status.
+func (db *Database) closeReservation(ctx context.Context, id uint, status inventory.Status,
+	change string, check func(r *inventory.Reservation, now time.Time) error,
+) (*inventory.Reservation, error) {
+	var rec Reservation
+	err := db.transaction(ctx, func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Limit(1).Find(&rec, id)
+		if res.Error != nil {
+			return fmt.Errorf("unable to fetch the reservation %d: %w", id, res.Error)
+		}
+
+		if res.RowsAffected == 0 {
+			return inventory.ErrReservationNotFound
+		}
+
+		res = orderLines(tx.Where("reservation_id = ?", id)).Find(&rec.Lines)
+		if res.Error != nil {
+			return fmt.Errorf("unable to fetch the lines of the reservation %d: %w", id,
+				res.Error)
+		}
+
+		now := tx.NowFunc()
+		current := rec.toModel()
+		if err := check(&current, now); err != nil {
+			return err
+		}
+
+		rec.Status = string(status)
+		rec.UpdatedAt = now
+		if err := lockStock(tx, nil, []uint{id}); err != nil {
+			return err
+		}
+
+		return releaseReservations(tx, now, []uint{id}, status, change)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r := rec.toModel()
+
+	return &r, nil
+}
+
+// variantIDs returns the ids of the variants with the given SKUs by their SKU, ignoring
+// the unknown ones. The variants are locked, so they aren't removed before the stock
+// changes.
+func variantIDs(tx *gorm.DB, skus []string) (map[string]uint, error) {
+	var variants Variants
+	res := tx.Clauses(clause.Locking{Strength: "SHARE"}).Select("id", "sku").
+		Where("sku IN ?", skus).Find(&variants)
+	if res.Error != nil {
+		return nil, fmt.Errorf("unable to fetch the variants: %w", res.Error)
+	}
+
+	ids := make(map[string]uint, len(variants))
+	for i := range variants {
+		ids[variants[i].SKU] = variants[i].ID
+	}
+
+	return ids, nil
+}
+
+// reserveLines reserves the units of the lines, and fails if any variant doesn't have
+// enough units available. The stock must be locked.
+func reserveLines(tx *gorm.DB, now time.Time, lines []ReservationLine) error {
+	for _, l := range lines {
+		res := tx.Model(&Stock{}).
+			Where("variant_id = ? AND on_hand - reserved >= ?", l.VariantID, l.Quantity).
+			Updates(map[string]any{
+				"reserved":   gorm.Expr("reserved + ?", l.Quantity),
+				"updated_at": now,
+			})
+		if res.Error != nil {
+			return fmt.Errorf("unable to reserve %s: %w", l.SKU, res.Error)
+		}
+
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("%w: %s doesn't have %d units available",
+				inventory.ErrInsufficientStock, l.SKU, l.Quantity)
+		}
+	}
+
+	return nil
+}
+
+// expireOverdue locks the stock of the variants, and releases their active reservations
+// after their expiration time.
+func expireOverdue(tx *gorm.DB, now time.Time, variants []uint) error {
+	ids, err := overdueReservations(tx, now, variants)
+	if err != nil {
+		return err
+	}
+
+	if err := lockStock(tx, variants, ids); err != nil {
+		return err
+	}
+
+	return releaseReservations(tx, now, ids, inventory.StatusExpired, releaseStock)
+}
+
+// overdueReservations locks the active reservations after their expiration time with a
+// line of the variants, or all of them without variants, and returns their ids. The
+// reservations locked by other transactions are skipped, as they are being closed.
+func overdueReservations(tx *gorm.DB, now time.Time, variants []uint) ([]uint, error) {
+	query := tx.Model(&Reservation{}).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND expires_at <= ?", inventory.StatusActive, now)
+	if variants != nil {
+		query = query.Where(`id IN (SELECT reservation_id FROM stock_reservation_lines
+			WHERE variant_id IN ?)`, variants)
+	}
+
+	var ids []uint
+	if res := query.Order("id").Pluck("id", &ids); res.Error != nil {
+		return nil, fmt.Errorf("unable to fetch the expired reservations: %w", res.Error)
+	}
+
+	return ids, nil
+}
+
+// removeStock releases the active reservations with a line of the removed variants, and
+// removes their stock, so their units aren't held by reservations that can't be
+// committed anymore. The overdue reservations are expired first.
+func removeStock(tx *gorm.DB, variants []uint) error {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	now := tx.NowFunc()
+	if err := expireOverdue(tx, now, variants); err != nil {
+		return err
+	}
+
+	var ids []uint
+	res := tx.Model(&Reservation{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where(`status = ? AND id IN (SELECT reservation_id FROM stock_reservation_lines
+			WHERE variant_id IN ?)`, inventory.StatusActive, variants).
+		Order("id").Pluck("id", &ids)
+	if res.Error != nil {
+		return fmt.Errorf("unable to fetch the reservations of the variants: %w", res.Error)
+	}
+
+	if err := lockStock(tx, variants, ids); err != nil {
+		return err
+	}
+
+	if err := releaseReservations(tx, now, ids, inventory.StatusReleased,
+		releaseStock); err != nil {
+		return err
+	}
+
+	res = tx.Where("variant_id IN ?", variants).Delete(&Stock{})
+	if res.Error != nil {
+		return fmt.Errorf("unable to delete the stock of the variants: %w", res.Error)
+	}
+
+	return nil
+}
+
+// lockStock locks the stock of the variants and of the lines of the reservations in the
+// order of the variants, so the concurrent changes of the stock lock it in the same
+// order and they don't deadlock.
+func lockStock(tx *gorm.DB, variants, reservations []uint) error {
+	if len(variants) == 0 && len(reservations) == 0 {
+		return nil
+	}
+
+	var locked []uint
+	res := tx.Model(&Stock{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where(`variant_id IN ? OR variant_id IN (SELECT variant_id
+			FROM stock_reservation_lines WHERE reservation_id IN ?)`, variants, reservations).
+		Order("variant_id").Pluck("variant_id", &locked)
+	if res.Error != nil {
+		return fmt.Errorf("unable to lock the stock: %w", res.Error)
+	}
+
+	return nil
+}
+
+// releaseReservations applies the change to the stock of the lines of the reservations,
+// and closes them with the status. The stock must be locked.
+func releaseReservations(tx *gorm.DB, now time.Time, ids []uint, status inventory.Status,
+	change string,
+) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	// The lines of several reservations are added up, as the update only applies one
+	// of the joined rows.
+	res := tx.Exec(`UPDATE inventory_levels il SET `+change+`, updated_at = ?
+		FROM (SELECT variant_id, SUM(quantity) AS quantity FROM stock_reservation_lines
+		WHERE reservation_id IN ? GROUP BY variant_id) l
+		WHERE il.variant_id = l.variant_id`, now, ids)
+	if res.Error != nil {
+		return fmt.Errorf("unable to update the stock of the reservations: %w", res.Error)
+	}
+
+	res = tx.Model(&Reservation{}).Where("id IN ?", ids).
+		Updates(map[string]any{"status": string(status), "updated_at": now})
+	if res.Error != nil {
+		return fmt.Errorf("unable to close the reservations: %w", res.Error)
+	}
+
+	return nil
+}
+
+// orderLines sorts the lines of the reservations by SKU.
+func orderLines(tx *gorm.DB) *gorm.DB {
+	return tx.Order("stock_reservation_lines.sku")
+}
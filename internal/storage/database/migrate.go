@@ -0,0 +1,483 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// migrationLockID is the key of the postgres advisory lock taken while running the
+// migrations, so two processes can't migrate the database at the same time.
+const migrationLockID int64 = 0x6d6967726174 // "migrat"
+
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    checksum CHAR(64) NOT NULL,
+    applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+)`
+
+var (
+	// ErrInvalidMigration is returned when the migration files are not valid.
+	ErrInvalidMigration = errors.New("invalid migration")
+	// ErrChecksumMismatch is returned when an applied migration was modified afterwards.
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+	// ErrUnknownMigration is returned when an applied migration has no migration file.
+	ErrUnknownMigration = errors.New("unknown applied migration")
+	// ErrSchemaOutdated is returned when the pending migrations are not applied.
+	ErrSchemaOutdated = errors.New("database schema outdated")
+)
+
+// migrationFileRegexp matches the migration files, like 001-categories.up.sql.
+var migrationFileRegexp = regexp.MustCompile(`^(\d+)-([\w-]+)\.(up|down)\.sql$`)
+
+// Migration is a versioned change of the database schema, with the queries to apply
+// it (up) and to revert it (down).
+type Migration struct {
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+	Version  int64
+}
+
+// MigrationStatus is the status of a migration in the database.
+type MigrationStatus struct {
+	AppliedAt time.Time
+	Name      string
+	Version   int64
+	Applied   bool
+	// Modified is true when the migration file changed after being applied.
+	Modified bool
+	// Missing is true when the migration is applied but there is no migration file.
+	Missing bool
+}
+
+// appliedMigration is a row of the schema_migrations table.
+type appliedMigration struct {
+	AppliedAt time.Time
+	Name      string
+	Checksum  string
+	Version   int64
+}
+
+// LoadMigrations reads the migration files from the file system. Every migration
+// has an up and a down file named <version>-<name>.up.sql and <version>-<name>.down.sql.
+// The migrations are returned sorted by version.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the migrations: %w", err)
+	}
+
+	migrations := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		parts := migrationFileRegexp.FindStringSubmatch(entry.Name())
+		if parts == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidMigration, entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := migrations[version]
+		if !ok {
+			m = &Migration{Version: version, Name: parts[2]}
+			migrations[version] = m
+		} else if m.Name != parts[2] {
+			return nil, fmt.Errorf("%w: duplicated version %d", ErrInvalidMigration, version)
+		}
+
+		if parts[3] == "up" {
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	res := make([]Migration, 0, len(migrations))
+	for _, m := range migrations {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("%w: migration %d-%s needs an up and a down file",
+				ErrInvalidMigration, m.Version, m.Name)
+		}
+
+		res = append(res, *m)
+	}
+
+	slices.SortFunc(res, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return res, nil
+}
+
+// MigrateUp applies up to steps pending migrations (all of them if steps is zero or
+// negative) and returns the applied ones.
+func (db *Database) MigrateUp(ctx context.Context, migrations []Migration,
+	steps int,
+) ([]Migration, error) {
+	applied := make([]Migration, 0)
+	err := db.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		done, err := checkAppliedMigrations(ctx, conn, migrations)
+		if err != nil {
+			return err
+		}
+
+		for i := range migrations {
+			m := migrations[i]
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+
+			if steps > 0 && len(applied) >= steps {
+				break
+			}
+
+			err := runInTx(ctx, conn, m.Up,
+				"INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)",
+				m.Version, m.Name, m.Checksum)
+			if err != nil {
+				return fmt.Errorf("unable to apply the migration %d-%s: %w", m.Version,
+					m.Name, err)
+			}
+
+			applied = append(applied, m)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// MigrateDown reverts the last steps applied migrations (one if steps is zero or
+// negative) and returns the reverted ones.
+func (db *Database) MigrateDown(ctx context.Context, migrations []Migration,
+	steps int,
+) ([]Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+
+	reverted := make([]Migration, 0)
+	err := db.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		done, err := checkAppliedMigrations(ctx, conn, migrations)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			m := migrations[i]
+			if _, ok := done[m.Version]; !ok {
+				continue
+			}
+
+			err := runInTx(ctx, conn, m.Down,
+				"DELETE FROM schema_migrations WHERE version = $1", m.Version)
+			if err != nil {
+				return fmt.Errorf("unable to revert the migration %d-%s: %w", m.Version,
+					m.Name, err)
+			}
+
+			reverted = append(reverted, m)
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// MigrateBaseline records the migrations up to the given version as applied, with the
+// checksums of their current files, without running them, and returns the recorded ones.
+// It adopts the databases whose schema was created before the migrations, so the operator
+// must check the schema already matches those migrations.
+func (db *Database) MigrateBaseline(ctx context.Context, migrations []Migration,
+	version int64,
+) ([]Migration, error) {
+	if !slices.ContainsFunc(migrations, func(m Migration) bool { return m.Version == version }) {
+		return nil, fmt.Errorf("%w: unknown version %d", ErrInvalidMigration, version)
+	}
+
+	recorded := make([]Migration, 0)
+	err := db.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		if _, err := conn.ExecContext(ctx, createMigrationsTable); err != nil {
+			return fmt.Errorf("unable to create the migrations table: %w", err)
+		}
+
+		for i := range migrations {
+			m := migrations[i]
+			if m.Version > version {
+				break
+			}
+
+			_, err := conn.ExecContext(ctx, `INSERT INTO schema_migrations
+				(version, name, checksum) VALUES ($1, $2, $3) ON CONFLICT (version)
+				DO UPDATE SET name = EXCLUDED.name, checksum = EXCLUDED.checksum`,
+				m.Version, m.Name, m.Checksum)
+			if err != nil {
+				return fmt.Errorf("unable to record the migration %d-%s: %w", m.Version,
+					m.Name, err)
+			}
+
+			recorded = append(recorded, m)
+		}
+
+		return nil
+	})
+
+	return recorded, err
+}
+
+// Seed runs the sql files of the file system, sorted by name, each one in a transaction,
+// and returns their names. They load optional data, like the sample products, so they
+// aren't versioned like the migrations, and they must be safe to run several times.
+func (db *Database) Seed(ctx context.Context, fsys fs.FS) ([]string, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the seed files: %w", err)
+	}
+
+	seeded := make([]string, 0, len(names))
+	err = db.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		for _, name := range names {
+			content, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return fmt.Errorf("unable to read the seed file %s: %w", name, err)
+			}
+
+			if err := runInTx(ctx, conn, string(content), ""); err != nil {
+				return fmt.Errorf("unable to run the seed file %s: %w", name, err)
+			}
+
+			seeded = append(seeded, name)
+		}
+
+		return nil
+	})
+
+	return seeded, err
+}
+
+// MigrationsStatus returns the status of all the migrations, including the applied
+// migrations without migration file.
+func (db *Database) MigrationsStatus(ctx context.Context,
+	migrations []Migration,
+) ([]MigrationStatus, error) {
+	sqlDB := db.conn()
+	if sqlDB == nil {
+		return nil, errNotConnected
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get a database connection: %w", err)
+	}
+
+	defer conn.Close()
+
+	done, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(migrations))
+	for i := range migrations {
+		m := migrations[i]
+		st := MigrationStatus{Version: m.Version, Name: m.Name}
+		if a, ok := done[m.Version]; ok {
+			st.Applied = true
+			st.AppliedAt = a.AppliedAt
+			st.Modified = a.Checksum != m.Checksum
+			delete(done, m.Version)
+		}
+
+		status = append(status, st)
+	}
+
+	for _, a := range done {
+		status = append(status, MigrationStatus{
+			Version:   a.Version,
+			Name:      a.Name,
+			AppliedAt: a.AppliedAt,
+			Applied:   true,
+			Missing:   true,
+		})
+	}
+
+	slices.SortFunc(status, func(a, b MigrationStatus) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return status, nil
+}
+
+// SchemaVersion returns the version of the last applied migration, or zero if there
+// are no migrations applied.
+func (db *Database) SchemaVersion(ctx context.Context) (int64, error) {
+	conn := db.conn()
+	if conn == nil {
+		return 0, errNotConnected
+	}
+
+	var version sql.NullInt64
+	err := conn.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_migrations").
+		Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("unable to get the schema version: %w", err)
+	}
+
+	return version.Int64, nil
+}
+
+// CheckSchemaVersion checks the last applied migration is, at least, the given version.
+func (db *Database) CheckSchemaVersion(ctx context.Context, version int64) error {
+	current, err := db.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if current < version {
+		return fmt.Errorf("%w: version %d, expected %d", ErrSchemaOutdated, current, version)
+	}
+
+	return nil
+}
+
+// withMigrationLock runs the function holding the migrations advisory lock. The lock
+// belongs to the database session, so all the queries are run in the same connection.
+func (db *Database) withMigrationLock(ctx context.Context,
+	fn func(conn *sql.Conn) error,
+) error {
+	sqlDB := db.conn()
+	if sqlDB == nil {
+		return errNotConnected
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get a database connection: %w", err)
+	}
+
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID)
+	if err != nil {
+		return fmt.Errorf("unable to lock the migrations: %w", err)
+	}
+
+	defer func() {
+		// The lock is released with the session anyway, so we use a new context in case
+		// the original one is already cancelled.
+		_, _ = conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)",
+			migrationLockID)
+	}()
+
+	return fn(conn)
+}
+
+// checkAppliedMigrations returns the applied migrations, checking all of them have a
+// migration file that was not modified after being applied.
+func checkAppliedMigrations(ctx context.Context, conn *sql.Conn,
+	migrations []Migration,
+) (map[int64]appliedMigration, error) {
+	done, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	for version, a := range done {
+		idx := slices.IndexFunc(migrations, func(m Migration) bool {
+			return m.Version == version
+		})
+		if idx < 0 {
+			return nil, fmt.Errorf("%w: %d-%s", ErrUnknownMigration, a.Version, a.Name)
+		}
+
+		if migrations[idx].Checksum != a.Checksum {
+			return nil, fmt.Errorf("%w: %d-%s", ErrChecksumMismatch, a.Version, a.Name)
+		}
+	}
+
+	return done, nil
+}
+
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+	if _, err := conn.ExecContext(ctx, createMigrationsTable); err != nil {
+		return nil, fmt.Errorf("unable to create the migrations table: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx,
+		"SELECT version, name, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch the applied migrations: %w", err)
+	}
+
+	defer rows.Close()
+
+	done := make(map[int64]appliedMigration)
+	for rows.Next() {
+		var a appliedMigration
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt); err != nil {
+			return nil, fmt.Errorf("unable to read the applied migrations: %w", err)
+		}
+
+		done[a.Version] = a
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read the applied migrations: %w", err)
+	}
+
+	return done, nil
+}
+
+// runInTx runs the migration query and, if it's not empty, the query to update the
+// migrations table in the same transaction.
+func runInTx(ctx context.Context, conn *sql.Conn, migration, query string,
+	args ...any,
+) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, migration); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	if query != "" {
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			_ = tx.Rollback()
+
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"002-products.up.sql":     {Data: []byte("CREATE TABLE products ();")},
+		"002-products.down.sql":   {Data: []byte("DROP TABLE products;")},
+		"001-categories.up.sql":   {Data: []byte("CREATE TABLE category ();")},
+		"001-categories.down.sql": {Data: []byte("DROP TABLE category;")},
+		"README.md":               {Data: []byte("not a migration")},
+	}
+
+	migrations, err := LoadMigrations(fsys)
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "categories", migrations[0].Name)
+	assert.Equal(t, "CREATE TABLE category ();", migrations[0].Up)
+	assert.Equal(t, "DROP TABLE category;", migrations[0].Down)
+	assert.Len(t, migrations[0].Checksum, 64)
+	assert.Equal(t, int64(2), migrations[1].Version)
+	assert.NotEqual(t, migrations[0].Checksum, migrations[1].Checksum)
+}
+
+func TestLoadMigrationsErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		fsys fstest.MapFS
+		name string
+	}{
+		{
+			name: "missing down",
+			fsys: fstest.MapFS{
+				"001-categories.up.sql": {Data: []byte("CREATE TABLE category ();")},
+			},
+		},
+		{
+			name: "duplicated version",
+			fsys: fstest.MapFS{
+				"001-categories.up.sql":   {Data: []byte("CREATE TABLE category ();")},
+				"001-categories.down.sql": {Data: []byte("DROP TABLE category;")},
+				"001-products.up.sql":     {Data: []byte("CREATE TABLE products ();")},
+				"001-products.down.sql":   {Data: []byte("DROP TABLE products;")},
+			},
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := LoadMigrations(tc.fsys)
+			require.ErrorIs(t, err, ErrInvalidMigration)
+		})
+	}
+}
+
+func TestLoadSQLMigrations(t *testing.T) {
+	t.Parallel()
+	migrations, err := LoadMigrations(os.DirFS(filepath.Join("..", "..", "..", "sql")))
+	require.NoError(t, err)
+	assert.NotEmpty(t, migrations)
+
+	// The sample data is loaded by the seed step, not by the migrations.
+	seeds, err := fs.Glob(os.DirFS(filepath.Join("..", "..", "..", "sql", "seed")), "*.sql")
+	require.NoError(t, err)
+	assert.NotEmpty(t, seeds)
+}
@@ -1,22 +1,43 @@
 package database
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
 	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
 
 	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/category"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/inventory"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/price"
 	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/product"
 	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/variant"
 )
 
 // Product represents a product in the catalog.
-// It includes a unique code and a price.
+// It includes a unique code, a name, a description, the attributes, a price with its
+// currency and the scheduled prices replacing it. The removed products are kept with
+// their removal time, and they are ignored by the queries. The version is increased on
+// every change of the product, its variants, its scheduled prices or its category.
 type Product struct {
-	Code       string          `gorm:"uniqueIndex;not null"`
-	Price      decimal.Decimal `gorm:"type:decimal(10,2);not null"`
-	Category   Category        `gorm:"foreignKey:CategoryID"`
-	Variants   Variants        `gorm:"foreignKey:ProductID"`
-	CategoryID uint            `gorm:"column:category"`
-	ID         uint            `gorm:"primaryKey"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt  `gorm:"index"`
+	Code        string          `gorm:"uniqueIndex;not null"`
+	Name        string          `gorm:"not null;default:''"`
+	Description string          `gorm:"not null;default:''"`
+	Attributes  Attributes      `gorm:"type:jsonb;not null;default:'{}'"`
+	Price       decimal.Decimal `gorm:"type:decimal(12,4);not null"`
+	Currency    string          `gorm:"type:char(3);not null;default:'EUR'"`
+	Category    Category        `gorm:"foreignKey:CategoryID"`
+	Variants    Variants        `gorm:"foreignKey:ProductID"`
+	Prices      Prices          `gorm:"foreignKey:ProductID"`
+	CategoryID  uint            `gorm:"column:category"`
+	ID          uint            `gorm:"primaryKey"`
+	Version     uint            `gorm:"not null;default:1"`
 }
 
 // TableName returns the table name for the Products.
@@ -30,16 +51,77 @@ func (p *Product) toModel() *product.Product {
 	}
 
 	return &product.Product{
-		Code:     p.Code,
-		Price:    p.Price,
-		Variants: p.Variants.toModel(),
-		ID:       p.ID,
-		Category: p.Category.toModel(),
+		Code:        p.Code,
+		Name:        p.Name,
+		Description: p.Description,
+		Attributes:  p.Attributes,
+		Price:       p.Price,
+		Currency:    p.Currency,
+		Variants:    p.Variants.toModel(),
+		Prices:      p.Prices.toModel(),
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+		ID:          p.ID,
+		Version:     p.Version,
+		Category:    p.Category.toModel(),
 	}
 }
 
 type Products []Product
 
+// Attributes of a product, stored as a jsonb object.
+type Attributes map[string]string
+
+// Value returns the json object of the attributes.
+func (a Attributes) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+
+	b, err := json.Marshal(a)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode the attributes: %w", err)
+	}
+
+	return string(b), nil
+}
+
+// Scan reads the attributes from their json object.
+func (a *Attributes) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*a = nil
+
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unable to decode the attributes: unexpected type %T", src)
+	}
+
+	var attrs map[string]string
+	if err := json.Unmarshal(data, &attrs); err != nil {
+		return fmt.Errorf("unable to decode the attributes: %w", err)
+	}
+
+	*a = attrs
+
+	return nil
+}
+
+// categories returns the categories of the products.
+func (p Products) categories() []*Category {
+	res := make([]*Category, 0, len(p))
+	for i := range p {
+		res = append(res, &p[i].Category)
+	}
+
+	return res
+}
+
 func (p Products) toModel() []product.Product {
 	res := make([]product.Product, 0, len(p))
 	for i := range p {
@@ -53,14 +135,32 @@ func (p Products) toModel() []product.Product {
 }
 
 // Variant represents a product variant in the catalog.
-// It includes a unique name, SKU, and an optional price.
+// It includes a unique name, SKU, and an optional price with its currency.
 // Variants can be used to represent different configurations or options for a product.
+// The variants without price inherit the price of the product.
 type Variant struct {
-	Name      string          `gorm:"not null"`
-	SKU       string          `gorm:"uniqueIndex;not null"`
-	Price     decimal.Decimal `gorm:"type:decimal(10,2);null"`
-	ID        uint            `gorm:"primaryKey"`
-	ProductID uint            `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt      `gorm:"index"`
+	Name      string              `gorm:"not null"`
+	SKU       string              `gorm:"uniqueIndex;not null"`
+	Price     decimal.NullDecimal `gorm:"type:decimal(12,4);null"`
+	Currency  sql.NullString      `gorm:"type:char(3);null"`
+	ID        uint                `gorm:"primaryKey"`
+	ProductID uint                `gorm:"not null"`
+	Version   uint                `gorm:"not null;default:1"`
+}
+
+func newVariant(v *variant.Variant) Variant {
+	return Variant{
+		Name:      v.Name,
+		SKU:       v.SKU,
+		Price:     decimal.NullDecimal{Decimal: v.Price, Valid: !v.Price.IsZero()},
+		Currency:  sql.NullString{String: v.Currency, Valid: v.Currency != ""},
+		ID:        v.ID,
+		ProductID: v.ProductID,
+		Version:   v.Version,
+	}
 }
 
 // TableName returns the database table name for the Variants.
@@ -76,9 +176,13 @@ func (v *Variant) toModel() *variant.Variant {
 	return &variant.Variant{
 		Name:      v.Name,
 		SKU:       v.SKU,
-		Price:     v.Price,
+		Price:     v.Price.Decimal,
+		Currency:  v.Currency.String,
+		CreatedAt: v.CreatedAt,
+		UpdatedAt: v.UpdatedAt,
 		ID:        v.ID,
 		ProductID: v.ProductID,
+		Version:   v.Version,
 	}
 }
 
@@ -96,11 +200,180 @@ func (v Variants) toModel() []variant.Variant {
 	return res
 }
 
+// Price is a scheduled price of a product, or of one of its variants if it has a SKU.
+// The prices can't be modified, and they are removed for good.
+type Price struct {
+	CreatedAt     time.Time
+	EffectiveFrom time.Time           `gorm:"not null"`
+	EffectiveTo   sql.NullTime        `gorm:"null"`
+	SKU           sql.NullString      `gorm:"type:varchar(32);null"`
+	Amount        decimal.Decimal     `gorm:"type:decimal(12,4);not null"`
+	CompareAt     decimal.NullDecimal `gorm:"type:decimal(12,4);null"`
+	Currency      string              `gorm:"type:char(3);not null"`
+	ID            uint                `gorm:"primaryKey"`
+	ProductID     uint                `gorm:"not null"`
+}
+
+func newPrice(p *price.Price, productID uint) Price {
+	return Price{
+		EffectiveFrom: p.EffectiveFrom,
+		EffectiveTo:   sql.NullTime{Time: p.EffectiveTo, Valid: !p.EffectiveTo.IsZero()},
+		SKU:           sql.NullString{String: p.SKU, Valid: p.SKU != ""},
+		Amount:        p.Amount,
+		CompareAt:     decimal.NullDecimal{Decimal: p.CompareAt, Valid: !p.CompareAt.IsZero()},
+		Currency:      p.Currency,
+		ProductID:     productID,
+	}
+}
+
+// TableName returns the database table name for the Prices.
+func (p *Price) TableName() string {
+	return "product_prices"
+}
+
+// toModel returns the model price, with its validity in UTC as the prices are created.
+func (p *Price) toModel() price.Price {
+	res := price.Price{
+		EffectiveFrom: p.EffectiveFrom.UTC(),
+		CreatedAt:     p.CreatedAt,
+		SKU:           p.SKU.String,
+		Amount:        p.Amount,
+		CompareAt:     p.CompareAt.Decimal,
+		Currency:      p.Currency,
+		ID:            p.ID,
+	}
+	if p.EffectiveTo.Valid {
+		res.EffectiveTo = p.EffectiveTo.Time.UTC()
+	}
+
+	return res
+}
+
+type Prices []Price
+
+func (p Prices) toModel() price.Prices {
+	res := make(price.Prices, 0, len(p))
+	for i := range p {
+		res = append(res, p[i].toModel())
+	}
+
+	return res
+}
+
+// Stock of a variant, with the units on hand and the units reserved by the active
+// reservations. The variants without stock don't have a row.
+type Stock struct {
+	UpdatedAt time.Time
+	VariantID uint  `gorm:"primaryKey;autoIncrement:false"`
+	OnHand    int64 `gorm:"not null;default:0"`
+	Reserved  int64 `gorm:"not null;default:0"`
+}
+
+// TableName returns the database table name for the Stocks.
+func (s *Stock) TableName() string {
+	return "inventory_levels"
+}
+
+// stockRow is the stock of a variant joined with the variant, with zero units if it
+// doesn't have stock.
+type stockRow struct {
+	UpdatedAt time.Time
+	SKU       string
+	OnHand    int64
+	Reserved  int64
+	VariantID uint
+}
+
+func (s *stockRow) toModel() inventory.Stock {
+	return inventory.Stock{
+		UpdatedAt: s.UpdatedAt,
+		SKU:       s.SKU,
+		OnHand:    s.OnHand,
+		Reserved:  s.Reserved,
+	}
+}
+
+// Reservation of the units of several variants.
+type Reservation struct {
+	ExpiresAt time.Time `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Status    string            `gorm:"type:varchar(16);not null;default:'active'"`
+	Lines     []ReservationLine `gorm:"foreignKey:ReservationID"`
+	ID        uint              `gorm:"primaryKey"`
+}
+
+func newReservation(r *inventory.Reservation) Reservation {
+	lines := make([]ReservationLine, 0, len(r.Lines))
+	for _, l := range r.Lines {
+		lines = append(lines, ReservationLine{
+			SKU:       l.SKU,
+			Quantity:  l.Quantity,
+			VariantID: l.VariantID,
+		})
+	}
+
+	return Reservation{
+		ExpiresAt: r.ExpiresAt,
+		Status:    string(inventory.StatusActive),
+		Lines:     lines,
+	}
+}
+
+// TableName returns the database table name for the Reservations.
+func (r *Reservation) TableName() string {
+	return "stock_reservations"
+}
+
+// toModel returns the model reservation, with its expiration time in UTC as the
+// reservations are created.
+func (r *Reservation) toModel() inventory.Reservation {
+	lines := make([]inventory.Line, 0, len(r.Lines))
+	for _, l := range r.Lines {
+		lines = append(lines, inventory.Line{
+			SKU:       l.SKU,
+			Quantity:  l.Quantity,
+			VariantID: l.VariantID,
+		})
+	}
+
+	return inventory.Reservation{
+		ExpiresAt: r.ExpiresAt.UTC(),
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+		Status:    inventory.Status(r.Status),
+		Lines:     lines,
+		ID:        r.ID,
+	}
+}
+
+// ReservationLine has the units of a variant reserved by a reservation. The SKU is kept,
+// so the line can be shown after the variant is removed.
+type ReservationLine struct {
+	SKU           string `gorm:"type:varchar(32);not null"`
+	Quantity      int64  `gorm:"not null"`
+	ReservationID uint   `gorm:"primaryKey;autoIncrement:false"`
+	VariantID     uint   `gorm:"primaryKey;autoIncrement:false"`
+}
+
+// TableName returns the database table name for the ReservationLines.
+func (l *ReservationLine) TableName() string {
+	return "stock_reservation_lines"
+}
+
 // Category of a product.
 type Category struct {
-	Code string `gorm:"uniqueIndex;not null"`
-	Name string `gorm:"not null"`
-	ID   uint64 `gorm:"primaryKey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	Code      string         `gorm:"uniqueIndex;not null"`
+	Name      string         `gorm:"not null"`
+	// ParentCode and Ancestors are read from the parent categories when they are needed.
+	ParentCode string     `gorm:"-"`
+	Ancestors  Categories `gorm:"-"`
+	ParentID   *uint64
+	ID         uint64 `gorm:"primaryKey"`
+	Version    uint   `gorm:"not null;default:1"`
 }
 
 func (c *Category) TableName() string {
@@ -112,11 +385,20 @@ func (c *Category) toModel() *category.Category {
 		return nil
 	}
 
-	return &category.Category{
-		ID:   c.ID,
-		Code: c.Code,
-		Name: c.Name,
+	cat := &category.Category{
+		CreatedAt:  c.CreatedAt,
+		UpdatedAt:  c.UpdatedAt,
+		ID:         c.ID,
+		Code:       c.Code,
+		Name:       c.Name,
+		ParentCode: c.ParentCode,
+		Version:    c.Version,
 	}
+	if len(c.Ancestors) > 0 {
+		cat.Ancestors = c.Ancestors.toModel()
+	}
+
+	return cat
 }
 
 type Categories []Category
@@ -0,0 +1,175 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/currency"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/price"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/product"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/variant"
+)
+
+// activePriceFrom selects the scheduled price of the product valid now. When several
+// prices are valid, the one starting later is used, and on the same start the one
+// created later.
+const activePriceFrom = ` FROM product_prices pp
+	WHERE pp.product_id = products.id AND pp.sku IS NULL AND pp.effective_from <= NOW()
+	AND (pp.effective_to IS NULL OR pp.effective_to > NOW())
+	ORDER BY pp.effective_from DESC, pp.id DESC LIMIT 1`
+
+const (
+	// onSaleQuery selects if the price of the product valid now is a sale.
+	onSaleQuery = `COALESCE((SELECT pp.compare_at IS NOT NULL` + activePriceFrom + `), FALSE)`
+	// activePriceQuery selects the amount of the price of the product valid now, its
+	// scheduled price or otherwise its base price, and activeCurrencyQuery its currency.
+	activePriceQuery    = `COALESCE((SELECT pp.amount` + activePriceFrom + `), products.price)`
+	activeCurrencyQuery = `COALESCE((SELECT pp.currency` + activePriceFrom +
+		`), products.currency)`
+)
+
+// SetRates sets the source of the exchange rates used to compare the prices in different
+// currencies. Without rates, only the prices in the same currency can be compared.
+func (db *Database) SetRates(rates func() *currency.Rates) {
+	db.rates = rates
+}
+
+// currentRates returns the last exchange rates of the source, or nil without source.
+func (db *Database) currentRates() *currency.Rates {
+	if db.rates == nil {
+		return nil
+	}
+
+	return db.rates()
+}
+
+// convertedPriceQuery selects the amount of the price of the product valid now converted
+// to the currency, rounded like the converted prices of the responses. The prices in a
+// currency without rate are null, so the rates are checked with checkRates before. The
+// currencies are valid codes and the rates decimals, so they are safe to add to the
+// query.
+func convertedPriceQuery(rates *currency.Rates, to string) string {
+	toRate, ok := rates.Rate(to)
+	if !ok {
+		return fmt.Sprintf("(CASE %s WHEN '%s' THEN %s END)", activeCurrencyQuery, to,
+			activePriceQuery)
+	}
+
+	var fromRates strings.Builder
+	for _, from := range rates.Currencies() {
+		fromRate, _ := rates.Rate(from)
+		fmt.Fprintf(&fromRates, " WHEN '%s' THEN %s", from, fromRate)
+	}
+
+	// The amount is multiplied before dividing, as in the conversion of the prices.
+	return fmt.Sprintf("ROUND(%s * %s / (CASE %s%s END), %d)", activePriceQuery, toRate,
+		activeCurrencyQuery, fromRates.String(), currency.Decimals(to))
+}
+
+// checkRates checks the prices of all the products can be converted to the currencies of
+// the converted price fields, so the filters and the sorts don't leave out any product.
+// It returns currency.ErrUnknownRate otherwise.
+func checkRates(tx *gorm.DB, rates *currency.Rates, fields ...string) error {
+	targets := make([]string, 0)
+	for _, field := range fields {
+		if code, ok := product.PriceCurrency(field); ok {
+			targets = append(targets, code)
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil
+	}
+
+	var currencies []string
+	res := tx.Model(&Product{}).Distinct().Pluck(activeCurrencyQuery, &currencies)
+	if res.Error != nil {
+		return fmt.Errorf("unable to fetch the currencies of the prices: %w", res.Error)
+	}
+
+	for _, to := range targets {
+		for _, from := range currencies {
+			if _, err := rates.Convert(decimal.Zero, from, to); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// CreatePrice adds a scheduled price to the product with the given code, or to its
+// variant if the price has a SKU. A price without currency gets the currency of the
+// product. The price is updated with the stored data.
+func (db *Database) CreatePrice(ctx context.Context, productCode string, p *price.Price) error {
+	prod, err := db.productByCode(ctx, productCode)
+	if err != nil {
+		return err
+	}
+
+	p.SetDefaultCurrency(prod.Currency)
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	rec := newPrice(p, prod.ID)
+	err = db.transaction(ctx, func(tx *gorm.DB) error {
+		if p.SKU != "" {
+			// The variant is locked, so it isn't removed before its price is created.
+			var v Variant
+			res := tx.Clauses(clause.Locking{Strength: "SHARE"}).
+				Where("product_id = ? AND sku = ?", prod.ID, p.SKU).Limit(1).Find(&v)
+			if res.Error != nil {
+				return fmt.Errorf("unable to fetch the variant %s: %w", p.SKU, res.Error)
+			}
+
+			if res.RowsAffected == 0 {
+				return variant.ErrNotFound
+			}
+		}
+
+		if res := tx.Create(&rec); res.Error != nil {
+			return fmt.Errorf("unable to create the price of %s: %w", productCode, res.Error)
+		}
+
+		return touchProduct(tx, prod.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	*p = rec.toModel()
+
+	return nil
+}
+
+// DeletePrice removes the scheduled price with the given id from the product.
+func (db *Database) DeletePrice(ctx context.Context, productCode string, id uint) error {
+	prod, err := db.productByCode(ctx, productCode)
+	if err != nil {
+		return err
+	}
+
+	return db.transaction(ctx, func(tx *gorm.DB) error {
+		res := tx.Where("product_id = ?", prod.ID).Delete(&Price{}, id)
+		if res.Error != nil {
+			return fmt.Errorf("unable to delete the price %d: %w", id, res.Error)
+		}
+
+		if res.RowsAffected == 0 {
+			return price.ErrNotFound
+		}
+
+		return touchProduct(tx, prod.ID)
+	})
+}
+
+// orderPrices sorts the preloaded prices by id, so they keep the creation order.
+func orderPrices(tx *gorm.DB) *gorm.DB {
+	return tx.Order("product_prices.id")
+}
@@ -1,71 +1,154 @@
 package database
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"slices"
 
+	"gorm.io/gorm"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/category"
 	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/filter"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/page"
 	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/product"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/sorting"
 )
 
 // GetAllProducts returns the list of all products stored in the database.
-func (db *Database) GetAllProducts() ([]product.Product, error) {
+func (db *Database) GetAllProducts(ctx context.Context) ([]product.Product, error) {
 	products := make(Products, 0)
-	res := db.session.Preload("Variants").Preload("Category").Find(&products)
-	if res.Error != nil {
-		return nil, fmt.Errorf("unable to fetch the products: %w", res.Error)
+	err := db.read(ctx, func(tx *gorm.DB) error {
+		products = products[:0]
+		res := tx.Preload("Variants", orderVariants).Preload("Prices", orderPrices).
+			Preload("Category").Find(&products)
+		if res.Error != nil {
+			return fmt.Errorf("unable to fetch the products: %w", res.Error)
+		}
+
+		return loadAncestors(tx, products.categories()...)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return products.toModel(), nil
 }
 
-// GetProducts returns the list of products stored in the database and filtered by the
-// limit, offset and other filters.
-func (db *Database) GetProducts(limit, offset int,
+// GetProducts returns the page of products stored in the database filtered by the
+// filters and sorted by the sorts, with the total number of products matching the
+// filters. The products are always sorted by id after the given sorts.
+func (db *Database) GetProducts(ctx context.Context, pg page.Page, sorts []sorting.Sort,
 	filters ...filter.Filter,
-) ([]product.Product, error) {
-	clause := ""
-	conditions := make([]any, 0)
-	for i := range filters {
-		filter := filters[i]
-		if i > 0 {
-			clause += " AND "
-		}
+) ([]product.Product, int64, error) {
+	rates := db.currentRates()
+	clause, values, err := filterClause(filters, rates)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to fetch the products: %w", err)
+	}
 
-		clause += fmt.Sprintf("%s %s ?", filter.Key, filter.Operation)
-		conditions = append(conditions, filter.Value)
+	columns, err := productSortColumns(sorts, rates)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to fetch the products: %w", err)
 	}
 
-	if len(conditions) > 0 {
-		conditions = append([]any{clause}, conditions...)
-	} else if len(clause) > 0 {
-		conditions = []any{clause}
+	backward := pg.Cursor != nil && pg.Cursor.Backward
+	var keyset string
+	var keysetValues []any
+	if pg.Cursor != nil {
+		keyset, keysetValues, err = keysetClause(columns, pg.Cursor)
+		if err != nil {
+			return nil, 0, fmt.Errorf("unable to fetch the products: %w", err)
+		}
 	}
 
+	var total int64
 	products := make(Products, 0)
-	res := db.session.Preload("Variants").Preload("Category").Limit(limit).Offset(offset)
-	res = res.Find(&products, conditions...)
-	if res.Error != nil {
-		return nil, fmt.Errorf("unable to fetch the products: %w", res.Error)
+	// The count and the page are read from the same database, so they are consistent.
+	err = db.read(ctx, func(tx *gorm.DB) error {
+		total = 0
+		products = products[:0]
+		fields := filter.Keys(filters...)
+		for i := range sorts {
+			fields = append(fields, sorts[i].Field)
+		}
+
+		if err := checkRates(tx, rates, fields...); err != nil {
+			return err
+		}
+
+		res := tx.Model(&Product{})
+		if clause != "" {
+			res = res.Where(clause, values...)
+		}
+
+		if res = res.Count(&total); res.Error != nil {
+			return fmt.Errorf("unable to count the products: %w", res.Error)
+		}
+
+		res = tx.Preload("Variants", orderVariants).Preload("Prices", orderPrices).
+			Preload("Category")
+		if clause != "" {
+			res = res.Where(clause, values...)
+		}
+
+		if pg.Cursor == nil {
+			res = res.Offset(pg.Offset)
+		} else {
+			res = res.Where(keyset, keysetValues...)
+		}
+
+		// We fetch the products before a backward cursor in reverse order to get the
+		// closest ones, and then we restore the order.
+		res = res.Order(orderClause(columns, backward)).Limit(pg.Limit).Find(&products)
+		if res.Error != nil {
+			return fmt.Errorf("unable to fetch the products: %w", res.Error)
+		}
+
+		return loadAncestors(tx, products.categories()...)
+	})
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return products.toModel(), nil
+	if backward {
+		slices.Reverse(products)
+	}
+
+	return products.toModel(), total, nil
 }
 
-func (db *Database) GetProduct(productCode string) (*product.Product, error) {
+// GetProduct returns a product stored in the database by its code.
+func (db *Database) GetProduct(ctx context.Context,
+	productCode string,
+) (*product.Product, error) {
 	if db == nil {
 		return nil, errors.New("no connection to the storage available")
 	}
 
-	if db.session == nil {
-		if err := db.Connect(); err != nil {
+	if db.primary.Load() == nil {
+		if err := db.Connect(ctx); err != nil {
 			return nil, err
 		}
 	}
 
+	var prod *product.Product
+	err := db.read(ctx, func(tx *gorm.DB) error {
+		var err error
+		prod, err = getProduct(tx, productCode)
+
+		return err
+	})
+
+	return prod, err
+}
+
+// getProduct returns the product with the given code, with its category, variants and
+// scheduled prices.
+func getProduct(tx *gorm.DB, productCode string) (*product.Product, error) {
 	var prod Product
-	res := db.session.Preload("Variants").Preload("Category").Find(&prod,
-		map[string]any{"code": productCode})
+	res := tx.Preload("Variants", orderVariants).Preload("Prices", orderPrices).
+		Preload("Category").Find(&prod, map[string]any{"code": productCode})
 	if res.Error != nil {
 		return nil, fmt.Errorf("unable to fetch the product %s: %w", productCode, res.Error)
 	}
@@ -74,5 +157,170 @@ func (db *Database) GetProduct(productCode string) (*product.Product, error) {
 		return nil, product.ErrNotFound
 	}
 
+	if err := loadAncestors(tx, &prod.Category); err != nil {
+		return nil, err
+	}
+
 	return prod.toModel(), nil
 }
+
+// CreateProduct adds a new product, with its variants, to the database. The category
+// is looked up by its code. The prices without currency get the default one.
+func (db *Database) CreateProduct(ctx context.Context, prod *product.Product) error {
+	prod.SetDefaultCurrency()
+	if err := prod.Validate(); err != nil {
+		return err
+	}
+
+	session, err := db.session(ctx)
+	if err != nil {
+		return err
+	}
+
+	cat, err := categoryByCode(session, prod.Category.Code)
+	if err != nil {
+		return fmt.Errorf("unable to create the product %s: %w", prod.Code, err)
+	}
+
+	if err := loadAncestors(session, cat); err != nil {
+		return fmt.Errorf("unable to create the product %s: %w", prod.Code, err)
+	}
+
+	p := Product{
+		Code:        prod.Code,
+		Name:        prod.Name,
+		Description: prod.Description,
+		Attributes:  prod.Attributes,
+		Price:       prod.Price,
+		Currency:    prod.Currency,
+		CategoryID:  uint(cat.ID),
+		Variants:    make(Variants, 0, len(prod.Variants)),
+	}
+	for i := range prod.Variants {
+		p.Variants = append(p.Variants, newVariant(&prod.Variants[i]))
+	}
+
+	// The variants are created in the same transaction as the product.
+	res := session.Omit("Category").Create(&p)
+	if res.Error != nil {
+		return fmt.Errorf("unable to create the product %s: %w", prod.Code,
+			translateError(res.Error))
+	}
+
+	p.Category = *cat
+	*prod = *p.toModel()
+
+	return nil
+}
+
+// UpdateProduct updates the name, the description, the attributes, the price with its
+// currency and the category of the product with the same code. If the product has a
+// version, it's only updated if the stored one has the same version. The product is
+// updated with the stored data.
+func (db *Database) UpdateProduct(ctx context.Context, prod *product.Product) error {
+	prod.SetDefaultCurrency()
+	if err := prod.Validate(); err != nil {
+		return err
+	}
+
+	// The category, the update and the read back are done in a single transaction, so
+	// the product returned is the updated one.
+	var updated *product.Product
+	err := db.transaction(ctx, func(tx *gorm.DB) error {
+		cat, err := categoryByCode(tx, prod.Category.Code)
+		if err != nil {
+			return fmt.Errorf("unable to update the product %s: %w", prod.Code, err)
+		}
+
+		query := tx.Model(&Product{}).Where("code = ?", prod.Code)
+		res := whereVersion(query, prod.Version).Updates(map[string]any{
+			"name":        prod.Name,
+			"description": prod.Description,
+			"attributes":  Attributes(prod.Attributes),
+			"price":       prod.Price,
+			"currency":    prod.Currency,
+			"category":    cat.ID,
+			"version":     nextVersion,
+		})
+		if res.Error != nil {
+			return fmt.Errorf("unable to update the product %s: %w", prod.Code,
+				translateError(res.Error))
+		}
+
+		if res.RowsAffected == 0 {
+			return missingOrStale(tx, &Product{}, map[string]any{"code": prod.Code},
+				product.ErrNotFound, product.ErrVersionMismatch)
+		}
+
+		// The product is read from the primary database, as the replicas may lag behind.
+		updated, err = getProduct(tx, prod.Code)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	*prod = *updated
+
+	return nil
+}
+
+// DeleteProduct removes a product, with its variants, from the database. They are kept
+// with their removal time (soft delete), so the code and the SKUs can be reused, and the
+// stock of the variants is removed, releasing their active reservations. If the version
+// isn't zero, the product is only removed if it has the same version.
+func (db *Database) DeleteProduct(ctx context.Context, productCode string,
+	version uint,
+) error {
+	return db.transaction(ctx, func(tx *gorm.DB) error {
+		var prod Product
+		res := tx.Find(&prod, map[string]any{"code": productCode})
+		if res.Error != nil {
+			return fmt.Errorf("unable to fetch the product %s: %w", productCode, res.Error)
+		}
+
+		if res.RowsAffected == 0 {
+			return product.ErrNotFound
+		}
+
+		// The product may be changed or removed concurrently after being fetched.
+		res = whereVersion(tx, version).Delete(&prod)
+		if res.Error != nil {
+			return fmt.Errorf("unable to delete the product %s: %w", productCode, res.Error)
+		}
+
+		if res.RowsAffected == 0 {
+			return missingOrStale(tx, &Product{}, map[string]any{"code": productCode},
+				product.ErrNotFound, product.ErrVersionMismatch)
+		}
+
+		var variants []uint
+		res = tx.Model(&Variant{}).Where("product_id = ?", prod.ID).Pluck("id", &variants)
+		if res.Error != nil {
+			return fmt.Errorf("unable to fetch the variants of the product %s: %w",
+				productCode, res.Error)
+		}
+
+		res = tx.Where("product_id = ?", prod.ID).Delete(&Variant{})
+		if res.Error != nil {
+			return fmt.Errorf("unable to delete the variants of the product %s: %w",
+				productCode, res.Error)
+		}
+
+		return removeStock(tx, variants)
+	})
+}
+
+// translateError returns the model error of the database errors caused by the data. A
+// duplicated key on a product creation can be either its code or a variant SKU.
+func translateError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return product.ErrDuplicated
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return category.ErrInvalidCategory
+	default:
+		return err
+	}
+}
@@ -1,13 +1,20 @@
 package database
 
+import "context"
+
 type ExecResult struct {
 	Error        error
 	RowsAffected int64
 }
 
 // Exec a query in the database.
-func (db *Database) Exec(query string, values ...any) *ExecResult {
-	result := db.session.Exec(query, values...)
+func (db *Database) Exec(ctx context.Context, query string, values ...any) *ExecResult {
+	session, err := db.session(ctx)
+	if err != nil {
+		return &ExecResult{Error: err}
+	}
+
+	result := session.Exec(query, values...)
 
 	return &ExecResult{
 		RowsAffected: result.RowsAffected,
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// replica is a read replica of the database.
+type replica struct {
+	session *gorm.DB
+	conn    *sql.DB
+	// failedAt is the time, in unix nanoseconds, of the last connection failure. It's
+	// zero if the replica is healthy.
+	failedAt atomic.Int64
+}
+
+// markFailed marks the replica as failed at the given time.
+func (r *replica) markFailed(now time.Time) {
+	r.failedAt.Store(now.UnixNano())
+}
+
+// available returns true if the replica is healthy or the retry interval passed
+// since its last failure.
+func (r *replica) available(now time.Time, retryInterval time.Duration) bool {
+	failedAt := r.failedAt.Load()
+
+	return failedAt == 0 || now.Sub(time.Unix(0, failedAt)) >= retryInterval
+}
+
+// nextReplica returns the next available replica in round-robin order, or nil if
+// none of them is available.
+func (db *Database) nextReplica(now time.Time) *replica {
+	if len(db.replicas) == 0 {
+		return nil
+	}
+
+	start := db.next.Add(1) - 1
+	for i := range uint64(len(db.replicas)) {
+		r := db.replicas[(start+i)%uint64(len(db.replicas))]
+		if r.available(now, db.config.ReplicaRetryInterval) {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// read runs the read only queries of fn in a replica. If there is no replica available
+// or the connection to the replica fails, the queries are run in the primary database.
+// The databases bound to a transaction don't have replicas, so they always read from
+// the primary and see their own writes.
+func (db *Database) read(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	r := db.nextReplica(time.Now())
+	if r == nil {
+		return db.retryRead(ctx, fn)
+	}
+
+	err := fn(r.session.WithContext(ctx))
+	if err == nil || !isTransientError(ctx, err) {
+		r.failedAt.Store(0)
+
+		return err
+	}
+
+	slog.Warn("unable to read from the replica, using the primary database", "error", err)
+	r.markFailed(time.Now())
+
+	return db.retryRead(ctx, fn)
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// transientCodes are the postgres error codes, besides the connection exceptions,
+// returned when the server is temporarily unable to run the queries.
+var transientCodes = []string{
+	"57P01", // admin_shutdown
+	"57P02", // crash_shutdown
+	"57P03", // cannot_connect_now
+	"53300", // too_many_connections
+}
+
+// isConnectionError returns true if the database couldn't be reached or the connection
+// was lost.
+func isConnectionError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var netErr net.Error
+	var connectErr *pgconn.ConnectError
+
+	return errors.As(err, &netErr) || errors.As(err, &connectErr) ||
+		errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// isTransientError returns true if the error is caused by a temporary failure of the
+// database, so the operation may succeed if it's retried.
+func isTransientError(ctx context.Context, err error) bool {
+	if isConnectionError(ctx, err) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+
+	// Class 08 is connection exception.
+	return strings.HasPrefix(pgErr.Code, "08") || slices.Contains(transientCodes, pgErr.Code)
+}
+
+// backoff returns the wait before the given retry, starting at 0. The base wait is
+// doubled on every retry up to maxWait, and a random jitter of up to half the wait is
+// subtracted, so the clients don't retry at the same time.
+func backoff(retry int, base, maxWait time.Duration) time.Duration {
+	wait := base
+	for range retry {
+		if wait >= maxWait/2 {
+			wait = maxWait
+
+			break
+		}
+
+		wait *= 2
+	}
+
+	wait = min(wait, maxWait)
+	if wait <= 0 {
+		return 0
+	}
+
+	return wait - rand.N(wait/2+1)
+}
+
+// sleep waits for the given time, or until the context is done.
+func sleep(ctx context.Context, wait time.Duration) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryRead runs the read only queries of fn in the primary database, and it retries
+// them once if they fail with a transient error. The reads inside a transaction are not
+// retried, as the transaction is aborted after an error.
+func (db *Database) retryRead(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	session, err := db.session(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = fn(session)
+	if err == nil || db.inTx || !isTransientError(ctx, err) {
+		return err
+	}
+
+	slog.Warn("unable to read from the database, retrying", "error", err)
+	wait := backoff(0, db.config.ConnectBackoff, db.config.ConnectMaxBackoff)
+	if err := sleep(ctx, wait); err != nil {
+		return err
+	}
+
+	// The session is fetched again, as the health check may have reopened the pool.
+	if session, err = db.session(ctx); err != nil {
+		return err
+	}
+
+	return fn(session)
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/filter"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/page"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/search"
+)
+
+// searchQuery joins the products with the parsed query, in the language of the search
+// settings.
+const searchQuery = "CROSS JOIN (SELECT language, websearch_to_tsquery(language, ?) AS query " +
+	"FROM search_settings) AS ts"
+
+// headlineOptions of the snippets of the search results.
+const headlineOptions = "StartSel=" + search.HighlightStart + ", StopSel=" +
+	search.HighlightStop + ", MaxWords=35, MinWords=15"
+
+// searchMatch is a product matching a search, with its snippet.
+type searchMatch struct {
+	Snippet string
+	ID      uint
+}
+
+// SearchProducts returns the page of products matching the full text search query and
+// the filters, sorted by relevance and then by id, with the total number of matching
+// products. The query has the web search syntax. The searches are only paginated by
+// offset.
+func (db *Database) SearchProducts(ctx context.Context, query string, pg page.Page,
+	filters ...filter.Filter,
+) ([]search.Result, int64, error) {
+	if err := search.ValidateQuery(query); err != nil {
+		return nil, 0, err
+	}
+
+	if pg.Cursor != nil {
+		return nil, 0, fmt.Errorf("%w: the searches are paginated by offset",
+			page.ErrInvalidCursor)
+	}
+
+	rates := db.currentRates()
+	clause, values, err := filterClause(filters, rates)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to search the products: %w", err)
+	}
+
+	var total int64
+	var matches []searchMatch
+	products := make(Products, 0)
+	// The count, the page and the products are read from the same database, so they are
+	// consistent.
+	err = db.read(ctx, func(tx *gorm.DB) error {
+		total = 0
+		matches = matches[:0]
+		products = products[:0]
+		if err := checkRates(tx, rates, filter.Keys(filters...)...); err != nil {
+			return err
+		}
+
+		matching := func() *gorm.DB {
+			res := tx.Model(&Product{}).Joins(searchQuery, query).
+				Where("products.search_vector @@ ts.query")
+			if clause != "" {
+				res = res.Where(clause, values...)
+			}
+
+			return res
+		}
+
+		if res := matching().Count(&total); res.Error != nil {
+			return fmt.Errorf("unable to count the products: %w", res.Error)
+		}
+
+		res := matching().
+			Select("products.id, ts_headline(ts.language, "+
+				"concat_ws(' ', products.name, products.description), ts.query, ?) AS snippet",
+				headlineOptions).
+			Order("ts_rank(products.search_vector, ts.query) DESC, products.id").
+			Offset(pg.Offset).Limit(pg.Limit).Scan(&matches)
+		if res.Error != nil {
+			return fmt.Errorf("unable to search the products: %w", res.Error)
+		}
+
+		if len(matches) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, 0, len(matches))
+		for _, m := range matches {
+			ids = append(ids, m.ID)
+		}
+
+		res = tx.Preload("Variants", orderVariants).Preload("Prices", orderPrices).
+			Preload("Category").Find(&products, ids)
+		if res.Error != nil {
+			return fmt.Errorf("unable to fetch the products: %w", res.Error)
+		}
+
+		return loadAncestors(tx, products.categories()...)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// The products are loaded by id, so they are sorted by their relevance again.
+	byID := make(map[uint]*Product, len(products))
+	for i := range products {
+		byID[products[i].ID] = &products[i]
+	}
+
+	results := make([]search.Result, 0, len(matches))
+	for _, m := range matches {
+		if p, ok := byID[m.ID]; ok {
+			results = append(results, search.Result{Product: *p.toModel(), Snippet: m.Snippet})
+		}
+	}
+
+	return results, total, nil
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/currency"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/page"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/product"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/sorting"
+)
+
+// sortColumn is a column used to sort the results.
+type sortColumn struct {
+	column
+	descending bool
+}
+
+// productSortColumn maps the fields of a product allowed in the sorts to their columns.
+// They are a subset of the filter columns, the ones the cursors can hold the values of,
+// so any other field is rejected like in the in-memory storage.
+func productSortColumn(field string, rates *currency.Rates) (column, bool) {
+	if _, ok := product.PriceCurrency(field); ok {
+		return productColumn(field, rates)
+	}
+
+	switch field {
+	case "id", "code", "price", "category":
+		return productColumn(field, rates)
+	}
+
+	return column{}, false
+}
+
+// productSortColumns returns the columns to sort the products, with the id as the last
+// one to break the ties, so the order is deterministic. The converted prices are sorted
+// with the exchange rates.
+func productSortColumns(sorts []sorting.Sort, rates *currency.Rates) ([]sortColumn, error) {
+	columns := make([]sortColumn, 0, len(sorts)+1)
+	hasID := false
+	for i := range sorts {
+		col, ok := productSortColumn(sorts[i].Field, rates)
+		if !ok {
+			return nil, &sorting.FieldError{Field: sorts[i].Field}
+		}
+
+		hasID = hasID || sorts[i].Field == "id"
+		columns = append(columns, sortColumn{column: col, descending: sorts[i].Descending})
+	}
+
+	if !hasID {
+		id, _ := productSortColumn("id", rates)
+		columns = append(columns, sortColumn{column: id})
+	}
+
+	return columns, nil
+}
+
+// orderClause returns the order by clause of the columns, in reverse order if reverse
+// is true.
+func orderClause(columns []sortColumn, reverse bool) string {
+	clauses := make([]string, 0, len(columns))
+	for i := range columns {
+		if columns[i].descending != reverse {
+			clauses = append(clauses, columns[i].name+" DESC")
+		} else {
+			clauses = append(clauses, columns[i].name)
+		}
+	}
+
+	return strings.Join(clauses, ", ")
+}
+
+// keysetClause returns the where clause to fetch the elements after the cursor (or
+// before it if it's backward), with its values. For the columns (a, b) it returns
+// (a > ?) OR (a = ? AND b > ?), using < for the descending columns.
+func keysetClause(columns []sortColumn, cur *page.Cursor) (string, []any, error) {
+	values, err := cursorValues(columns, cur)
+	if err != nil {
+		return "", nil, err
+	}
+
+	clauses := make([]string, 0, len(columns))
+	args := make([]any, 0)
+	for i := range columns {
+		parts := make([]string, 0, i+1)
+		for j := range i {
+			parts = append(parts, columns[j].name+" = ?")
+			args = append(args, values[j])
+		}
+
+		op := ">"
+		if columns[i].descending != cur.Backward {
+			op = "<"
+		}
+
+		parts = append(parts, fmt.Sprintf("%s %s ?", columns[i].name, op))
+		args = append(args, values[i])
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	return "(" + strings.Join(clauses, " OR ") + ")", args, nil
+}
+
+// cursorValues returns the values of the cursor for each sort column. The last column
+// is the id if it was added to break the ties.
+func cursorValues(columns []sortColumn, cur *page.Cursor) ([]any, error) {
+	if len(cur.Values) != len(columns) && len(cur.Values) != len(columns)-1 {
+		return nil, fmt.Errorf("%w: the cursor doesn't match the sort", page.ErrInvalidCursor)
+	}
+
+	values := make([]any, 0, len(columns))
+	for i := range columns {
+		if i == len(cur.Values) {
+			values = append(values, cur.ID)
+
+			break
+		}
+
+		if !columns[i].numeric {
+			values = append(values, cur.Values[i])
+
+			continue
+		}
+
+		d, err := decimal.NewFromString(cur.Values[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid value %q", page.ErrInvalidCursor, cur.Values[i])
+		}
+
+		values = append(values, d)
+	}
+
+	return values, nil
+}
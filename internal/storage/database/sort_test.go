@@ -0,0 +1,74 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/page"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/sorting"
+)
+
+func TestOrderClause(t *testing.T) {
+	t.Parallel()
+	columns, err := productSortColumns([]sorting.Sort{
+		{Field: "price"},
+		{Field: "code", Descending: true},
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, activePriceQuery+", products.code DESC, products.id",
+		orderClause(columns, false))
+	assert.Equal(t, activePriceQuery+" DESC, products.code, products.id DESC",
+		orderClause(columns, true))
+
+	columns, err = productSortColumns([]sorting.Sort{{Field: "price.USD"}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, convertedPriceQuery(nil, "USD")+", products.id", orderClause(columns, false))
+
+	columns, err = productSortColumns([]sorting.Sort{{Field: "id", Descending: true}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "products.id DESC", orderClause(columns, false))
+
+	var fieldErr *sorting.FieldError
+	for _, field := range []string{"unknown", "updated_at", "attr.material", "category_tree",
+		"on_sale", "in_stock", "price.usd"} {
+		_, err = productSortColumns([]sorting.Sort{{Field: field}}, nil)
+		require.True(t, errors.As(err, &fieldErr), field)
+		assert.Equal(t, field, fieldErr.Field)
+	}
+}
+
+func TestKeysetClause(t *testing.T) {
+	t.Parallel()
+	columns, err := productSortColumns([]sorting.Sort{
+		{Field: "price"},
+		{Field: "code", Descending: true},
+	}, nil)
+	require.NoError(t, err)
+
+	p := activePriceQuery
+	cur := &page.Cursor{Values: []string{"10.5", "PROD001"}, ID: 3}
+	clause, values, err := keysetClause(columns, cur)
+	require.NoError(t, err)
+	assert.Equal(t, "(("+p+" > ?) OR ("+p+" = ? AND products.code < ?) OR "+
+		"("+p+" = ? AND products.code = ? AND products.id > ?))", clause)
+	price := decimal.RequireFromString("10.5")
+	assert.Equal(t, []any{price, price, "PROD001", price, "PROD001", uint(3)}, values)
+
+	cur.Backward = true
+	clause, _, err = keysetClause(columns, cur)
+	require.NoError(t, err)
+	assert.Equal(t, "(("+p+" < ?) OR ("+p+" = ? AND products.code > ?) OR "+
+		"("+p+" = ? AND products.code = ? AND products.id < ?))", clause)
+
+	for _, cur := range []*page.Cursor{
+		{ID: 3},
+		{Values: []string{"cheap", "PROD001"}, ID: 3},
+	} {
+		_, _, err = keysetClause(columns, cur)
+		require.ErrorIs(t, err, page.ErrInvalidCursor)
+	}
+}
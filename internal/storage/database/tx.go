@@ -0,0 +1,22 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/storage"
+)
+
+// WithTx runs fn in a database transaction. The storage passed to fn runs all its
+// queries in the transaction, which is committed if fn succeeds and rolled back if it
+// fails or panics. Nested calls use savepoints, so an inner failure only rolls back
+// the changes of the inner call.
+func (db *Database) WithTx(ctx context.Context, fn func(tx storage.Storage) error) error {
+	return db.transaction(ctx, func(tx *gorm.DB) error {
+		res := &Database{config: db.config, rates: db.rates, inTx: true}
+		res.setPrimary(tx, db.conn())
+
+		return fn(res)
+	})
+}
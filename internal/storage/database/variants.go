@@ -0,0 +1,187 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/product"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/variant"
+)
+
+// CreateVariant adds a new variant to the product with the given code. The SKUs are
+// unique among all the products. A price without currency gets the currency of the
+// product.
+func (db *Database) CreateVariant(ctx context.Context, productCode string,
+	v *variant.Variant,
+) error {
+	prod, err := db.productByCode(ctx, productCode)
+	if err != nil {
+		return err
+	}
+
+	v.SetDefaultCurrency(prod.Currency)
+	if err := v.Validate(); err != nil {
+		return err
+	}
+
+	v.ProductID = prod.ID
+	rec := newVariant(v)
+	err = db.transaction(ctx, func(tx *gorm.DB) error {
+		res := tx.Create(&rec)
+		if res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrDuplicatedKey) {
+				return fmt.Errorf("unable to create the variant %s: %w", v.SKU,
+					variant.ErrDuplicated)
+			}
+
+			return fmt.Errorf("unable to create the variant %s: %w", v.SKU, res.Error)
+		}
+
+		return touchProduct(tx, prod.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	*v = *rec.toModel()
+
+	return nil
+}
+
+// UpdateVariant updates the name and the price, with its currency, of the variant of
+// the product with the same SKU. A variant without price inherits the price of the
+// product, and a price without currency gets the currency of the product. If the
+// variant has a version, it's only updated if the stored one has the same version.
+func (db *Database) UpdateVariant(ctx context.Context, productCode string,
+	v *variant.Variant,
+) error {
+	prod, err := db.productByCode(ctx, productCode)
+	if err != nil {
+		return err
+	}
+
+	v.SetDefaultCurrency(prod.Currency)
+	if err := v.Validate(); err != nil {
+		return err
+	}
+
+	rec := newVariant(v)
+	var updated Variant
+	err = db.transaction(ctx, func(tx *gorm.DB) error {
+		query := tx.Model(&updated).Clauses(clause.Returning{}).
+			Where("product_id = ? AND sku = ?", prod.ID, v.SKU)
+		res := whereVersion(query, v.Version).Updates(map[string]any{
+			"name":     rec.Name,
+			"price":    rec.Price,
+			"currency": rec.Currency,
+			"version":  nextVersion,
+		})
+		if res.Error != nil {
+			return fmt.Errorf("unable to update the variant %s: %w", v.SKU, res.Error)
+		}
+
+		if res.RowsAffected == 0 {
+			return missingOrStale(tx, &Variant{},
+				map[string]any{"product_id": prod.ID, "sku": v.SKU},
+				variant.ErrNotFound, variant.ErrVersionMismatch)
+		}
+
+		return touchProduct(tx, prod.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	*v = *updated.toModel()
+
+	return nil
+}
+
+// DeleteVariant removes the variant with the given SKU from the product. It's kept with
+// its removal time (soft delete), so the SKU can be reused, and its scheduled prices and
+// its stock are removed, releasing its active reservations. If the version isn't zero,
+// the variant is only removed if it has the same version.
+func (db *Database) DeleteVariant(ctx context.Context, productCode, sku string,
+	version uint,
+) error {
+	prod, err := db.productByCode(ctx, productCode)
+	if err != nil {
+		return err
+	}
+
+	return db.transaction(ctx, func(tx *gorm.DB) error {
+		var ids []uint
+		res := tx.Model(&Variant{}).Where("product_id = ? AND sku = ?", prod.ID, sku).
+			Pluck("id", &ids)
+		if res.Error != nil {
+			return fmt.Errorf("unable to fetch the variant %s: %w", sku, res.Error)
+		}
+
+		query := tx.Where("product_id = ? AND sku = ?", prod.ID, sku)
+		res = whereVersion(query, version).Delete(&Variant{})
+		if res.Error != nil {
+			return fmt.Errorf("unable to delete the variant %s: %w", sku, res.Error)
+		}
+
+		if res.RowsAffected == 0 {
+			return missingOrStale(tx, &Variant{},
+				map[string]any{"product_id": prod.ID, "sku": sku},
+				variant.ErrNotFound, variant.ErrVersionMismatch)
+		}
+
+		res = tx.Where("product_id = ? AND sku = ?", prod.ID, sku).Delete(&Price{})
+		if res.Error != nil {
+			return fmt.Errorf("unable to delete the prices of the variant %s: %w", sku,
+				res.Error)
+		}
+
+		if err := removeStock(tx, ids); err != nil {
+			return err
+		}
+
+		return touchProduct(tx, prod.ID)
+	})
+}
+
+// touchProduct updates the update time and the version of the product, as its variants
+// and its scheduled prices are part of it.
+func touchProduct(tx *gorm.DB, productID uint) error {
+	res := tx.Model(&Product{}).Where("id = ?", productID).
+		Updates(map[string]any{"updated_at": tx.NowFunc(), "version": nextVersion})
+	if res.Error != nil {
+		return fmt.Errorf("unable to update the product %d: %w", productID, res.Error)
+	}
+
+	return nil
+}
+
+// productByCode returns the product with the given code, without its variants and
+// category.
+func (db *Database) productByCode(ctx context.Context, code string) (*Product, error) {
+	session, err := db.session(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var prod Product
+	res := session.Find(&prod, map[string]any{"code": code})
+	if res.Error != nil {
+		return nil, fmt.Errorf("unable to fetch the product %s: %w", code, res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return nil, product.ErrNotFound
+	}
+
+	return &prod, nil
+}
+
+// orderVariants sorts the preloaded variants by id, so they keep the creation order
+// after being updated.
+func orderVariants(tx *gorm.DB) *gorm.DB {
+	return tx.Order("product_variants.id")
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// nextVersion increases the version of the updated rows.
+var nextVersion = gorm.Expr("version + 1")
+
+// whereVersion restricts the query to the rows with the given version. A zero version
+// doesn't restrict the query, so the change is unconditional.
+func whereVersion(tx *gorm.DB, version uint) *gorm.DB {
+	if version == 0 {
+		return tx
+	}
+
+	return tx.Where("version = ?", version)
+}
+
+// missingOrStale returns the error of a conditional change that didn't affect any row:
+// the mismatch error if the row matching the query exists, as it has a different
+// version, or the not found error otherwise.
+func missingOrStale(tx *gorm.DB, model any, query map[string]any,
+	notFound, mismatch error,
+) error {
+	var count int64
+	if res := tx.Model(model).Where(query).Count(&count); res.Error != nil {
+		return fmt.Errorf("unable to check the version: %w", res.Error)
+	}
+
+	if count == 0 {
+		return notFound
+	}
+
+	return mismatch
+}
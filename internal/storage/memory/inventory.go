@@ -0,0 +1,366 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/inventory"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/variant"
+)
+
+// GetStock obtains the stock of the variant with the given SKU.
+func (m *Memory) GetStock(ctx context.Context, sku string) (*inventory.Stock, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("unable to fetch the stock of %s: %w", sku, err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	v := m.findVariant(sku)
+	if v == nil {
+		return nil, variant.ErrNotFound
+	}
+
+	s := m.stockOf(v)
+
+	return &s, nil
+}
+
+// GetStocks obtains the stock of the variants with the given SKUs, ignoring the unknown
+// ones.
+func (m *Memory) GetStocks(ctx context.Context, skus []string) ([]inventory.Stock, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("unable to fetch the stock: %w", err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	res := make([]inventory.Stock, 0, len(skus))
+	for _, sku := range skus {
+		if v := m.findVariant(sku); v != nil {
+			res = append(res, m.stockOf(v))
+		}
+	}
+
+	return res, nil
+}
+
+// AdjustStock adds the delta, positive or negative, to the units on hand of the variant
+// with the given SKU. It fails with an insufficient stock error if there would be fewer
+// units on hand than reserved, once the expired reservations are released.
+func (m *Memory) AdjustStock(ctx context.Context, sku string,
+	delta int64,
+) (*inventory.Stock, error) {
+	if err := inventory.ValidateAdjustment(delta); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("unable to adjust the stock of %s: %w", sku, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v := m.findVariant(sku)
+	if v == nil {
+		return nil, variant.ErrNotFound
+	}
+
+	now := m.now()
+	m.expireOverdue(now, []uint{v.ID})
+	s := m.stockOf(v)
+	if s.OnHand+delta < s.Reserved {
+		return nil, fmt.Errorf("%w: %s has %d units reserved", inventory.ErrInsufficientStock,
+			sku, s.Reserved)
+	}
+
+	s.OnHand += delta
+	s.UpdatedAt = now
+	m.stock[v.ID] = s
+
+	m.version++
+
+	return &s, nil
+}
+
+// CreateReservation reserves the units of all the lines of the reservation, or none of
+// them if any variant doesn't have enough units available, once the expired
+// reservations are released. The reservation is updated with the stored data.
+func (m *Memory) CreateReservation(ctx context.Context, r *inventory.Reservation) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("unable to create the reservation: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if !r.ExpiresAt.After(now) {
+		return fmt.Errorf("%w: it's already expired", inventory.ErrInvalidReservation)
+	}
+
+	lines := slices.Clone(r.Lines)
+	slices.SortFunc(lines, func(a, b inventory.Line) int {
+		return cmp.Compare(a.SKU, b.SKU)
+	})
+
+	variants := make([]uint, 0, len(lines))
+	for i := range lines {
+		v := m.findVariant(lines[i].SKU)
+		if v == nil {
+			return fmt.Errorf("unable to reserve %s: %w", lines[i].SKU, variant.ErrNotFound)
+		}
+
+		lines[i].VariantID = v.ID
+		variants = append(variants, v.ID)
+	}
+
+	m.expireOverdue(now, variants)
+	for i := range lines {
+		if s := m.stockOf(m.findVariant(lines[i].SKU)); s.Available() < lines[i].Quantity {
+			return fmt.Errorf("%w: %s has %d units available", inventory.ErrInsufficientStock,
+				lines[i].SKU, s.Available())
+		}
+	}
+
+	m.applyLines(lines, now, func(s *inventory.Stock, quantity int64) {
+		s.Reserved += quantity
+	})
+
+	m.lastReservationID++
+	r.ID = m.lastReservationID
+	r.Status = inventory.StatusActive
+	r.Lines = lines
+	r.CreatedAt = now
+	r.UpdatedAt = now
+	m.reservations = append(m.reservations, cloneReservation(r))
+
+	m.version++
+
+	return nil
+}
+
+// GetReservation obtains a reservation by its id.
+func (m *Memory) GetReservation(ctx context.Context, id uint) (*inventory.Reservation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("unable to fetch the reservation %d: %w", id, err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	r := m.findReservation(id)
+	if r == nil {
+		return nil, inventory.ErrReservationNotFound
+	}
+
+	res := cloneReservation(r)
+
+	return &res, nil
+}
+
+// CommitReservation removes the reserved units from the stock on hand. It fails if the
+// reservation isn't active or it's expired.
+func (m *Memory) CommitReservation(ctx context.Context,
+	id uint,
+) (*inventory.Reservation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("unable to commit the reservation %d: %w", id, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r := m.findReservation(id)
+	if r == nil {
+		return nil, inventory.ErrReservationNotFound
+	}
+
+	now := m.now()
+	if err := r.ValidateCommit(now); err != nil {
+		return nil, err
+	}
+
+	m.applyLines(r.Lines, now, func(s *inventory.Stock, quantity int64) {
+		s.OnHand -= quantity
+		s.Reserved -= quantity
+	})
+	r.Status = inventory.StatusCommitted
+	r.UpdatedAt = now
+	res := cloneReservation(r)
+
+	m.version++
+
+	return &res, nil
+}
+
+// ReleaseReservation makes the reserved units available again. It fails if the
+// reservation isn't active.
+func (m *Memory) ReleaseReservation(ctx context.Context,
+	id uint,
+) (*inventory.Reservation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("unable to release the reservation %d: %w", id, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r := m.findReservation(id)
+	if r == nil {
+		return nil, inventory.ErrReservationNotFound
+	}
+
+	if r.Status != inventory.StatusActive {
+		return nil, fmt.Errorf("%w: it's %s", inventory.ErrReservationClosed, r.Status)
+	}
+
+	m.releaseReservation(r, inventory.StatusReleased, m.now())
+	res := cloneReservation(r)
+
+	m.version++
+
+	return &res, nil
+}
+
+// ExpireReservations releases the active reservations after their expiration time, and
+// returns how many of them expired.
+func (m *Memory) ExpireReservations(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("unable to expire the reservations: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expired := m.expireOverdue(m.now(), nil)
+	if expired > 0 {
+		m.version++
+	}
+
+	return expired, nil
+}
+
+// expireOverdue releases the active reservations with a line of the variants after their
+// expiration time, or all of them without variants, and returns how many of them
+// expired. The caller must hold the write lock.
+func (m *Memory) expireOverdue(now time.Time, variants []uint) int64 {
+	var expired int64
+	for i := range m.reservations {
+		r := &m.reservations[i]
+		if !r.Expired(now) {
+			continue
+		}
+
+		if variants == nil || hasVariant(r, variants) {
+			m.releaseReservation(r, inventory.StatusExpired, now)
+			expired++
+		}
+	}
+
+	return expired
+}
+
+// removeStock releases the active reservations with a line of the removed variants, and
+// removes their stock. The overdue reservations are expired first. The caller must hold
+// the write lock.
+func (m *Memory) removeStock(variants []uint, now time.Time) {
+	m.expireOverdue(now, variants)
+	for i := range m.reservations {
+		r := &m.reservations[i]
+		if r.Status == inventory.StatusActive && hasVariant(r, variants) {
+			m.releaseReservation(r, inventory.StatusReleased, now)
+		}
+	}
+
+	for _, id := range variants {
+		delete(m.stock, id)
+	}
+}
+
+// releaseReservation makes the units of the reservation available, and closes it with
+// the status. The caller must hold the write lock.
+func (m *Memory) releaseReservation(r *inventory.Reservation, status inventory.Status,
+	now time.Time,
+) {
+	m.applyLines(r.Lines, now, func(s *inventory.Stock, quantity int64) {
+		s.Reserved -= quantity
+	})
+	r.Status = status
+	r.UpdatedAt = now
+}
+
+// applyLines applies the change to the stock of the variant of every line. The caller
+// must hold the write lock.
+func (m *Memory) applyLines(lines []inventory.Line, now time.Time,
+	change func(s *inventory.Stock, quantity int64),
+) {
+	for _, l := range lines {
+		s := m.stock[l.VariantID]
+		s.SKU = l.SKU
+		change(&s, l.Quantity)
+		s.UpdatedAt = now
+		m.stock[l.VariantID] = s
+	}
+}
+
+// stockOf returns the stock of the variant, with zero units if it doesn't have stock.
+// The caller must hold the lock.
+func (m *Memory) stockOf(v *variant.Variant) inventory.Stock {
+	s, ok := m.stock[v.ID]
+	if !ok {
+		return inventory.Stock{SKU: v.SKU, UpdatedAt: v.CreatedAt}
+	}
+
+	return s
+}
+
+// findVariant returns the variant with the given SKU, or nil if it doesn't exist. The
+// caller must hold the lock.
+func (m *Memory) findVariant(sku string) *variant.Variant {
+	for i := range m.products {
+		if idx := variantIndex(&m.products[i], sku); idx >= 0 {
+			return &m.products[i].product.Variants[idx]
+		}
+	}
+
+	return nil
+}
+
+// findReservation returns the reservation with the given id, or nil if it doesn't exist.
+// The caller must hold the lock.
+func (m *Memory) findReservation(id uint) *inventory.Reservation {
+	for i := range m.reservations {
+		if m.reservations[i].ID == id {
+			return &m.reservations[i]
+		}
+	}
+
+	return nil
+}
+
+// hasVariant reports whether the reservation has a line of any of the variants.
+func hasVariant(r *inventory.Reservation, variants []uint) bool {
+	return slices.ContainsFunc(r.Lines, func(l inventory.Line) bool {
+		return slices.Contains(variants, l.VariantID)
+	})
+}
+
+// cloneReservation returns a copy of the reservation, so the callers can't modify the
+// data kept in the storage.
+func cloneReservation(r *inventory.Reservation) inventory.Reservation {
+	res := *r
+	res.Lines = slices.Clone(r.Lines)
+
+	return res
+}
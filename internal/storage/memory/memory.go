@@ -0,0 +1,941 @@
+// Package memory implements an in-memory storage, mainly used for tests and local
+// development. It follows the same semantics as the database storage and it's safe
+// for concurrent use.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/category"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/currency"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/filter"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/inventory"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/page"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/product"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/sorting"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/variant"
+)
+
+// productRecord stores a product with the reference to its category, so the category
+// is always resolved with its latest data, as the database does.
+type productRecord struct {
+	product    product.Product
+	categoryID uint64
+	// deletedAt is the removal time of the removed products.
+	deletedAt time.Time
+}
+
+// Memory storage for the server.
+type Memory struct {
+	categories []category.Category
+	products   []productRecord
+	// removedProducts are the products removed, kept with their variants and prices as
+	// the database does (soft delete).
+	removedProducts []productRecord
+	mu              sync.RWMutex
+	lastCategory    uint64
+	lastProductID   uint
+	lastVariantID   uint
+	lastPriceID     uint
+	// stock has the units of the variants by their id, as the SKUs of the removed
+	// variants can be reused.
+	stock             map[uint]inventory.Stock
+	reservations      []inventory.Reservation
+	lastReservationID uint
+	// version changes on every successful write, so the transactions only replace the
+	// data when they modified it.
+	version uint64
+	// now returns the time of the writes.
+	now func() time.Time
+	// rates returns the exchange rates used to compare the prices in different
+	// currencies.
+	rates func() *currency.Rates
+}
+
+// New returns a new empty in-memory storage.
+func New() *Memory {
+	return &Memory{
+		categories: make([]category.Category, 0),
+		products:   make([]productRecord, 0),
+		stock:      make(map[uint]inventory.Stock),
+		now:        func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Connect to the storage.
+func (m *Memory) Connect(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// Disconnect from the storage.
+func (m *Memory) Disconnect(_ context.Context) error {
+	return nil
+}
+
+// GetAllProducts returns the list of all products stored in memory.
+func (m *Memory) GetAllProducts(ctx context.Context) ([]product.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("unable to fetch the products: %w", err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	products := make([]product.Product, 0, len(m.products))
+	for i := range m.products {
+		products = append(products, m.toModel(&m.products[i]))
+	}
+
+	return products, nil
+}
+
+// GetProducts returns the page of products stored in memory filtered by the filters
+// and sorted by the sorts, with the total number of products matching the filters. The
+// products are always sorted by id after the given sorts.
+func (m *Memory) GetProducts(ctx context.Context, pg page.Page, sorts []sorting.Sort,
+	filters ...filter.Filter,
+) ([]product.Product, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, fmt.Errorf("unable to fetch the products: %w", err)
+	}
+
+	columns, err := sortColumns(sorts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to fetch the products: %w", err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	fields := filter.Keys(filters...)
+	for i := range sorts {
+		fields = append(fields, sorts[i].Field)
+	}
+
+	if err := m.checkRates(fields...); err != nil {
+		return nil, 0, fmt.Errorf("unable to fetch the products: %w", err)
+	}
+
+	matching := make([]sortedRecord, 0)
+	for i := range m.products {
+		ok, err := m.matches(&m.products[i], filters)
+		if err != nil {
+			return nil, 0, fmt.Errorf("unable to fetch the products: %w", err)
+		}
+
+		if !ok {
+			continue
+		}
+
+		keys, err := m.recordKeys(&m.products[i], columns)
+		if err != nil {
+			return nil, 0, fmt.Errorf("unable to fetch the products: %w", err)
+		}
+
+		matching = append(matching, sortedRecord{record: &m.products[i], keys: keys})
+	}
+
+	slices.SortFunc(matching, func(a, b sortedRecord) int {
+		return compareKeys(columns, a.keys, b.keys)
+	})
+
+	selected, err := paginate(matching, columns, pg)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to fetch the products: %w", err)
+	}
+
+	products := make([]product.Product, 0, len(selected))
+	for i := range selected {
+		products = append(products, m.toModel(selected[i].record))
+	}
+
+	return products, int64(len(matching)), nil
+}
+
+// GetProduct obtains a product from the storage by its code.
+func (m *Memory) GetProduct(ctx context.Context,
+	productCode string,
+) (*product.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("unable to fetch the product %s: %w", productCode, err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rec := m.findProduct(productCode)
+	if rec == nil {
+		return nil, product.ErrNotFound
+	}
+
+	prod := m.toModel(rec)
+
+	return &prod, nil
+}
+
+// CreateProduct adds a new product, with its variants, to the storage. The product
+// category is looked up by its code and it must exist. The prices without currency get
+// the default one.
+func (m *Memory) CreateProduct(ctx context.Context, prod *product.Product) error {
+	prod.SetDefaultCurrency()
+	if err := prod.Validate(); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("unable to create the product %s: %w", prod.Code, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cat := m.findCategory(prod.Category.Code)
+	if cat == nil {
+		return fmt.Errorf("unable to create the product %s: %w", prod.Code,
+			category.ErrInvalidCategory)
+	}
+
+	if m.findProduct(prod.Code) != nil {
+		return fmt.Errorf("unable to create the product %s: %w", prod.Code,
+			product.ErrDuplicated)
+	}
+
+	for i := range prod.Variants {
+		if m.skuExists(prod.Variants[i].SKU) {
+			return fmt.Errorf("unable to create the product %s: %w", prod.Code,
+				variant.ErrDuplicated)
+		}
+	}
+
+	now := m.now()
+	m.lastProductID++
+	prod.ID = m.lastProductID
+	prod.CreatedAt = now
+	prod.UpdatedAt = now
+	prod.Version = 1
+	stored := *cat
+	prod.Category = &stored
+
+	variants := make([]variant.Variant, 0, len(prod.Variants))
+	for i := range prod.Variants {
+		m.lastVariantID++
+		prod.Variants[i].ID = m.lastVariantID
+		prod.Variants[i].ProductID = prod.ID
+		prod.Variants[i].CreatedAt = now
+		prod.Variants[i].UpdatedAt = now
+		prod.Variants[i].Version = 1
+		variants = append(variants, prod.Variants[i])
+	}
+
+	rec := *prod
+	rec.Category = nil
+	rec.Attributes = maps.Clone(prod.Attributes)
+	rec.Variants = variants
+	// The scheduled prices are added once the product exists.
+	rec.Prices = nil
+	m.products = append(m.products, productRecord{product: rec, categoryID: cat.ID})
+
+	m.version++
+
+	return nil
+}
+
+// UpdateProduct updates the name, the description, the attributes, the price with its
+// currency and the category of the product with the same code. If the product has a
+// version, it's only updated if the stored one has the same version. The product is
+// updated with the stored data.
+func (m *Memory) UpdateProduct(ctx context.Context, prod *product.Product) error {
+	prod.SetDefaultCurrency()
+	if err := prod.Validate(); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("unable to update the product %s: %w", prod.Code, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cat := m.findCategory(prod.Category.Code)
+	if cat == nil {
+		return fmt.Errorf("unable to update the product %s: %w", prod.Code,
+			category.ErrInvalidCategory)
+	}
+
+	rec := m.findProduct(prod.Code)
+	if rec == nil {
+		return product.ErrNotFound
+	}
+
+	if !sameVersion(rec.product.Version, prod.Version) {
+		return product.ErrVersionMismatch
+	}
+
+	rec.product.Name = prod.Name
+	rec.product.Description = prod.Description
+	rec.product.Attributes = maps.Clone(prod.Attributes)
+	rec.product.Price = prod.Price
+	rec.product.Currency = prod.Currency
+	rec.product.UpdatedAt = m.now()
+	rec.product.Version++
+	rec.categoryID = cat.ID
+	*prod = m.toModel(rec)
+
+	m.version++
+
+	return nil
+}
+
+// DeleteProduct removes a product, with its variants, from the storage. The product is
+// kept as removed, as in the database, so its code and the SKUs of its variants can be
+// reused, and the stock of the variants is removed, releasing their active reservations.
+// If the version isn't zero, the product is only removed if it has the same version.
+func (m *Memory) DeleteProduct(ctx context.Context, productCode string, version uint) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("unable to delete the product %s: %w", productCode, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	idx := slices.IndexFunc(m.products, func(rec productRecord) bool {
+		return rec.product.Code == productCode
+	})
+	if idx < 0 {
+		return product.ErrNotFound
+	}
+
+	if !sameVersion(m.products[idx].product.Version, version) {
+		return product.ErrVersionMismatch
+	}
+
+	rec := m.products[idx]
+	rec.deletedAt = m.now()
+	variants := make([]uint, 0, len(rec.product.Variants))
+	for _, v := range rec.product.Variants {
+		variants = append(variants, v.ID)
+	}
+
+	m.removeStock(variants, rec.deletedAt)
+	m.removedProducts = append(m.removedProducts, rec)
+	m.products = slices.Delete(m.products, idx, idx+1)
+	m.version++
+
+	return nil
+}
+
+// GetAllCategories gets a list of all the categories stored in memory.
+func (m *Memory) GetAllCategories(ctx context.Context) (category.Categories, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("unable to fetch the categories: %w", err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return slices.Clone(m.categories), nil
+}
+
+// AddCategory adds a new category to the storage. The category is updated with the
+// stored data.
+func (m *Memory) AddCategory(ctx context.Context, cat *category.Category) error {
+	if cat.Name == "" {
+		return category.ErrInvalidCategory
+	}
+
+	if cat.Code == "" {
+		return category.ErrInvalidCategory
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("unable to create the category: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.findCategory(cat.Code) != nil {
+		return fmt.Errorf("unable to create the category %s: %w", cat.Code,
+			category.ErrDuplicated)
+	}
+
+	if cat.ParentCode != "" && m.findCategory(cat.ParentCode) == nil {
+		return fmt.Errorf("%w: unknown parent category %s", category.ErrInvalidCategory,
+			cat.ParentCode)
+	}
+
+	now := m.now()
+	m.lastCategory++
+	m.categories = append(m.categories, category.Category{
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		ID:         m.lastCategory,
+		Code:       cat.Code,
+		Name:       cat.Name,
+		ParentCode: cat.ParentCode,
+		Version:    1,
+	})
+	*cat = m.categories[len(m.categories)-1]
+
+	m.version++
+
+	return nil
+}
+
+// GetCategory obtains a category from the storage by its code.
+func (m *Memory) GetCategory(ctx context.Context, code string) (*category.Category, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("unable to fetch the category %s: %w", code, err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cat := m.findCategory(code)
+	if cat == nil {
+		return nil, category.ErrNotFound
+	}
+
+	res := *cat
+	res.Ancestors = m.ancestors(cat)
+
+	return &res, nil
+}
+
+// UpdateCategory updates the name and the parent of the category with the same code. If
+// the category has a version, it's only updated if the stored one has the same version.
+// It fails with a cycle error if the parent is the category or one of its descendants.
+// The products of the category and its descendants are changed too, as they include
+// its name in their breadcrumbs.
+func (m *Memory) UpdateCategory(ctx context.Context, cat *category.Category) error {
+	if cat.Name == "" || cat.Code == "" {
+		return category.ErrInvalidCategory
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("unable to update the category %s: %w", cat.Code, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := m.findCategory(cat.Code)
+	if stored == nil {
+		return category.ErrNotFound
+	}
+
+	if !sameVersion(stored.Version, cat.Version) {
+		return category.ErrVersionMismatch
+	}
+
+	if err := m.checkParent(cat.Code, cat.ParentCode); err != nil {
+		return err
+	}
+
+	stored.Name = cat.Name
+	stored.ParentCode = cat.ParentCode
+	stored.UpdatedAt = m.now()
+	stored.Version++
+	for id := range m.descendants(stored.ID) {
+		m.touchProducts(id, id)
+	}
+
+	*cat = *stored
+
+	m.version++
+
+	return nil
+}
+
+// DeleteCategory removes a category from the storage. If some products belong to it
+// they are moved to the reassignTo category, and it fails with an in use error if
+// reassignTo is empty or the category has subcategories. If the version isn't zero, the
+// category is only removed if it has the same version.
+func (m *Memory) DeleteCategory(ctx context.Context, code, reassignTo string,
+	version uint,
+) error {
+	if code == reassignTo {
+		return fmt.Errorf("%w: the category can't be reassigned to itself",
+			category.ErrInvalidCategory)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("unable to delete the category %s: %w", code, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	idx := slices.IndexFunc(m.categories, func(cat category.Category) bool {
+		return cat.Code == code
+	})
+	if idx < 0 {
+		return category.ErrNotFound
+	}
+
+	if !sameVersion(m.categories[idx].Version, version) {
+		return category.ErrVersionMismatch
+	}
+
+	if slices.ContainsFunc(m.categories, func(cat category.Category) bool {
+		return cat.ParentCode == code
+	}) {
+		return fmt.Errorf("unable to delete the category %s: %w: it has subcategories", code,
+			category.ErrInUse)
+	}
+
+	id := m.categories[idx].ID
+	var target *category.Category
+	if reassignTo != "" {
+		target = m.findCategory(reassignTo)
+		if target == nil {
+			return fmt.Errorf("%w: unknown category %s", category.ErrInvalidCategory,
+				reassignTo)
+		}
+	}
+
+	inUse := slices.ContainsFunc(m.products, func(rec productRecord) bool {
+		return rec.categoryID == id
+	})
+	if inUse && target == nil {
+		return fmt.Errorf("unable to delete the category %s: %w", code, category.ErrInUse)
+	}
+
+	if target != nil {
+		m.touchProducts(id, target.ID)
+	}
+
+	m.categories = slices.Delete(m.categories, idx, idx+1)
+
+	m.version++
+
+	return nil
+}
+
+// touchProducts updates the update time and the version of the products of the
+// category, and moves them to the target category. The caller must hold the lock.
+func (m *Memory) touchProducts(categoryID, target uint64) {
+	now := m.now()
+	for i := range m.products {
+		if m.products[i].categoryID != categoryID {
+			continue
+		}
+
+		m.products[i].categoryID = target
+		m.products[i].product.UpdatedAt = now
+		m.products[i].product.Version++
+	}
+}
+
+// checkParent checks the parent exists, and it isn't the category or one of its
+// descendants. The caller must hold the lock.
+func (m *Memory) checkParent(code, parentCode string) error {
+	if parentCode == "" {
+		return nil
+	}
+
+	if m.findCategory(parentCode) == nil {
+		return fmt.Errorf("%w: unknown parent category %s", category.ErrInvalidCategory,
+			parentCode)
+	}
+
+	for ancestor := parentCode; ancestor != ""; ancestor = m.parentCode(ancestor) {
+		if ancestor == code {
+			return fmt.Errorf("%w: %s can't be moved under %s", category.ErrCycle, code,
+				parentCode)
+		}
+	}
+
+	return nil
+}
+
+// parentCode returns the code of the parent of the category, or an empty string if it's
+// a root category. The caller must hold the lock.
+func (m *Memory) parentCode(code string) string {
+	if cat := m.findCategory(code); cat != nil {
+		return cat.ParentCode
+	}
+
+	return ""
+}
+
+// descendants returns the ids of the category and all its descendants. The caller must
+// hold the lock.
+func (m *Memory) descendants(id uint64) map[uint64]bool {
+	ids := map[uint64]bool{id: true}
+	for added := true; added; {
+		added = false
+		for i := range m.categories {
+			cat := &m.categories[i]
+			if ids[cat.ID] || cat.ParentCode == "" {
+				continue
+			}
+
+			if parent := m.findCategory(cat.ParentCode); parent != nil && ids[parent.ID] {
+				ids[cat.ID] = true
+				added = true
+			}
+		}
+	}
+
+	return ids
+}
+
+// ancestors returns the categories above the category, from the root to its parent. The
+// caller must hold the lock.
+func (m *Memory) ancestors(cat *category.Category) category.Categories {
+	ancestors := make(category.Categories, 0)
+	for code := cat.ParentCode; code != ""; code = m.parentCode(code) {
+		if parent := m.findCategory(code); parent != nil {
+			ancestors = append(ancestors, *parent)
+		}
+	}
+
+	slices.Reverse(ancestors)
+
+	return ancestors
+}
+
+// sameVersion returns true if the stored version matches the expected one. A zero
+// expected version matches any version.
+func sameVersion(stored, expected uint) bool {
+	return expected == 0 || stored == expected
+}
+
+func (m *Memory) findCategory(code string) *category.Category {
+	for i := range m.categories {
+		if m.categories[i].Code == code {
+			return &m.categories[i]
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) findProduct(code string) *productRecord {
+	for i := range m.products {
+		if m.products[i].product.Code == code {
+			return &m.products[i]
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) categoryByID(id uint64) *category.Category {
+	for i := range m.categories {
+		if m.categories[i].ID == id {
+			cat := m.categories[i]
+
+			return &cat
+		}
+	}
+
+	return nil
+}
+
+// toModel returns a copy of the stored product, so the callers can't modify the data
+// kept in the storage.
+func (m *Memory) toModel(rec *productRecord) product.Product {
+	prod := rec.product
+	prod.Attributes = maps.Clone(rec.product.Attributes)
+	prod.Variants = slices.Clone(rec.product.Variants)
+	prod.Prices = slices.Clone(rec.product.Prices)
+	prod.Category = m.categoryByID(rec.categoryID)
+	if prod.Category != nil {
+		prod.Category.Ancestors = m.ancestors(prod.Category)
+	}
+
+	return prod
+}
+
+func (m *Memory) matches(rec *productRecord, filters []filter.Filter) (bool, error) {
+	for i := range filters {
+		ok, err := m.match(rec, &filters[i])
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func (m *Memory) match(rec *productRecord, f *filter.Filter) (bool, error) {
+	if err := f.Validate(); err != nil {
+		return false, err
+	}
+
+	if len(f.And) > 0 {
+		return m.matches(rec, f.And)
+	}
+
+	if len(f.Or) > 0 {
+		// All the filters are evaluated, so the invalid ones are always rejected.
+		matched := false
+		for i := range f.Or {
+			ok, err := m.match(rec, &f.Or[i])
+			if err != nil {
+				return false, err
+			}
+
+			matched = matched || ok
+		}
+
+		return matched, nil
+	}
+
+	switch f.Key {
+	case "id":
+		return matchNumber(f, decimal.NewFromUint64(uint64(rec.product.ID)))
+	case "category":
+		return matchNumber(f, decimal.NewFromUint64(rec.categoryID))
+	case "category_tree":
+		return m.matchCategoryTree(rec, f)
+	case "price":
+		return matchNumber(f, rec.product.ActivePrice(m.now()).Amount)
+	case "currency":
+		return matchText(f, rec.product.ActivePrice(m.now()).Currency), nil
+	case "code":
+		return matchText(f, rec.product.Code), nil
+	case "updated_at":
+		return matchTime(f, rec.product.UpdatedAt)
+	case "on_sale":
+		return m.matchOnSale(rec, f)
+	case "in_stock":
+		return m.matchInStock(rec, f)
+	}
+
+	if code, ok := product.PriceCurrency(f.Key); ok {
+		amount, err := m.convertedPrice(rec, code)
+		if err != nil {
+			return false, err
+		}
+
+		return matchNumber(f, amount)
+	}
+
+	if key, ok := product.AttributeKey(f.Key); ok {
+		// The products without the attribute don't match any comparison, as in the
+		// database.
+		value, found := rec.product.Attributes[key]
+
+		return found && matchText(f, value), nil
+	}
+
+	return false, &filter.FieldError{Field: f.Key}
+}
+
+// matchCategoryTree checks the category of the product is one of the filter categories
+// or one of their descendants. The caller must hold the lock.
+func (m *Memory) matchCategoryTree(rec *productRecord, f *filter.Filter) (bool, error) {
+	if f.Operation != filter.Equal && f.Operation != filter.In {
+		return false, fmt.Errorf("%w: %s can't be applied to %s", filter.ErrInvalidFilter,
+			f.Operation, f.Key)
+	}
+
+	matched := false
+	for _, v := range filterValues(f) {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("%w: invalid %s %q", filter.ErrInvalidFilter, f.Key, v)
+		}
+
+		matched = matched || m.descendants(id)[rec.categoryID]
+	}
+
+	return matched, nil
+}
+
+// matchOnSale checks the product is on sale, or not, with its price valid now.
+func (m *Memory) matchOnSale(rec *productRecord, f *filter.Filter) (bool, error) {
+	active := rec.product.Prices.Active("", m.now())
+
+	return matchBool(f, active != nil && active.OnSale())
+}
+
+// matchInStock checks some variant of the product has units available, or none of them.
+// The caller must hold the lock.
+func (m *Memory) matchInStock(rec *productRecord, f *filter.Filter) (bool, error) {
+	inStock := slices.ContainsFunc(rec.product.Variants, func(v variant.Variant) bool {
+		s := m.stockOf(&v)
+
+		return s.InStock()
+	})
+
+	return matchBool(f, inStock)
+}
+
+// matchBool checks the boolean value is equal, or not equal, to the filter value.
+func matchBool(f *filter.Filter, value bool) (bool, error) {
+	if f.Operation != filter.Equal && f.Operation != filter.NotEqual {
+		return false, fmt.Errorf("%w: %s can't be applied to %s", filter.ErrInvalidFilter,
+			f.Operation, f.Key)
+	}
+
+	want, err := strconv.ParseBool(f.Value)
+	if err != nil {
+		return false, fmt.Errorf("%w: invalid %s %q", filter.ErrInvalidFilter, f.Key, f.Value)
+	}
+
+	return (value == want) == (f.Operation == filter.Equal), nil
+}
+
+// filterValues returns the values of the filter.
+func filterValues(f *filter.Filter) []string {
+	if f.Operation == filter.In || f.Operation == filter.Between {
+		return f.Values
+	}
+
+	return []string{f.Value}
+}
+
+func matchNumber(f *filter.Filter, value decimal.Decimal) (bool, error) {
+	if f.Operation.IsTextOperation() {
+		return false, fmt.Errorf("%w: %s can't be applied to %s", filter.ErrInvalidFilter,
+			f.Operation, f.Key)
+	}
+
+	raw := filterValues(f)
+	values := make([]decimal.Decimal, 0, len(raw))
+	for _, v := range raw {
+		d, err := decimal.NewFromString(v)
+		if err != nil {
+			return false, fmt.Errorf("%w: invalid %s %q", filter.ErrInvalidFilter, f.Key, v)
+		}
+
+		values = append(values, d)
+	}
+
+	if f.Operation == filter.In {
+		return slices.ContainsFunc(values, value.Equal), nil
+	}
+
+	if f.Operation == filter.Between {
+		return value.Cmp(values[0]) >= 0 && value.Cmp(values[1]) <= 0, nil
+	}
+
+	return compare(f.Operation, value.Cmp(values[0])), nil
+}
+
+func matchTime(f *filter.Filter, value time.Time) (bool, error) {
+	if f.Operation.IsTextOperation() {
+		return false, fmt.Errorf("%w: %s can't be applied to %s", filter.ErrInvalidFilter,
+			f.Operation, f.Key)
+	}
+
+	raw := filterValues(f)
+	values := make([]time.Time, 0, len(raw))
+	for _, v := range raw {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return false, fmt.Errorf("%w: invalid %s %q", filter.ErrInvalidFilter, f.Key, v)
+		}
+
+		values = append(values, t)
+	}
+
+	if f.Operation == filter.In {
+		return slices.ContainsFunc(values, value.Equal), nil
+	}
+
+	if f.Operation == filter.Between {
+		return value.Compare(values[0]) >= 0 && value.Compare(values[1]) <= 0, nil
+	}
+
+	return compare(f.Operation, value.Compare(values[0])), nil
+}
+
+func matchText(f *filter.Filter, value string) bool {
+	values := filterValues(f)
+	switch f.Operation {
+	case filter.In:
+		return slices.Contains(values, value)
+	case filter.Between:
+		return value >= values[0] && value <= values[1]
+	case filter.Prefix:
+		return strings.HasPrefix(value, values[0])
+	case filter.Contains:
+		return strings.Contains(value, values[0])
+	default:
+		return compare(f.Operation, strings.Compare(value, values[0]))
+	}
+}
+
+// compare returns the result of a comparison operation given the result of comparing
+// the two values (-1, 0 or 1).
+func compare(op filter.OperationType, cmp int) bool {
+	switch op {
+	case filter.Equal:
+		return cmp == 0
+	case filter.NotEqual:
+		return cmp != 0
+	case filter.LessThan:
+		return cmp < 0
+	case filter.LessOrEqual:
+		return cmp <= 0
+	case filter.GreaterThan:
+		return cmp > 0
+	case filter.GreaterOrEqual:
+		return cmp >= 0
+	default:
+		return false
+	}
+}
+
+// paginate returns the records of the page. The records must be sorted by the columns.
+func paginate(records []sortedRecord, columns []sortColumn,
+	pg page.Page,
+) ([]sortedRecord, error) {
+	start, end := 0, len(records)
+	if pg.Cursor == nil {
+		start = min(max(pg.Offset, 0), end)
+		if pg.Limit >= 0 {
+			end = min(start+pg.Limit, end)
+		}
+
+		return records[start:end], nil
+	}
+
+	keys, err := cursorKeys(columns, pg.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if pg.Cursor.Backward {
+		end = slices.IndexFunc(records, func(r sortedRecord) bool {
+			return compareKeys(columns, r.keys, keys) >= 0
+		})
+		if end < 0 {
+			end = len(records)
+		}
+
+		if pg.Limit >= 0 {
+			start = max(end-pg.Limit, 0)
+		}
+
+		return records[start:end], nil
+	}
+
+	start = slices.IndexFunc(records, func(r sortedRecord) bool {
+		return compareKeys(columns, r.keys, keys) > 0
+	})
+	if start < 0 {
+		start = end
+	}
+
+	if pg.Limit >= 0 {
+		end = min(start+pg.Limit, end)
+	}
+
+	return records[start:end], nil
+}
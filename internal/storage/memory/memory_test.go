@@ -0,0 +1,1167 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/category"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/currency"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/filter"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/inventory"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/page"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/price"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/product"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/search"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/sorting"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/variant"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/storage"
+)
+
+var _ storage.Storage = (*Memory)(nil)
+
+// nolint: funlen
+func TestGetProducts(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		filters       []filter.Filter
+		sorts         []sorting.Sort
+		expectedCodes []string
+		cursor        *page.Cursor
+		expectedTotal int64
+		limit         int
+		offset        int
+		expectedError bool
+	}{
+		{
+			name:  "all products",
+			limit: 10,
+			expectedCodes: []string{
+				"PROD001", "PROD002", "PROD003", "PROD004", "PROD005", "PROD006", "PROD007",
+				"PROD008",
+			},
+			expectedTotal: 8,
+		},
+		{
+			name:          "with limit",
+			limit:         2,
+			expectedCodes: []string{"PROD001", "PROD002"},
+			expectedTotal: 8,
+		},
+		{
+			name:          "with limit and offset",
+			limit:         2,
+			offset:        2,
+			expectedCodes: []string{"PROD003", "PROD004"},
+			expectedTotal: 8,
+		},
+		{
+			name:          "offset out of range",
+			limit:         2,
+			offset:        20,
+			expectedCodes: []string{},
+			expectedTotal: 8,
+		},
+		{
+			name:          "with cursor",
+			limit:         2,
+			cursor:        &page.Cursor{ID: 2},
+			expectedCodes: []string{"PROD003", "PROD004"},
+			expectedTotal: 8,
+		},
+		{
+			name:          "with backward cursor",
+			limit:         2,
+			cursor:        &page.Cursor{ID: 5, Backward: true},
+			expectedCodes: []string{"PROD003", "PROD004"},
+			expectedTotal: 8,
+		},
+		{
+			name:          "with backward cursor at the beginning",
+			limit:         2,
+			cursor:        &page.Cursor{ID: 2, Backward: true},
+			expectedCodes: []string{"PROD001"},
+			expectedTotal: 8,
+		},
+		{
+			name:          "with cursor at the end",
+			limit:         2,
+			cursor:        &page.Cursor{ID: 8},
+			expectedCodes: []string{},
+			expectedTotal: 8,
+		},
+		{
+			name:  "with category and cursor",
+			limit: 10,
+			filters: []filter.Filter{
+				{Key: "category", Value: "1", Operation: filter.Equal},
+			},
+			cursor:        &page.Cursor{ID: 1},
+			expectedCodes: []string{"PROD004", "PROD007"},
+			expectedTotal: 3,
+		},
+		{
+			name:  "with category",
+			limit: 10,
+			filters: []filter.Filter{
+				{Key: "category", Value: "1", Operation: filter.Equal},
+			},
+			expectedCodes: []string{"PROD001", "PROD004", "PROD007"},
+			expectedTotal: 3,
+		},
+		{
+			name:  "with category and price",
+			limit: 10,
+			filters: []filter.Filter{
+				{Key: "category", Value: "1", Operation: filter.Equal},
+				{Key: "price", Value: "15.1", Operation: filter.LessThan},
+			},
+			expectedCodes: []string{"PROD001", "PROD004"},
+			expectedTotal: 2,
+		},
+		{
+			name:  "with price in another currency",
+			limit: 10,
+			filters: []filter.Filter{
+				{Key: "price", Value: "15.1", Operation: filter.LessThan},
+				{Key: "currency", Value: "USD", Operation: filter.Equal},
+			},
+			expectedCodes: []string{},
+			expectedTotal: 0,
+		},
+		{
+			name:  "with price range",
+			limit: 10,
+			filters: []filter.Filter{
+				{Key: "price", Values: []string{"10", "15"}, Operation: filter.Between},
+			},
+			expectedCodes: []string{"PROD001", "PROD002", "PROD004"},
+			expectedTotal: 3,
+		},
+		{
+			name:  "with attribute",
+			limit: 10,
+			filters: []filter.Filter{
+				{Key: "attr.material", Value: "cotton", Operation: filter.Equal},
+				{Key: "price", Value: "15", Operation: filter.GreaterOrEqual},
+			},
+			expectedCodes: []string{"PROD004", "PROD007"},
+			expectedTotal: 2,
+		},
+		{
+			name:  "with missing attribute",
+			limit: 10,
+			filters: []filter.Filter{
+				{Key: "attr.color", Value: "red", Operation: filter.NotEqual},
+			},
+			expectedCodes: []string{},
+			expectedTotal: 0,
+		},
+		{
+			name:  "with codes",
+			limit: 10,
+			filters: []filter.Filter{
+				{Key: "code", Values: []string{"PROD002", "PROD008"}, Operation: filter.In},
+			},
+			expectedCodes: []string{"PROD002", "PROD008"},
+			expectedTotal: 2,
+		},
+		{
+			name:  "with or group",
+			limit: 10,
+			filters: []filter.Filter{
+				filter.AnyOf(
+					filter.Filter{Key: "price", Value: "9", Operation: filter.LessThan},
+					filter.Filter{Key: "code", Value: "PROD00", Operation: filter.Prefix},
+				),
+				{Key: "price", Value: "20", Operation: filter.GreaterOrEqual},
+			},
+			expectedCodes: []string{"PROD005"},
+			expectedTotal: 1,
+		},
+		{
+			name:  "with not equal and contains",
+			limit: 10,
+			filters: []filter.Filter{
+				{Key: "category", Value: "1", Operation: filter.NotEqual},
+				{Key: "code", Value: "D00", Operation: filter.Contains},
+				{Key: "price", Value: "9.99", Operation: filter.LessOrEqual},
+			},
+			expectedCodes: []string{"PROD003", "PROD006", "PROD008"},
+			expectedTotal: 3,
+		},
+		{
+			name:  "text operation on a numeric field",
+			limit: 10,
+			filters: []filter.Filter{
+				{Key: "price", Value: "1", Operation: filter.Prefix},
+			},
+			expectedError: true,
+		},
+		{
+			name:  "invalid value",
+			limit: 10,
+			filters: []filter.Filter{
+				{Key: "price", Value: "cheap", Operation: filter.GreaterThan},
+			},
+			expectedError: true,
+		},
+		{
+			name:  "unknown filter",
+			limit: 10,
+			filters: []filter.Filter{
+				{Key: "unknown", Value: "1", Operation: filter.Equal},
+			},
+			expectedError: true,
+		},
+		{
+			name:  "sorted by price",
+			limit: 10,
+			sorts: []sorting.Sort{{Field: "price"}},
+			expectedCodes: []string{
+				"PROD006", "PROD003", "PROD008", "PROD001", "PROD002", "PROD004", "PROD007",
+				"PROD005",
+			},
+			expectedTotal: 8,
+		},
+		{
+			name:          "sorted by category and code descending",
+			limit:         4,
+			sorts:         []sorting.Sort{{Field: "category"}, {Field: "code", Descending: true}},
+			expectedCodes: []string{"PROD007", "PROD004", "PROD001", "PROD006"},
+			expectedTotal: 8,
+		},
+		{
+			name:          "sorted by price with cursor",
+			limit:         2,
+			sorts:         []sorting.Sort{{Field: "price"}},
+			cursor:        &page.Cursor{Values: []string{"9.99"}, ID: 8},
+			expectedCodes: []string{"PROD001", "PROD002"},
+			expectedTotal: 8,
+		},
+		{
+			name:          "sorted by price with backward cursor",
+			limit:         2,
+			sorts:         []sorting.Sort{{Field: "price"}},
+			cursor:        &page.Cursor{Values: []string{"10.99"}, ID: 1, Backward: true},
+			expectedCodes: []string{"PROD003", "PROD008"},
+			expectedTotal: 8,
+		},
+		{
+			name:          "sorted by category descending with cursor on a tie",
+			limit:         2,
+			sorts:         []sorting.Sort{{Field: "category", Descending: true}},
+			cursor:        &page.Cursor{Values: []string{"3"}, ID: 5},
+			expectedCodes: []string{"PROD008", "PROD002"},
+			expectedTotal: 8,
+		},
+		{
+			name:          "cursor not matching the sort",
+			limit:         2,
+			sorts:         []sorting.Sort{{Field: "price"}},
+			cursor:        &page.Cursor{Values: []string{"cheap"}, ID: 1},
+			expectedError: true,
+		},
+		{
+			name:          "unknown sort",
+			limit:         10,
+			sorts:         []sorting.Sort{{Field: "unknown"}},
+			expectedError: true,
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			m, err := NewWithSampleData(context.TODO())
+			require.NoError(t, err)
+
+			pg := page.Page{Limit: tc.limit, Offset: tc.offset, Cursor: tc.cursor}
+			products, total, err := m.GetProducts(context.TODO(), pg, tc.sorts, tc.filters...)
+			if tc.expectedError {
+				var filterErr *filter.FieldError
+				var sortErr *sorting.FieldError
+				if !errors.As(err, &filterErr) && !errors.As(err, &sortErr) &&
+					!errors.Is(err, page.ErrInvalidCursor) {
+					require.ErrorIs(t, err, filter.ErrInvalidFilter)
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+			codes := make([]string, 0, len(products))
+			for j := range products {
+				codes = append(codes, products[j].Code)
+			}
+
+			assert.Equal(t, tc.expectedCodes, codes)
+			assert.Equal(t, tc.expectedTotal, total)
+		})
+	}
+}
+
+func TestGetProduct(t *testing.T) {
+	t.Parallel()
+	m, err := NewWithSampleData(context.TODO())
+	require.NoError(t, err)
+
+	prod, err := m.GetProduct(context.TODO(), "PROD005")
+	require.NoError(t, err)
+	assert.Equal(t, "CAT003", prod.Category.Code)
+	assert.Len(t, prod.Variants, 6)
+
+	// Modifying the returned product must not modify the stored one.
+	prod.Variants[0].Name = "modified"
+	prod.Category.Name = "modified"
+	prod, err = m.GetProduct(context.TODO(), "PROD005")
+	require.NoError(t, err)
+	assert.Equal(t, "Variant A", prod.Variants[0].Name)
+	assert.Equal(t, "Accessories", prod.Category.Name)
+
+	_, err = m.GetProduct(context.TODO(), "unknown")
+	require.ErrorIs(t, err, product.ErrNotFound)
+}
+
+func TestWriteProducts(t *testing.T) {
+	t.Parallel()
+	ctx := context.TODO()
+	m, err := NewWithSampleData(ctx)
+	require.NoError(t, err)
+
+	prod := product.Product{
+		Code:     "PROD009",
+		Price:    decimal.RequireFromString("19.99"),
+		Category: &category.Category{Code: "CAT002"},
+	}
+	require.NoError(t, m.CreateProduct(ctx, &prod))
+	assert.Equal(t, uint(9), prod.ID)
+	assert.Equal(t, "Shoes", prod.Category.Name)
+
+	duplicated := prod
+	require.ErrorIs(t, m.CreateProduct(ctx, &duplicated), product.ErrDuplicated)
+
+	prod.Category = &category.Category{Code: "CAT009"}
+	require.ErrorIs(t, m.UpdateProduct(ctx, &prod), category.ErrInvalidCategory)
+
+	prod.Price = decimal.RequireFromString("9.99")
+	prod.Category = &category.Category{Code: "CAT001"}
+	require.NoError(t, m.UpdateProduct(ctx, &prod))
+	stored, err := m.GetProduct(ctx, "PROD009")
+	require.NoError(t, err)
+	assert.Equal(t, "9.99", stored.Price.String())
+	assert.Equal(t, "CAT001", stored.Category.Code)
+
+	require.NoError(t, m.DeleteProduct(ctx, "PROD009", 0))
+	require.ErrorIs(t, m.DeleteProduct(ctx, "PROD009", 0), product.ErrNotFound)
+	require.ErrorIs(t, m.UpdateProduct(ctx, &prod), product.ErrNotFound)
+
+	// The removed product is kept, and its code can be reused.
+	require.Len(t, m.removedProducts, 1)
+	assert.Equal(t, "PROD009", m.removedProducts[0].product.Code)
+	assert.False(t, m.removedProducts[0].deletedAt.IsZero())
+
+	reused := product.Product{Code: "PROD009", Name: "Reused", Price: decimal.NewFromInt(1),
+		Category: &category.Category{Code: "CAT001"}}
+	require.NoError(t, m.CreateProduct(ctx, &reused))
+	assert.Greater(t, reused.ID, prod.ID)
+}
+
+func TestWriteVariants(t *testing.T) {
+	t.Parallel()
+	ctx := context.TODO()
+	m, err := NewWithSampleData(ctx)
+	require.NoError(t, err)
+
+	v := variant.Variant{Name: "Variant C", SKU: "SKU002C"}
+	require.NoError(t, m.CreateVariant(ctx, "PROD002", &v))
+	assert.NotZero(t, v.ID)
+	require.ErrorIs(t, m.CreateVariant(ctx, "PROD003", &v), variant.ErrDuplicated)
+	require.ErrorIs(t, m.CreateVariant(ctx, "PROD009", &v), product.ErrNotFound)
+
+	v.Price = decimal.RequireFromString("13.49")
+	require.NoError(t, m.UpdateVariant(ctx, "PROD002", &v))
+	require.ErrorIs(t, m.UpdateVariant(ctx, "PROD003", &v), variant.ErrNotFound)
+
+	prod, err := m.GetProduct(ctx, "PROD002")
+	require.NoError(t, err)
+	require.Len(t, prod.Variants, 3)
+	assert.Equal(t, "13.49", prod.Variants[2].Price.String())
+
+	require.NoError(t, m.DeleteVariant(ctx, "PROD002", "SKU002C", 0))
+	require.ErrorIs(t, m.DeleteVariant(ctx, "PROD002", "SKU002C", 0), variant.ErrNotFound)
+}
+
+func TestCurrencies(t *testing.T) {
+	t.Parallel()
+	ctx := context.TODO()
+	m, err := NewWithSampleData(ctx)
+	require.NoError(t, err)
+
+	// The sample prices are in the default currency.
+	prod, err := m.GetProduct(ctx, "PROD001")
+	require.NoError(t, err)
+	assert.Equal(t, currency.Default, prod.Currency)
+	assert.Equal(t, currency.Default, prod.Variants[0].Currency)
+	assert.Empty(t, prod.Variants[1].Currency)
+
+	prod = &product.Product{
+		Code:     "PROD009",
+		Price:    decimal.RequireFromString("1500"),
+		Currency: "JPY",
+		Category: &category.Category{Code: "CAT002"},
+		Variants: []variant.Variant{
+			{Name: "Variant A", SKU: "SKU009A", Price: decimal.RequireFromString("1600")},
+			{Name: "Variant B", SKU: "SKU009B", Price: decimal.RequireFromString("10.99"),
+				Currency: "USD"},
+		},
+	}
+	require.NoError(t, m.CreateProduct(ctx, prod))
+	assert.Equal(t, "JPY", prod.Variants[0].Currency)
+	assert.Equal(t, "USD", prod.Variants[1].Currency)
+
+	// The prices of the variants without currency have the decimals of the product one.
+	v := variant.Variant{Name: "Variant C", SKU: "SKU009C",
+		Price: decimal.RequireFromString("16.5")}
+	require.ErrorIs(t, m.CreateVariant(ctx, "PROD009", &v), variant.ErrInvalidVariant)
+
+	v.Price = decimal.RequireFromString("1650")
+	require.NoError(t, m.CreateVariant(ctx, "PROD009", &v))
+	assert.Equal(t, "JPY", v.Currency)
+
+	prod.Price = decimal.RequireFromString("15.5")
+	require.ErrorIs(t, m.UpdateProduct(ctx, prod), product.ErrInvalidProduct)
+
+	prod.Currency = "EUR"
+	prod.Version = 0
+	require.NoError(t, m.UpdateProduct(ctx, prod))
+	stored, err := m.GetProduct(ctx, "PROD009")
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", stored.Currency)
+	assert.Equal(t, "JPY", stored.Variants[0].Currency)
+}
+
+func TestPrices(t *testing.T) {
+	t.Parallel()
+	ctx := context.TODO()
+	m, err := NewWithSampleData(ctx)
+	require.NoError(t, err)
+
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	sale := price.Price{
+		Amount:        decimal.RequireFromString("8.99"),
+		CompareAt:     decimal.RequireFromString("10.99"),
+		EffectiveFrom: now,
+		EffectiveTo:   now.Add(24 * time.Hour),
+	}
+	require.NoError(t, m.CreatePrice(ctx, "PROD001", &sale))
+	assert.Equal(t, uint(1), sale.ID)
+	assert.Equal(t, currency.Default, sale.Currency)
+	assert.Equal(t, now, sale.CreatedAt)
+
+	variantPrice := price.Price{SKU: "SKU001A", Amount: decimal.RequireFromString("9.5"),
+		EffectiveFrom: now}
+	require.NoError(t, m.CreatePrice(ctx, "PROD001", &variantPrice))
+
+	unknown := price.Price{SKU: "SKU002A", Amount: decimal.RequireFromString("9.5"),
+		EffectiveFrom: now}
+	require.ErrorIs(t, m.CreatePrice(ctx, "PROD001", &unknown), variant.ErrNotFound)
+	require.ErrorIs(t, m.CreatePrice(ctx, "PROD001", &price.Price{EffectiveFrom: now}),
+		price.ErrInvalidPrice)
+	require.ErrorIs(t, m.CreatePrice(ctx, "PROD009", &sale), product.ErrNotFound)
+
+	// The prices are part of the product.
+	prod, err := m.GetProduct(ctx, "PROD001")
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), prod.Version)
+	assert.Len(t, prod.Prices, 2)
+	assert.Equal(t, "8.99", prod.ActivePrice(now).Amount.String())
+
+	onSale := filter.Filter{Key: "on_sale", Value: "true", Operation: filter.Equal}
+	products, total, err := m.GetProducts(ctx, page.Page{Limit: -1}, nil, onSale)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+	assert.Equal(t, "PROD001", products[0].Code)
+
+	_, total, err = m.GetProducts(ctx, page.Page{Limit: -1}, nil,
+		filter.Filter{Key: "on_sale", Value: "false", Operation: filter.Equal})
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), total)
+
+	_, _, err = m.GetProducts(ctx, page.Page{Limit: -1}, nil,
+		filter.Filter{Key: "on_sale", Value: "maybe", Operation: filter.Equal})
+	require.ErrorIs(t, err, filter.ErrInvalidFilter)
+
+	// The price filter and sort use the sale price.
+	cheap := filter.Filter{Key: "price", Value: "9", Operation: filter.LessThan}
+	byPrice := []sorting.Sort{{Field: "price", Descending: true}}
+	products, _, err = m.GetProducts(ctx, page.Page{Limit: -1}, byPrice, cheap)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PROD001", "PROD003", "PROD006"}, productCodes(products))
+
+	// The sale ends.
+	now = now.Add(24 * time.Hour)
+	_, total, err = m.GetProducts(ctx, page.Page{Limit: -1}, nil, onSale)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), total)
+
+	products, _, err = m.GetProducts(ctx, page.Page{Limit: -1}, byPrice, cheap)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PROD003", "PROD006"}, productCodes(products))
+
+	require.NoError(t, m.DeletePrice(ctx, "PROD001", sale.ID))
+	require.ErrorIs(t, m.DeletePrice(ctx, "PROD001", sale.ID), price.ErrNotFound)
+
+	// The prices of the removed variants are removed too.
+	require.NoError(t, m.DeleteVariant(ctx, "PROD001", "SKU001A", 0))
+	prod, err = m.GetProduct(ctx, "PROD001")
+	require.NoError(t, err)
+	assert.Empty(t, prod.Prices)
+}
+
+func TestStock(t *testing.T) {
+	t.Parallel()
+	ctx := context.TODO()
+	m, err := NewWithSampleData(ctx)
+	require.NoError(t, err)
+
+	stock, err := m.GetStock(ctx, "SKU001A")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), stock.OnHand)
+	assert.False(t, stock.InStock())
+
+	stock, err = m.AdjustStock(ctx, "SKU001A", 5)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), stock.Available())
+
+	_, err = m.AdjustStock(ctx, "SKU001A", -6)
+	require.ErrorIs(t, err, inventory.ErrInsufficientStock)
+	_, err = m.AdjustStock(ctx, "SKU001A", 0)
+	require.ErrorIs(t, err, inventory.ErrInvalidQuantity)
+	_, err = m.AdjustStock(ctx, "SKU009A", 1)
+	require.ErrorIs(t, err, variant.ErrNotFound)
+	_, err = m.GetStock(ctx, "SKU009A")
+	require.ErrorIs(t, err, variant.ErrNotFound)
+
+	stocks, err := m.GetStocks(ctx, []string{"SKU001A", "SKU001B", "SKU009A"})
+	require.NoError(t, err)
+	require.Len(t, stocks, 2)
+	assert.Equal(t, int64(5), stocks[0].OnHand)
+	assert.Equal(t, "SKU001B", stocks[1].SKU)
+
+	// The stock doesn't change the version of the product.
+	inStock := filter.Filter{Key: "in_stock", Value: "true", Operation: filter.Equal}
+	products, total, err := m.GetProducts(ctx, page.Page{Limit: 10}, nil, inStock)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, "PROD001", products[0].Code)
+	assert.Equal(t, uint(1), products[0].Version)
+
+	inStock.Operation = filter.LessThan
+	_, _, err = m.GetProducts(ctx, page.Page{Limit: 10}, nil, inStock)
+	require.ErrorIs(t, err, filter.ErrInvalidFilter)
+
+	// The removal of the product releases the reservations and removes the stock of its
+	// variants.
+	r := inventory.Reservation{ExpiresAt: time.Now().Add(time.Hour),
+		Lines: []inventory.Line{{SKU: "SKU001A", Quantity: 2}}}
+	require.NoError(t, m.CreateReservation(ctx, &r))
+	require.NoError(t, m.DeleteProduct(ctx, "PROD001", 0))
+	res, err := m.GetReservation(ctx, r.ID)
+	require.NoError(t, err)
+	assert.Equal(t, inventory.StatusReleased, res.Status)
+	assert.Empty(t, m.stock)
+}
+
+// nolint: funlen
+func TestReservations(t *testing.T) {
+	t.Parallel()
+	ctx := context.TODO()
+	m, err := NewWithSampleData(ctx)
+	require.NoError(t, err)
+
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	_, err = m.AdjustStock(ctx, "SKU001A", 5)
+	require.NoError(t, err)
+	_, err = m.AdjustStock(ctx, "SKU001B", 2)
+	require.NoError(t, err)
+
+	r := inventory.Reservation{ExpiresAt: now.Add(time.Minute), Lines: []inventory.Line{
+		{SKU: "SKU001B", Quantity: 1}, {SKU: "SKU001A", Quantity: 3},
+	}}
+	require.NoError(t, m.CreateReservation(ctx, &r))
+	assert.Equal(t, uint(1), r.ID)
+	assert.Equal(t, inventory.StatusActive, r.Status)
+	assert.Equal(t, "SKU001A", r.Lines[0].SKU)
+
+	// All the lines are reserved, or none of them.
+	tooMany := inventory.Reservation{ExpiresAt: now.Add(time.Minute), Lines: []inventory.Line{
+		{SKU: "SKU001A", Quantity: 1}, {SKU: "SKU001B", Quantity: 2},
+	}}
+	require.ErrorIs(t, m.CreateReservation(ctx, &tooMany), inventory.ErrInsufficientStock)
+	stock, err := m.GetStock(ctx, "SKU001A")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stock.Reserved)
+
+	unknown := inventory.Reservation{ExpiresAt: now.Add(time.Minute),
+		Lines: []inventory.Line{{SKU: "SKU009A", Quantity: 1}}}
+	require.ErrorIs(t, m.CreateReservation(ctx, &unknown), variant.ErrNotFound)
+
+	// The reserved units can't be removed from the stock.
+	_, err = m.AdjustStock(ctx, "SKU001A", -3)
+	require.ErrorIs(t, err, inventory.ErrInsufficientStock)
+
+	committed, err := m.CommitReservation(ctx, r.ID)
+	require.NoError(t, err)
+	assert.Equal(t, inventory.StatusCommitted, committed.Status)
+	stock, err = m.GetStock(ctx, "SKU001A")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stock.OnHand)
+	assert.Equal(t, int64(0), stock.Reserved)
+
+	_, err = m.CommitReservation(ctx, r.ID)
+	require.ErrorIs(t, err, inventory.ErrReservationClosed)
+	_, err = m.ReleaseReservation(ctx, r.ID)
+	require.ErrorIs(t, err, inventory.ErrReservationClosed)
+	_, err = m.GetReservation(ctx, 9)
+	require.ErrorIs(t, err, inventory.ErrReservationNotFound)
+
+	released := inventory.Reservation{ExpiresAt: now.Add(time.Minute),
+		Lines: []inventory.Line{{SKU: "SKU001A", Quantity: 2}}}
+	require.NoError(t, m.CreateReservation(ctx, &released))
+	res, err := m.ReleaseReservation(ctx, released.ID)
+	require.NoError(t, err)
+	assert.Equal(t, inventory.StatusReleased, res.Status)
+
+	expired := inventory.Reservation{ExpiresAt: now.Add(time.Minute),
+		Lines: []inventory.Line{{SKU: "SKU001A", Quantity: 2}}}
+	require.NoError(t, m.CreateReservation(ctx, &expired))
+	other := inventory.Reservation{ExpiresAt: now.Add(time.Minute),
+		Lines: []inventory.Line{{SKU: "SKU001B", Quantity: 1}}}
+	require.NoError(t, m.CreateReservation(ctx, &other))
+
+	now = now.Add(time.Minute)
+	_, err = m.CommitReservation(ctx, expired.ID)
+	require.ErrorIs(t, err, inventory.ErrReservationExpired)
+
+	// The expired reservations of the variant are released before reserving it again.
+	again := inventory.Reservation{ExpiresAt: now.Add(time.Minute),
+		Lines: []inventory.Line{{SKU: "SKU001A", Quantity: 2}}}
+	require.NoError(t, m.CreateReservation(ctx, &again))
+	res, err = m.GetReservation(ctx, expired.ID)
+	require.NoError(t, err)
+	assert.Equal(t, inventory.StatusExpired, res.Status)
+
+	count, err := m.ExpireReservations(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	res, err = m.GetReservation(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, inventory.StatusExpired, res.Status)
+
+	stock, err = m.GetStock(ctx, "SKU001B")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stock.OnHand)
+	assert.Equal(t, int64(0), stock.Reserved)
+}
+
+func TestConcurrentReservations(t *testing.T) {
+	t.Parallel()
+	ctx := context.TODO()
+	m, err := NewWithSampleData(ctx)
+	require.NoError(t, err)
+
+	const units = 5
+	_, err = m.AdjustStock(ctx, "SKU001A", units)
+	require.NoError(t, err)
+
+	const workers = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	reserved := 0
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := inventory.Reservation{ExpiresAt: time.Now().Add(time.Hour),
+				Lines: []inventory.Line{{SKU: "SKU001A", Quantity: 1}}}
+			err := m.CreateReservation(ctx, &r)
+			if err != nil {
+				assert.ErrorIs(t, err, inventory.ErrInsufficientStock)
+
+				return
+			}
+
+			mu.Lock()
+			reserved++
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, units, reserved)
+	stock, err := m.GetStock(ctx, "SKU001A")
+	require.NoError(t, err)
+	assert.Equal(t, int64(units), stock.Reserved)
+}
+
+func TestTimestamps(t *testing.T) {
+	t.Parallel()
+	ctx := context.TODO()
+	m, err := NewWithSampleData(ctx)
+	require.NoError(t, err)
+
+	// The sample data is created before.
+	created := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	now := created
+	m.now = func() time.Time { return now }
+
+	prod := product.Product{
+		Code:     "PROD009",
+		Price:    decimal.RequireFromString("10"),
+		Category: &category.Category{Code: "CAT001"},
+		Variants: []variant.Variant{{Name: "Variant A", SKU: "SKU009A"}},
+	}
+	require.NoError(t, m.CreateProduct(ctx, &prod))
+	assert.Equal(t, created, prod.CreatedAt)
+	assert.Equal(t, created, prod.UpdatedAt)
+	assert.Equal(t, created, prod.Variants[0].CreatedAt)
+
+	now = created.Add(time.Hour)
+	prod.Price = decimal.RequireFromString("12")
+	require.NoError(t, m.UpdateProduct(ctx, &prod))
+	assert.Equal(t, created, prod.CreatedAt)
+	assert.Equal(t, now, prod.UpdatedAt)
+
+	// The changes of the variants update the product.
+	now = created.Add(2 * time.Hour)
+	v := variant.Variant{Name: "Variant B", SKU: "SKU009B"}
+	require.NoError(t, m.CreateVariant(ctx, "PROD009", &v))
+	stored, err := m.GetProduct(ctx, "PROD009")
+	require.NoError(t, err)
+	assert.Equal(t, now, stored.UpdatedAt)
+	assert.Equal(t, created, stored.Variants[0].UpdatedAt)
+	assert.Equal(t, now, stored.Variants[1].CreatedAt)
+
+	products, total, err := m.GetProducts(ctx, page.Page{Limit: 10}, nil, filter.Filter{
+		Key:       "updated_at",
+		Value:     created.Add(time.Hour).Format(time.RFC3339),
+		Operation: filter.GreaterOrEqual,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, "PROD009", products[0].Code)
+
+	_, _, err = m.GetProducts(ctx, page.Page{Limit: 10}, nil, filter.Filter{
+		Key:       "updated_at",
+		Value:     "yesterday",
+		Operation: filter.GreaterOrEqual,
+	})
+	require.ErrorIs(t, err, filter.ErrInvalidFilter)
+}
+
+func TestVersions(t *testing.T) {
+	t.Parallel()
+	ctx := context.TODO()
+	m, err := NewWithSampleData(ctx)
+	require.NoError(t, err)
+
+	prod, err := m.GetProduct(ctx, "PROD002")
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), prod.Version)
+	assert.Equal(t, uint(1), prod.Variants[0].Version)
+
+	stale := *prod
+	require.NoError(t, m.UpdateProduct(ctx, prod))
+	assert.Equal(t, uint(2), prod.Version)
+	require.ErrorIs(t, m.UpdateProduct(ctx, &stale), product.ErrVersionMismatch)
+	require.ErrorIs(t, m.DeleteProduct(ctx, "PROD002", 1), product.ErrVersionMismatch)
+
+	// The changes of the variants change the version of the product.
+	v := prod.Variants[0]
+	require.NoError(t, m.UpdateVariant(ctx, "PROD002", &v))
+	assert.Equal(t, uint(2), v.Version)
+	require.ErrorIs(t, m.UpdateVariant(ctx, "PROD002", &prod.Variants[0]),
+		variant.ErrVersionMismatch)
+	require.ErrorIs(t, m.DeleteVariant(ctx, "PROD002", v.SKU, 1), variant.ErrVersionMismatch)
+	require.NoError(t, m.DeleteVariant(ctx, "PROD002", v.SKU, 2))
+	require.ErrorIs(t, m.DeleteProduct(ctx, "PROD002", 3), product.ErrVersionMismatch)
+	require.NoError(t, m.DeleteProduct(ctx, "PROD002", 4))
+
+	// The changes of the categories change the version of their products.
+	cat := category.Category{Code: "CAT003", Name: "Bags", Version: 1}
+	require.NoError(t, m.UpdateCategory(ctx, &cat))
+	assert.Equal(t, uint(2), cat.Version)
+	prod, err = m.GetProduct(ctx, "PROD003")
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), prod.Version)
+
+	cat.Version = 1
+	require.ErrorIs(t, m.UpdateCategory(ctx, &cat), category.ErrVersionMismatch)
+	require.ErrorIs(t, m.DeleteCategory(ctx, "CAT003", "CAT001", 1), category.ErrVersionMismatch)
+	require.NoError(t, m.DeleteCategory(ctx, "CAT003", "CAT001", 2))
+	prod, err = m.GetProduct(ctx, "PROD003")
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), prod.Version)
+}
+
+func TestAddCategory(t *testing.T) {
+	t.Parallel()
+	ctx := context.TODO()
+	m := New()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	require.ErrorIs(t, m.AddCategory(ctx, &category.Category{Code: "test"}),
+		category.ErrInvalidCategory)
+	require.ErrorIs(t, m.AddCategory(ctx, &category.Category{Name: "test"}),
+		category.ErrInvalidCategory)
+	require.NoError(t, m.AddCategory(ctx, &category.Category{Code: "test", Name: "test"}))
+	require.ErrorIs(t, m.AddCategory(ctx, &category.Category{Code: "test", Name: "other"}),
+		category.ErrDuplicated)
+
+	categories, err := m.GetAllCategories(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, category.Categories{{
+		CreatedAt: now,
+		UpdatedAt: now,
+		ID:        1,
+		Code:      "test",
+		Name:      "test",
+		Version:   1,
+	}}, categories)
+}
+
+func TestDeleteCategory(t *testing.T) {
+	t.Parallel()
+	ctx := context.TODO()
+	m, err := NewWithSampleData(ctx)
+	require.NoError(t, err)
+
+	require.ErrorIs(t, m.DeleteCategory(ctx, "CAT002", "", 0), category.ErrInUse)
+	require.ErrorIs(t, m.DeleteCategory(ctx, "CAT002", "CAT009", 0), category.ErrInvalidCategory)
+	require.ErrorIs(t, m.DeleteCategory(ctx, "CAT009", "", 0), category.ErrNotFound)
+	require.NoError(t, m.DeleteCategory(ctx, "CAT002", "CAT001", 0))
+
+	_, err = m.GetCategory(ctx, "CAT002")
+	require.ErrorIs(t, err, category.ErrNotFound)
+	prod, err := m.GetProduct(ctx, "PROD002")
+	require.NoError(t, err)
+	assert.Equal(t, "CAT001", prod.Category.Code)
+}
+
+func TestCategoryTree(t *testing.T) {
+	t.Parallel()
+	ctx := context.TODO()
+	m, err := NewWithSampleData(ctx)
+	require.NoError(t, err)
+
+	// Clothing > Shirts > Polos
+	require.ErrorIs(t, m.AddCategory(ctx, &category.Category{Code: "CAT004", Name: "Shirts",
+		ParentCode: "CAT009"}), category.ErrInvalidCategory)
+	require.NoError(t, m.AddCategory(ctx, &category.Category{Code: "CAT004", Name: "Shirts",
+		ParentCode: "CAT001"}))
+	require.NoError(t, m.AddCategory(ctx, &category.Category{Code: "CAT005", Name: "Polos",
+		ParentCode: "CAT004"}))
+
+	polos, err := m.GetCategory(ctx, "CAT005")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CAT001", "CAT004"},
+		[]string{polos.Ancestors[0].Code, polos.Ancestors[1].Code})
+
+	// The categories can't be moved under themselves or their descendants.
+	for _, parent := range []string{"CAT001", "CAT004", "CAT005"} {
+		err = m.UpdateCategory(ctx, &category.Category{Code: "CAT001", Name: "Clothing",
+			ParentCode: parent})
+		require.ErrorIs(t, err, category.ErrCycle)
+	}
+
+	require.ErrorIs(t, m.DeleteCategory(ctx, "CAT004", "", 0), category.ErrInUse)
+
+	// Moving a category changes the products of its descendants.
+	prod, err := m.GetProduct(ctx, "PROD001")
+	require.NoError(t, err)
+	prod.Category = &category.Category{Code: "CAT005"}
+	require.NoError(t, m.UpdateProduct(ctx, prod))
+	require.NoError(t, m.UpdateCategory(ctx, &category.Category{Code: "CAT004", Name: "Tops",
+		ParentCode: "CAT003"}))
+
+	moved, err := m.GetProduct(ctx, "PROD001")
+	require.NoError(t, err)
+	assert.Equal(t, prod.Version+1, moved.Version)
+	assert.Equal(t, category.Categories{{Code: "CAT003", Name: "Accessories"},
+		{Code: "CAT004", Name: "Tops", ParentCode: "CAT003"}},
+		withoutMetadata(moved.Category.Ancestors))
+
+	products, _, err := m.GetProducts(ctx, page.Page{Limit: 10}, nil, filter.Filter{
+		Key: "category_tree", Value: "3", Operation: filter.Equal,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PROD001", "PROD003", "PROD005", "PROD008"},
+		productCodes(products))
+}
+
+// productCodes returns the codes of the products.
+func productCodes(products []product.Product) []string {
+	codes := make([]string, 0, len(products))
+	for i := range products {
+		codes = append(codes, products[i].Code)
+	}
+
+	return codes
+}
+
+// withoutMetadata returns the categories without their ids, timestamps and versions.
+func withoutMetadata(cats category.Categories) category.Categories {
+	res := make(category.Categories, 0, len(cats))
+	for _, cat := range cats {
+		res = append(res, category.Category{Code: cat.Code, Name: cat.Name,
+			ParentCode: cat.ParentCode})
+	}
+
+	return res
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	t.Parallel()
+	ctx := context.TODO()
+	m, err := NewWithSampleData(ctx)
+	require.NoError(t, err)
+
+	const workers = 10
+	var wg sync.WaitGroup
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			code := fmt.Sprintf("CONC%03d", i)
+			assert.NoError(t, m.AddCategory(ctx, &category.Category{Code: code, Name: code}))
+			_, _, err := m.GetProducts(ctx, page.Page{Limit: 10}, nil)
+			assert.NoError(t, err)
+			_, err = m.GetAllCategories(ctx)
+			assert.NoError(t, err)
+		}()
+	}
+
+	wg.Wait()
+	categories, err := m.GetAllCategories(ctx)
+	require.NoError(t, err)
+	assert.Len(t, categories, workers+3)
+}
+
+func TestCancelledContext(t *testing.T) {
+	t.Parallel()
+	m, err := NewWithSampleData(context.TODO())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+
+	_, _, err = m.GetProducts(ctx, page.Page{Limit: 10}, nil)
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = m.GetProduct(ctx, "PROD001")
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = m.GetAllCategories(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+	err = m.AddCategory(ctx, &category.Category{Code: "test", Name: "test"})
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestWithTx(t *testing.T) {
+	t.Parallel()
+	ctx := context.TODO()
+	m, err := NewWithSampleData(ctx)
+	require.NoError(t, err)
+
+	errRollback := errors.New("rollback")
+	err = m.WithTx(ctx, func(tx storage.Storage) error {
+		require.NoError(t, tx.AddCategory(ctx, &category.Category{Code: "CAT004", Name: "Bags"}))
+
+		// The nested transaction is rolled back on its own.
+		err := tx.WithTx(ctx, func(nested storage.Storage) error {
+			require.NoError(t, nested.DeleteProduct(ctx, "PROD001", 0))
+
+			return errRollback
+		})
+		require.ErrorIs(t, err, errRollback)
+
+		return tx.DeleteProduct(ctx, "PROD002", 0)
+	})
+	require.NoError(t, err)
+
+	_, err = m.GetCategory(ctx, "CAT004")
+	require.NoError(t, err)
+	_, err = m.GetProduct(ctx, "PROD001")
+	require.NoError(t, err)
+	_, err = m.GetProduct(ctx, "PROD002")
+	require.ErrorIs(t, err, product.ErrNotFound)
+
+	err = m.WithTx(ctx, func(tx storage.Storage) error {
+		require.NoError(t, tx.DeleteProduct(ctx, "PROD003", 0))
+
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+
+	assert.Panics(t, func() {
+		_ = m.WithTx(ctx, func(tx storage.Storage) error {
+			require.NoError(t, tx.DeleteProduct(ctx, "PROD003", 0))
+			panic("failure")
+		})
+	})
+
+	_, err = m.GetProduct(ctx, "PROD003")
+	require.NoError(t, err)
+
+	// The concurrent writes wait for the transaction, instead of making it fail.
+	done := make(chan error)
+	err = m.WithTx(ctx, func(tx storage.Storage) error {
+		go func() {
+			done <- m.DeleteProduct(ctx, "PROD004", 0)
+		}()
+
+		return tx.DeleteProduct(ctx, "PROD003", 0)
+	})
+	require.NoError(t, err)
+	require.NoError(t, <-done)
+
+	for _, code := range []string{"PROD003", "PROD004"} {
+		_, err = m.GetProduct(ctx, code)
+		require.ErrorIs(t, err, product.ErrNotFound)
+	}
+
+	// The failed writes don't change the version.
+	version := m.version
+	require.ErrorIs(t, m.DeleteProduct(ctx, "PROD004", 0), product.ErrNotFound)
+	assert.Equal(t, version, m.version)
+}
+
+// nolint: funlen
+func TestSearchProducts(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name            string
+		query           string
+		filters         []filter.Filter
+		expectedCodes   []string
+		expectedSnippet string
+		expectedError   error
+		expectedTotal   int64
+		limit           int
+		offset          int
+	}{
+		{
+			name:            "single word",
+			query:           "scarf",
+			limit:           10,
+			expectedCodes:   []string{"PROD005"},
+			expectedSnippet: "Wool <mark>Scarf</mark> Warm knitted <mark>scarf</mark> for the winter.",
+			expectedTotal:   1,
+		},
+		{
+			name:            "sorted by relevance",
+			query:           "SHOES",
+			limit:           10,
+			expectedCodes:   []string{"PROD002", "PROD006"},
+			expectedSnippet: "Running <mark>Shoes</mark> Lightweight <mark>shoes</mark> for road running.",
+			expectedTotal:   2,
+		},
+		{
+			name:          "variant sku",
+			query:         "sku004b",
+			limit:         10,
+			expectedCodes: []string{"PROD004"},
+			expectedTotal: 1,
+		},
+		{
+			name:          "all the words",
+			query:         `"wool scarf" winter`,
+			limit:         10,
+			expectedCodes: []string{"PROD005"},
+			expectedTotal: 1,
+		},
+		{
+			name:          "alternatives",
+			query:         "belt or cap",
+			limit:         10,
+			expectedCodes: []string{"PROD003", "PROD008"},
+			expectedTotal: 2,
+		},
+		{
+			name:          "excluded word",
+			query:         "shoes -running",
+			limit:         10,
+			expectedCodes: []string{"PROD006"},
+			expectedTotal: 1,
+		},
+		{
+			name:          "with filters",
+			query:         "shoes or scarf",
+			filters:       []filter.Filter{{Key: "category", Value: "3", Operation: filter.Equal}},
+			limit:         10,
+			expectedCodes: []string{"PROD005"},
+			expectedTotal: 1,
+		},
+		{
+			name:          "with offset",
+			query:         "shoes",
+			limit:         1,
+			offset:        1,
+			expectedCodes: []string{"PROD006"},
+			expectedTotal: 2,
+		},
+		{
+			name:          "no matches",
+			query:         "umbrella",
+			limit:         10,
+			expectedCodes: []string{},
+		},
+		{
+			name:          "blank query",
+			query:         "  ",
+			limit:         10,
+			expectedError: search.ErrInvalidQuery,
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			m, err := NewWithSampleData(context.TODO())
+			require.NoError(t, err)
+
+			pg := page.Page{Limit: tc.limit, Offset: tc.offset}
+			results, total, err := m.SearchProducts(context.TODO(), tc.query, pg, tc.filters...)
+			if tc.expectedError != nil {
+				require.ErrorIs(t, err, tc.expectedError)
+
+				return
+			}
+
+			require.NoError(t, err)
+			codes := make([]string, 0, len(results))
+			for j := range results {
+				codes = append(codes, results[j].Product.Code)
+			}
+
+			assert.Equal(t, tc.expectedCodes, codes)
+			assert.Equal(t, tc.expectedTotal, total)
+			if tc.expectedSnippet != "" {
+				assert.Equal(t, tc.expectedSnippet, results[0].Snippet)
+			}
+		})
+	}
+}
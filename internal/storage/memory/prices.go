@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/currency"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/price"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/product"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/variant"
+)
+
+// SetRates sets the source of the exchange rates used to compare the prices in different
+// currencies. Without rates, only the prices in the same currency can be compared.
+func (m *Memory) SetRates(rates func() *currency.Rates) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rates = rates
+}
+
+// currentRates returns the last exchange rates of the source, or nil without source.
+func (m *Memory) currentRates() *currency.Rates {
+	if m.rates == nil {
+		return nil
+	}
+
+	return m.rates()
+}
+
+// convertedPrice returns the price of the product valid now converted to the currency.
+func (m *Memory) convertedPrice(rec *productRecord, to string) (decimal.Decimal, error) {
+	active := rec.product.ActivePrice(m.now())
+
+	return m.currentRates().Convert(active.Amount, active.Currency, to)
+}
+
+// checkRates checks the prices of all the products can be converted to the currencies of
+// the converted price fields, so the filters and the sorts don't leave out any product,
+// as in the database. It returns currency.ErrUnknownRate otherwise. The caller must hold
+// the lock.
+func (m *Memory) checkRates(fields ...string) error {
+	for _, field := range fields {
+		code, ok := product.PriceCurrency(field)
+		if !ok {
+			continue
+		}
+
+		for i := range m.products {
+			if _, err := m.convertedPrice(&m.products[i], code); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// CreatePrice adds a scheduled price to the product with the given code, or to its
+// variant if the price has a SKU. A price without currency gets the currency of the
+// product. The price is updated with the stored data.
+func (m *Memory) CreatePrice(ctx context.Context, productCode string, p *price.Price) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("unable to create the price of %s: %w", productCode, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rec := m.findProduct(productCode)
+	if rec == nil {
+		return product.ErrNotFound
+	}
+
+	p.SetDefaultCurrency(rec.product.Currency)
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	if p.SKU != "" && variantIndex(rec, p.SKU) < 0 {
+		return variant.ErrNotFound
+	}
+
+	now := m.now()
+	m.lastPriceID++
+	p.ID = m.lastPriceID
+	p.CreatedAt = now
+	rec.product.Prices = append(rec.product.Prices, *p)
+	touchProduct(rec, now)
+
+	m.version++
+
+	return nil
+}
+
+// DeletePrice removes the scheduled price with the given id from the product.
+func (m *Memory) DeletePrice(ctx context.Context, productCode string, id uint) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("unable to delete the price %d: %w", id, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rec := m.findProduct(productCode)
+	if rec == nil {
+		return product.ErrNotFound
+	}
+
+	idx := slices.IndexFunc(rec.product.Prices, func(p price.Price) bool {
+		return p.ID == id
+	})
+	if idx < 0 {
+		return price.ErrNotFound
+	}
+
+	rec.product.Prices = slices.Delete(rec.product.Prices, idx, idx+1)
+	touchProduct(rec, m.now())
+
+	m.version++
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/category"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/product"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/variant"
+)
+
+// NewWithSampleData returns a new in-memory storage loaded with the same sample data
+// as the seed files of the sql/seed directory.
+func NewWithSampleData(ctx context.Context) (*Memory, error) {
+	m := New()
+	categories := []category.Category{
+		{Code: "CAT001", Name: "Clothing"},
+		{Code: "CAT002", Name: "Shoes"},
+		{Code: "CAT003", Name: "Accessories"},
+	}
+	for i := range categories {
+		if err := m.AddCategory(ctx, &categories[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	products := sampleProducts()
+	for i := range products {
+		if err := m.CreateProduct(ctx, &products[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// nolint: funlen
+func sampleProducts() []product.Product {
+	return []product.Product{
+		{
+			Code:        "PROD001",
+			Name:        "Classic T-Shirt",
+			Description: "Short sleeve t-shirt with a crew neck.",
+			Attributes:  map[string]string{"material": "cotton"},
+			Price:       decimal.RequireFromString("10.99"),
+			Category:    &category.Category{Code: "CAT001"},
+			Variants: []variant.Variant{
+				{Name: "Variant A", SKU: "SKU001A", Price: decimal.RequireFromString("11.99")},
+				{Name: "Variant B", SKU: "SKU001B"},
+				{Name: "Variant C", SKU: "SKU001C"},
+			},
+		},
+		{
+			Code:        "PROD002",
+			Name:        "Running Shoes",
+			Description: "Lightweight shoes for road running.",
+			Attributes:  map[string]string{"material": "mesh"},
+			Price:       decimal.RequireFromString("12.49"),
+			Category:    &category.Category{Code: "CAT002"},
+			Variants: []variant.Variant{
+				{Name: "Variant A", SKU: "SKU002A"},
+				{Name: "Variant B", SKU: "SKU002B"},
+			},
+		},
+		{
+			Code:        "PROD003",
+			Name:        "Leather Belt",
+			Description: "Adjustable belt with a metal buckle.",
+			Attributes:  map[string]string{"material": "leather"},
+			Price:       decimal.RequireFromString("8.75"),
+			Category:    &category.Category{Code: "CAT003"},
+			Variants: []variant.Variant{
+				{Name: "Variant A", SKU: "SKU003A", Price: decimal.RequireFromString("8.99")},
+			},
+		},
+		{
+			Code:        "PROD004",
+			Name:        "Denim Jeans",
+			Description: "Slim fit jeans with five pockets.",
+			Attributes:  map[string]string{"material": "cotton"},
+			Price:       decimal.RequireFromString("15.00"),
+			Category:    &category.Category{Code: "CAT001"},
+			Variants: []variant.Variant{
+				{Name: "Variant A", SKU: "SKU004A", Price: decimal.RequireFromString("15.50")},
+				{Name: "Variant B", SKU: "SKU004B", Price: decimal.RequireFromString("16.00")},
+				{Name: "Variant C", SKU: "SKU004C"},
+				{Name: "Variant D", SKU: "SKU004D", Price: decimal.RequireFromString("16.99")},
+			},
+		},
+		{
+			Code:        "PROD005",
+			Name:        "Wool Scarf",
+			Description: "Warm knitted scarf for the winter.",
+			Attributes:  map[string]string{"material": "wool"},
+			Price:       decimal.RequireFromString("22.99"),
+			Category:    &category.Category{Code: "CAT003"},
+			Variants: []variant.Variant{
+				{Name: "Variant A", SKU: "SKU005A", Price: decimal.RequireFromString("23.99")},
+				{Name: "Variant B", SKU: "SKU005B"},
+				{Name: "Variant C", SKU: "SKU005C"},
+				{Name: "Variant D", SKU: "SKU005D", Price: decimal.RequireFromString("22.99")},
+				{Name: "Variant E", SKU: "SKU005E", Price: decimal.RequireFromString("23.49")},
+				{Name: "Variant F", SKU: "SKU005F"},
+			},
+		},
+		{
+			Code:        "PROD006",
+			Name:        "Flip Flops",
+			Description: "Rubber sandals for the beach.",
+			Attributes:  map[string]string{"material": "rubber"},
+			Price:       decimal.RequireFromString("5.50"),
+			Category:    &category.Category{Code: "CAT002"},
+		},
+		{
+			Code:        "PROD007",
+			Name:        "Hooded Sweatshirt",
+			Description: "Fleece hoodie with a front pocket.",
+			Attributes:  map[string]string{"material": "cotton"},
+			Price:       decimal.RequireFromString("18.20"),
+			Category:    &category.Category{Code: "CAT001"},
+			Variants: []variant.Variant{
+				{Name: "Variant A", SKU: "SKU007A"},
+				{Name: "Variant B", SKU: "SKU007B"},
+				{Name: "Variant C", SKU: "SKU007C"},
+				{Name: "Variant D", SKU: "SKU007D"},
+				{Name: "Variant E", SKU: "SKU007E", Price: decimal.RequireFromString("18.75")},
+			},
+		},
+		{
+			Code:        "PROD008",
+			Name:        "Baseball Cap",
+			Description: "Adjustable cap with a curved brim.",
+			Attributes:  map[string]string{"material": "cotton"},
+			Price:       decimal.RequireFromString("9.99"),
+			Category:    &category.Category{Code: "CAT003"},
+			Variants: []variant.Variant{
+				{Name: "Variant A", SKU: "SKU008A", Price: decimal.RequireFromString("10.49")},
+			},
+		},
+	}
+}
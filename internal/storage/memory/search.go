@@ -0,0 +1,281 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/filter"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/page"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/search"
+)
+
+// Weights of the fields of the products in the search ranking, the same as the default
+// weights of postgres: the code and the name, the category and the variants, and the
+// description.
+const (
+	weightA = 1.0
+	weightB = 0.4
+	weightC = 0.2
+)
+
+// searchQuery is a parsed search query. It matches if any of its groups match.
+type searchQuery []searchGroup
+
+// searchGroup matches if all its included terms match and none of the excluded ones.
+type searchGroup struct {
+	include []string
+	exclude []string
+}
+
+// searchDocument has the terms of the fields of a product, by weight.
+type searchDocument struct {
+	a, b, c []string
+}
+
+// rankedRecord is a product record matching a search, with its rank.
+type rankedRecord struct {
+	record *productRecord
+	rank   float64
+}
+
+// SearchProducts returns the page of products matching the full text search query and
+// the filters, sorted by relevance, with the total number of matching products. The
+// query has the web search syntax (or between the alternatives and - to exclude words),
+// and the phrases match their words in any order. The words are compared without case
+// and plural, as a rough approximation of the stemming of the database. The searches are
+// only paginated by offset.
+func (m *Memory) SearchProducts(ctx context.Context, query string, pg page.Page,
+	filters ...filter.Filter,
+) ([]search.Result, int64, error) {
+	if err := search.ValidateQuery(query); err != nil {
+		return nil, 0, err
+	}
+
+	if pg.Cursor != nil {
+		return nil, 0, fmt.Errorf("%w: the searches are paginated by offset",
+			page.ErrInvalidCursor)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, 0, fmt.Errorf("unable to search the products: %w", err)
+	}
+
+	q := parseQuery(query)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if err := m.checkRates(filter.Keys(filters...)...); err != nil {
+		return nil, 0, fmt.Errorf("unable to search the products: %w", err)
+	}
+
+	matching := make([]rankedRecord, 0)
+	for i := range m.products {
+		rec := &m.products[i]
+		ok, err := m.matches(rec, filters)
+		if err != nil {
+			return nil, 0, fmt.Errorf("unable to search the products: %w", err)
+		}
+
+		if !ok {
+			continue
+		}
+
+		if rank, found := q.rank(m.searchDocument(rec)); found {
+			matching = append(matching, rankedRecord{record: rec, rank: rank})
+		}
+	}
+
+	slices.SortFunc(matching, func(a, b rankedRecord) int {
+		if c := cmp.Compare(b.rank, a.rank); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(a.record.product.ID, b.record.product.ID)
+	})
+
+	start := min(max(pg.Offset, 0), len(matching))
+	end := len(matching)
+	if pg.Limit >= 0 {
+		end = min(start+pg.Limit, end)
+	}
+
+	terms := q.terms()
+	results := make([]search.Result, 0, end-start)
+	for i := start; i < end; i++ {
+		prod := m.toModel(matching[i].record)
+		results = append(results, search.Result{
+			Product: prod,
+			Snippet: highlight(joinText(prod.Name, prod.Description), terms),
+		})
+	}
+
+	return results, int64(len(matching)), nil
+}
+
+// searchDocument returns the search terms of the product. The caller must hold the lock.
+func (m *Memory) searchDocument(rec *productRecord) searchDocument {
+	doc := searchDocument{
+		a: searchTerms(joinText(rec.product.Code, rec.product.Name)),
+		c: searchTerms(rec.product.Description),
+	}
+	if cat := m.categoryByID(rec.categoryID); cat != nil {
+		doc.b = searchTerms(cat.Name)
+	}
+
+	for i := range rec.product.Variants {
+		v := &rec.product.Variants[i]
+		doc.b = append(doc.b, searchTerms(joinText(v.Name, v.SKU))...)
+	}
+
+	return doc
+}
+
+// parseQuery parses a search query with the web search syntax.
+func parseQuery(query string) searchQuery {
+	q := searchQuery{{}}
+	for _, word := range strings.Fields(query) {
+		if strings.EqualFold(word, "or") {
+			q = append(q, searchGroup{})
+
+			continue
+		}
+
+		group := &q[len(q)-1]
+		if rest, ok := strings.CutPrefix(word, "-"); ok {
+			group.exclude = append(group.exclude, searchTerms(rest)...)
+		} else {
+			group.include = append(group.include, searchTerms(word)...)
+		}
+	}
+
+	return slices.DeleteFunc(q, func(g searchGroup) bool {
+		return len(g.include) == 0 && len(g.exclude) == 0
+	})
+}
+
+// rank returns the rank of the document, the best rank of the matching groups, and
+// false if no group matches.
+func (q searchQuery) rank(doc searchDocument) (float64, bool) {
+	best, found := 0.0, false
+	for _, group := range q {
+		if slices.ContainsFunc(group.exclude, doc.contains) {
+			continue
+		}
+
+		rank := 0.0
+		matched := true
+		for _, term := range group.include {
+			weight := doc.weight(term)
+			matched = matched && weight > 0
+			rank += weight
+		}
+
+		if matched && (!found || rank > best) {
+			best, found = rank, true
+		}
+	}
+
+	return best, found
+}
+
+// terms returns the set of included terms of the query.
+func (q searchQuery) terms() map[string]bool {
+	terms := make(map[string]bool)
+	for _, group := range q {
+		for _, term := range group.include {
+			terms[term] = true
+		}
+	}
+
+	return terms
+}
+
+// weight returns the weight of the most relevant field with the term, or 0 if no field
+// has it.
+func (d *searchDocument) weight(term string) float64 {
+	switch {
+	case slices.Contains(d.a, term):
+		return weightA
+	case slices.Contains(d.b, term):
+		return weightB
+	case slices.Contains(d.c, term):
+		return weightC
+	default:
+		return 0
+	}
+}
+
+func (d *searchDocument) contains(term string) bool {
+	return d.weight(term) > 0
+}
+
+// highlight encloses the words of the text matching the terms with the highlight marks.
+func highlight(text string, terms map[string]bool) string {
+	var b strings.Builder
+	start := -1
+	writeWord := func(word string) {
+		if terms[searchTerm(word)] {
+			b.WriteString(search.HighlightStart + word + search.HighlightStop)
+		} else {
+			b.WriteString(word)
+		}
+	}
+
+	for i, r := range text {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+
+			continue
+		}
+
+		if start >= 0 {
+			writeWord(text[start:i])
+			start = -1
+		}
+
+		b.WriteRune(r)
+	}
+
+	if start >= 0 {
+		writeWord(text[start:])
+	}
+
+	return b.String()
+}
+
+// searchTerms returns the search terms of the words of the text.
+func searchTerms(text string) []string {
+	words := strings.FieldsFunc(text, func(r rune) bool { return !isWordRune(r) })
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, searchTerm(word))
+	}
+
+	return terms
+}
+
+// searchTerm returns the search term of a word: in lower case and without the plural.
+func searchTerm(word string) string {
+	term := strings.ToLower(word)
+	if len(term) > 3 && strings.HasSuffix(term, "s") && !strings.HasSuffix(term, "ss") {
+		term = term[:len(term)-1]
+	}
+
+	return term
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// joinText joins the non empty texts with spaces.
+func joinText(texts ...string) string {
+	return strings.Join(slices.DeleteFunc(texts, func(s string) bool { return s == "" }), " ")
+}
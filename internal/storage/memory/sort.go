@@ -0,0 +1,143 @@
+package memory
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/page"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/product"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/sorting"
+)
+
+// sortColumn is a field used to sort the products.
+type sortColumn struct {
+	field      string
+	numeric    bool
+	descending bool
+}
+
+// sortKey is the value of a sort field of a product.
+type sortKey struct {
+	text   string
+	number decimal.Decimal
+}
+
+// sortedRecord is a product record with the values of its sort fields.
+type sortedRecord struct {
+	record *productRecord
+	keys   []sortKey
+}
+
+// sortColumns returns the columns to sort the products, with the id as the last one to
+// break the ties, so the order is deterministic.
+func sortColumns(sorts []sorting.Sort) ([]sortColumn, error) {
+	columns := make([]sortColumn, 0, len(sorts)+1)
+	hasID := false
+	for i := range sorts {
+		col := sortColumn{field: sorts[i].Field, descending: sorts[i].Descending}
+		switch col.field {
+		case "id", "category", "price":
+			col.numeric = true
+		case "code":
+		default:
+			if _, ok := product.PriceCurrency(col.field); !ok {
+				return nil, &sorting.FieldError{Field: col.field}
+			}
+
+			col.numeric = true
+		}
+
+		hasID = hasID || col.field == "id"
+		columns = append(columns, col)
+	}
+
+	if !hasID {
+		columns = append(columns, sortColumn{field: "id", numeric: true})
+	}
+
+	return columns, nil
+}
+
+// recordKeys returns the values of the sort columns of the record, with the price valid
+// now, converted to the currency of the converted price columns. The caller must hold
+// the lock.
+func (m *Memory) recordKeys(rec *productRecord, columns []sortColumn) ([]sortKey, error) {
+	keys := make([]sortKey, 0, len(columns))
+	for i := range columns {
+		switch columns[i].field {
+		case "id":
+			keys = append(keys, sortKey{number: decimal.NewFromUint64(uint64(rec.product.ID))})
+		case "category":
+			keys = append(keys, sortKey{number: decimal.NewFromUint64(rec.categoryID)})
+		case "price":
+			keys = append(keys, sortKey{number: rec.product.ActivePrice(m.now()).Amount})
+		case "code":
+			keys = append(keys, sortKey{text: rec.product.Code})
+		default:
+			code, _ := product.PriceCurrency(columns[i].field)
+			amount, err := m.convertedPrice(rec, code)
+			if err != nil {
+				return nil, err
+			}
+
+			keys = append(keys, sortKey{number: amount})
+		}
+	}
+
+	return keys, nil
+}
+
+// cursorKeys returns the values of the sort columns of the cursor. The last column is
+// the id if it was added to break the ties.
+func cursorKeys(columns []sortColumn, cur *page.Cursor) ([]sortKey, error) {
+	if len(cur.Values) != len(columns) && len(cur.Values) != len(columns)-1 {
+		return nil, fmt.Errorf("%w: the cursor doesn't match the sort", page.ErrInvalidCursor)
+	}
+
+	keys := make([]sortKey, 0, len(columns))
+	for i := range columns {
+		if i == len(cur.Values) {
+			keys = append(keys, sortKey{number: decimal.NewFromUint64(uint64(cur.ID))})
+
+			break
+		}
+
+		if !columns[i].numeric {
+			keys = append(keys, sortKey{text: cur.Values[i]})
+
+			continue
+		}
+
+		d, err := decimal.NewFromString(cur.Values[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid value %q", page.ErrInvalidCursor, cur.Values[i])
+		}
+
+		keys = append(keys, sortKey{number: d})
+	}
+
+	return keys, nil
+}
+
+// compareKeys compares the sort values of two products, returning -1, 0 or 1 if a goes
+// before, with or after b.
+func compareKeys(columns []sortColumn, a, b []sortKey) int {
+	for i := range columns {
+		res := strings.Compare(a[i].text, b[i].text)
+		if columns[i].numeric {
+			res = a[i].number.Cmp(b[i].number)
+		}
+
+		if columns[i].descending {
+			res = -res
+		}
+
+		if res != 0 {
+			return res
+		}
+	}
+
+	return 0
+}
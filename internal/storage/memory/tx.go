@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/inventory"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/storage"
+)
+
+// WithTx runs fn in a transaction. The storage passed to fn is a copy of the current
+// data, which replaces the data of the storage if fn succeeds and modifies it. If fn
+// fails or panics the copy is discarded. The transactions hold the write lock while fn
+// runs, so they are serialized with the other writes and the reads never see them
+// partially applied. fn must only use the storage it receives.
+func (m *Memory) WithTx(ctx context.Context, fn func(tx storage.Storage) error) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("unable to start the transaction: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tx := m.clone()
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	tx.mu.RLock()
+	defer tx.mu.RUnlock()
+
+	if tx.version == m.version {
+		return nil
+	}
+
+	m.version = tx.version
+	m.categories = tx.categories
+	m.products = tx.products
+	m.removedProducts = tx.removedProducts
+	m.lastCategory = tx.lastCategory
+	m.lastProductID = tx.lastProductID
+	m.lastVariantID = tx.lastVariantID
+	m.lastPriceID = tx.lastPriceID
+	m.stock = tx.stock
+	m.reservations = tx.reservations
+	m.lastReservationID = tx.lastReservationID
+
+	return nil
+}
+
+// clone returns a deep copy of the storage data. The caller must hold the lock.
+func (m *Memory) clone() *Memory {
+	products := make([]productRecord, 0, len(m.products))
+	for i := range m.products {
+		rec := m.products[i]
+		rec.product.Variants = slices.Clone(rec.product.Variants)
+		rec.product.Prices = slices.Clone(rec.product.Prices)
+		products = append(products, rec)
+	}
+
+	reservations := make([]inventory.Reservation, 0, len(m.reservations))
+	for i := range m.reservations {
+		reservations = append(reservations, cloneReservation(&m.reservations[i]))
+	}
+
+	return &Memory{
+		categories:        slices.Clone(m.categories),
+		products:          products,
+		removedProducts:   slices.Clone(m.removedProducts),
+		lastCategory:      m.lastCategory,
+		lastProductID:     m.lastProductID,
+		lastVariantID:     m.lastVariantID,
+		lastPriceID:       m.lastPriceID,
+		stock:             maps.Clone(m.stock),
+		reservations:      reservations,
+		lastReservationID: m.lastReservationID,
+		version:           m.version,
+		now:               m.now,
+		rates:             m.rates,
+	}
+}
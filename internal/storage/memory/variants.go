@@ -0,0 +1,165 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/price"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/product"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/variant"
+)
+
+// CreateVariant adds a new variant to the product with the given code. The SKUs are
+// unique among all the products. A price without currency gets the currency of the
+// product.
+func (m *Memory) CreateVariant(ctx context.Context, productCode string,
+	v *variant.Variant,
+) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("unable to create the variant %s: %w", v.SKU, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rec := m.findProduct(productCode)
+	if rec == nil {
+		return product.ErrNotFound
+	}
+
+	v.SetDefaultCurrency(rec.product.Currency)
+	if err := v.Validate(); err != nil {
+		return err
+	}
+
+	if m.skuExists(v.SKU) {
+		return fmt.Errorf("unable to create the variant %s: %w", v.SKU, variant.ErrDuplicated)
+	}
+
+	now := m.now()
+	m.lastVariantID++
+	v.ID = m.lastVariantID
+	v.ProductID = rec.product.ID
+	v.CreatedAt = now
+	v.UpdatedAt = now
+	v.Version = 1
+	rec.product.Variants = append(rec.product.Variants, *v)
+	touchProduct(rec, now)
+
+	m.version++
+
+	return nil
+}
+
+// UpdateVariant updates the name and the price, with its currency, of the variant of
+// the product with the same SKU. A variant without price inherits the price of the
+// product, and a price without currency gets the currency of the product. If the
+// variant has a version, it's only updated if the stored one has the same version.
+func (m *Memory) UpdateVariant(ctx context.Context, productCode string,
+	v *variant.Variant,
+) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("unable to update the variant %s: %w", v.SKU, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rec := m.findProduct(productCode)
+	if rec == nil {
+		return product.ErrNotFound
+	}
+
+	v.SetDefaultCurrency(rec.product.Currency)
+	if err := v.Validate(); err != nil {
+		return err
+	}
+
+	idx := variantIndex(rec, v.SKU)
+	if idx < 0 {
+		return variant.ErrNotFound
+	}
+
+	stored := &rec.product.Variants[idx]
+	if !sameVersion(stored.Version, v.Version) {
+		return variant.ErrVersionMismatch
+	}
+
+	now := m.now()
+	stored.Name = v.Name
+	stored.Price = v.Price
+	stored.Currency = v.Currency
+	stored.UpdatedAt = now
+	stored.Version++
+	touchProduct(rec, now)
+	*v = *stored
+
+	m.version++
+
+	return nil
+}
+
+// DeleteVariant removes the variant with the given SKU, with its scheduled prices and its
+// stock, from the product, releasing its active reservations. If the version isn't zero,
+// the variant is only removed if it has the same version.
+func (m *Memory) DeleteVariant(ctx context.Context, productCode, sku string,
+	version uint,
+) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("unable to delete the variant %s: %w", sku, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rec := m.findProduct(productCode)
+	if rec == nil {
+		return product.ErrNotFound
+	}
+
+	idx := variantIndex(rec, sku)
+	if idx < 0 {
+		return variant.ErrNotFound
+	}
+
+	if !sameVersion(rec.product.Variants[idx].Version, version) {
+		return variant.ErrVersionMismatch
+	}
+
+	now := m.now()
+	m.removeStock([]uint{rec.product.Variants[idx].ID}, now)
+	rec.product.Variants = slices.Delete(rec.product.Variants, idx, idx+1)
+	rec.product.Prices = slices.DeleteFunc(rec.product.Prices, func(p price.Price) bool {
+		return p.SKU == sku
+	})
+	touchProduct(rec, now)
+
+	m.version++
+
+	return nil
+}
+
+// touchProduct updates the update time and the version of the product, as its
+// variants and its scheduled prices are part of it.
+func touchProduct(rec *productRecord, now time.Time) {
+	rec.product.UpdatedAt = now
+	rec.product.Version++
+}
+
+func (m *Memory) skuExists(sku string) bool {
+	for i := range m.products {
+		if variantIndex(&m.products[i], sku) >= 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func variantIndex(rec *productRecord, sku string) int {
+	return slices.IndexFunc(rec.product.Variants, func(v variant.Variant) bool {
+		return v.SKU == sku
+	})
+}
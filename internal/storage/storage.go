@@ -2,25 +2,108 @@
 package storage
 
 import (
+	"context"
+
 	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/category"
 	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/filter"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/inventory"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/page"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/price"
 	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/product"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/search"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/sorting"
+	"github.com/i02sopop/go-hiring-challenge-1.2.0/internal/model/variant"
 )
 
 // Storage type for the server.
+// Every method receives a context, so the cancellation of a request or the server
+// shutdown is propagated to the underlying storage.
 type Storage interface {
 	// Connect to the storage.
-	Connect() error
+	Connect(ctx context.Context) error
 	// GetAllProducts gets a list of all the products stored in the storage.
-	GetAllProducts() ([]product.Product, error)
-	// GetProducts obtains a list of products from the repository with a limit and an offset.
-	GetProducts(limit, offset int, filters ...filter.Filter) ([]product.Product, error)
+	GetAllProducts(ctx context.Context) ([]product.Product, error)
+	// GetProducts obtains a page of sorted products from the repository, and the total
+	// number of products matching the filters.
+	GetProducts(ctx context.Context, pg page.Page, sorts []sorting.Sort,
+		filters ...filter.Filter) ([]product.Product, int64, error)
+	// SearchProducts obtains a page of the products matching the full text search query
+	// and the filters, sorted by relevance, and the total number of matching products. The
+	// query has the web search syntax, and the searches are only paginated by offset.
+	SearchProducts(ctx context.Context, query string, pg page.Page,
+		filters ...filter.Filter) ([]search.Result, int64, error)
 	// GetProduct obtains a product from the storage by its code.
-	GetProduct(productCode string) (*product.Product, error)
+	GetProduct(ctx context.Context, productCode string) (*product.Product, error)
+	// CreateProduct adds a new product, with its variants, to the storage. The category is
+	// looked up by its code.
+	CreateProduct(ctx context.Context, prod *product.Product) error
+	// UpdateProduct updates the name, the description, the attributes, the price and the
+	// category of the product with the same code. If the product has a version, it fails
+	// with a version mismatch error if the stored product has a different one.
+	UpdateProduct(ctx context.Context, prod *product.Product) error
+	// DeleteProduct removes a product, with its variants, from the storage. If the version
+	// isn't zero, it fails with a version mismatch error if the product has a different one.
+	DeleteProduct(ctx context.Context, productCode string, version uint) error
+	// CreateVariant adds a new variant to the product with the given code.
+	CreateVariant(ctx context.Context, productCode string, v *variant.Variant) error
+	// UpdateVariant updates the name and the price of the variant of the product with the
+	// same SKU. If the variant has a version, it fails with a version mismatch error if the
+	// stored variant has a different one.
+	UpdateVariant(ctx context.Context, productCode string, v *variant.Variant) error
+	// DeleteVariant removes the variant with the given SKU, with its scheduled prices, from
+	// the product. If the version isn't zero, it fails with a version mismatch error if the
+	// variant has a different one.
+	DeleteVariant(ctx context.Context, productCode, sku string, version uint) error
+	// CreatePrice adds a scheduled price to the product with the given code, or to its
+	// variant if the price has a SKU.
+	CreatePrice(ctx context.Context, productCode string, p *price.Price) error
+	// DeletePrice removes the scheduled price with the given id from the product.
+	DeletePrice(ctx context.Context, productCode string, id uint) error
+	// GetStock obtains the stock of the variant with the given SKU.
+	GetStock(ctx context.Context, sku string) (*inventory.Stock, error)
+	// GetStocks obtains the stock of the variants with the given SKUs, ignoring the
+	// unknown ones.
+	GetStocks(ctx context.Context, skus []string) ([]inventory.Stock, error)
+	// AdjustStock adds the delta to the units on hand of the variant with the given SKU.
+	// It fails with an insufficient stock error if there would be fewer units on hand
+	// than reserved.
+	AdjustStock(ctx context.Context, sku string, delta int64) (*inventory.Stock, error)
+	// CreateReservation reserves the units of all the lines of the reservation, or none
+	// of them, failing with an insufficient stock error, if any variant doesn't have
+	// enough units available.
+	CreateReservation(ctx context.Context, r *inventory.Reservation) error
+	// GetReservation obtains a reservation by its id.
+	GetReservation(ctx context.Context, id uint) (*inventory.Reservation, error)
+	// CommitReservation removes the reserved units from the stock on hand. It fails if
+	// the reservation isn't active or it's expired.
+	CommitReservation(ctx context.Context, id uint) (*inventory.Reservation, error)
+	// ReleaseReservation makes the reserved units available again. It fails if the
+	// reservation isn't active.
+	ReleaseReservation(ctx context.Context, id uint) (*inventory.Reservation, error)
+	// ExpireReservations releases the active reservations after their expiration time,
+	// and returns how many of them expired.
+	ExpireReservations(ctx context.Context) (int64, error)
 	// GetAllCategories gets a list of all the categories stored in the storage.
-	GetAllCategories() (category.Categories, error)
-	// AddCategory adds a new category to the storage.
-	AddCategory(cat *category.Category) error
+	GetAllCategories(ctx context.Context) (category.Categories, error)
+	// AddCategory adds a new category to the storage, under its parent category if it has
+	// one.
+	AddCategory(ctx context.Context, cat *category.Category) error
+	// GetCategory obtains a category, with its ancestors, from the storage by its code.
+	GetCategory(ctx context.Context, code string) (*category.Category, error)
+	// UpdateCategory updates the name and the parent of the category with the same code.
+	// It fails with a cycle error if the parent is the category or one of its
+	// descendants. If the category has a version, it fails with a version mismatch error
+	// if the stored category has a different one.
+	UpdateCategory(ctx context.Context, cat *category.Category) error
+	// DeleteCategory removes a category from the storage. If some products belong to it
+	// they are moved to the reassignTo category, and it fails if reassignTo is empty. It
+	// fails if the category has subcategories. If the version isn't zero, it fails with a
+	// version mismatch error if the category has a different one.
+	DeleteCategory(ctx context.Context, code, reassignTo string, version uint) error
+	// WithTx runs fn as a unit of work, with a storage bound to a transaction. The
+	// transaction is committed if fn succeeds, and rolled back if it fails or panics.
+	// Nested calls run in a nested transaction, so they can be rolled back on their own.
+	WithTx(ctx context.Context, fn func(tx Storage) error) error
 	// Disconnect from the storage.
-	Disconnect() error
+	Disconnect(ctx context.Context) error
 }
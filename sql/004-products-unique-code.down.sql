@@ -0,0 +1,2 @@
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_code_key;
+ALTER TABLE products ALTER COLUMN code DROP NOT NULL;
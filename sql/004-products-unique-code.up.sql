@@ -0,0 +1,3 @@
+-- The product codes identify the products in the API, so they must be unique
+ALTER TABLE products ALTER COLUMN code SET NOT NULL;
+ALTER TABLE products ADD CONSTRAINT products_code_key UNIQUE (code);
@@ -0,0 +1,3 @@
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_category_fkey;
+ALTER TABLE products ADD CONSTRAINT products_category_fkey FOREIGN KEY (category)
+    REFERENCES category(id) ON DELETE CASCADE;
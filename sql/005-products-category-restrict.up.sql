@@ -0,0 +1,4 @@
+-- The categories with products can't be removed, the products must be moved first
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_category_fkey;
+ALTER TABLE products ADD CONSTRAINT products_category_fkey FOREIGN KEY (category)
+    REFERENCES category(id) ON DELETE RESTRICT;
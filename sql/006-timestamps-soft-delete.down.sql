@@ -0,0 +1,30 @@
+DROP INDEX IF EXISTS products_updated_at_idx;
+
+-- The removed entities are lost
+DELETE FROM product_variants WHERE deleted_at IS NOT NULL
+    OR product_id IN (SELECT id FROM products WHERE deleted_at IS NOT NULL);
+DELETE FROM products WHERE deleted_at IS NOT NULL;
+DELETE FROM category WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS category_code_key;
+ALTER TABLE category ADD CONSTRAINT category_code_key UNIQUE (code);
+DROP INDEX IF EXISTS product_variants_sku_key;
+ALTER TABLE product_variants ADD CONSTRAINT product_variants_sku_key UNIQUE (sku);
+DROP INDEX IF EXISTS products_code_key;
+ALTER TABLE products ADD CONSTRAINT products_code_key UNIQUE (code);
+
+ALTER TABLE category DROP COLUMN deleted_at;
+
+ALTER TABLE product_variants
+    DROP COLUMN deleted_at,
+    ALTER COLUMN created_at DROP NOT NULL,
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at DROP NOT NULL,
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC';
+
+ALTER TABLE products
+    DROP COLUMN deleted_at,
+    ALTER COLUMN created_at DROP NOT NULL,
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at DROP NOT NULL,
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC';
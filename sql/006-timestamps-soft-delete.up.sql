@@ -0,0 +1,31 @@
+-- The timestamps are stored with their time zone, and the removed entities are kept
+-- with their removal time (soft delete)
+UPDATE products
+    SET created_at = COALESCE(created_at, NOW()), updated_at = COALESCE(updated_at, NOW());
+ALTER TABLE products
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at SET NOT NULL,
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at SET NOT NULL,
+    ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+
+UPDATE product_variants
+    SET created_at = COALESCE(created_at, NOW()), updated_at = COALESCE(updated_at, NOW());
+ALTER TABLE product_variants
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at SET NOT NULL,
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at SET NOT NULL,
+    ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+
+ALTER TABLE category ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+
+-- The codes and SKUs of the removed entities can be reused
+ALTER TABLE products DROP CONSTRAINT products_code_key;
+CREATE UNIQUE INDEX products_code_key ON products (code) WHERE deleted_at IS NULL;
+ALTER TABLE product_variants DROP CONSTRAINT product_variants_sku_key;
+CREATE UNIQUE INDEX product_variants_sku_key ON product_variants (sku) WHERE deleted_at IS NULL;
+ALTER TABLE category DROP CONSTRAINT category_code_key;
+CREATE UNIQUE INDEX category_code_key ON category (code) WHERE deleted_at IS NULL;
+
+CREATE INDEX products_updated_at_idx ON products (updated_at) WHERE deleted_at IS NULL;
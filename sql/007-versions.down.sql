@@ -0,0 +1,3 @@
+ALTER TABLE product_variants DROP COLUMN version;
+ALTER TABLE products DROP COLUMN version;
+ALTER TABLE category DROP COLUMN version;
@@ -0,0 +1,5 @@
+-- The version of the entities is increased on every change, so the concurrent changes
+-- can be detected (optimistic concurrency control)
+ALTER TABLE category ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE products ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE product_variants ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
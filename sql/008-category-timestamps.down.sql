@@ -0,0 +1 @@
+ALTER TABLE category DROP COLUMN updated_at, DROP COLUMN created_at;
@@ -0,0 +1,4 @@
+-- The categories keep their creation and update times, so their reads can be cached
+ALTER TABLE category
+    ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS products_attributes_idx;
+ALTER TABLE products DROP COLUMN attributes, DROP COLUMN description, DROP COLUMN name;
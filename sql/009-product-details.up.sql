@@ -0,0 +1,8 @@
+-- The products have a human readable name and description, and structured attributes.
+-- The index of the attributes is used by the containment queries filtering them
+ALTER TABLE products
+    ADD COLUMN name VARCHAR(256) NOT NULL DEFAULT '',
+    ADD COLUMN description TEXT NOT NULL DEFAULT '',
+    ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX products_attributes_idx ON products USING GIN (attributes jsonb_path_ops);
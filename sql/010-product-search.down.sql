@@ -0,0 +1,12 @@
+DROP TRIGGER IF EXISTS search_settings_language ON search_settings;
+DROP TRIGGER IF EXISTS category_search ON category;
+DROP TRIGGER IF EXISTS product_variants_search ON product_variants;
+DROP TRIGGER IF EXISTS products_search ON products;
+DROP FUNCTION IF EXISTS search_settings_update();
+DROP FUNCTION IF EXISTS category_search_update();
+DROP FUNCTION IF EXISTS product_variants_search_update();
+DROP FUNCTION IF EXISTS products_search_update();
+DROP FUNCTION IF EXISTS products_search_document(products);
+DROP INDEX IF EXISTS products_search_idx;
+ALTER TABLE products DROP COLUMN search_vector;
+DROP TABLE IF EXISTS search_settings;
@@ -0,0 +1,72 @@
+-- The catalog is searched with a full text index of the products, kept up to date by
+-- triggers. The language of the index and the queries is the text search configuration
+-- of search_settings, and changing it rebuilds the index
+CREATE TABLE IF NOT EXISTS search_settings (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    language REGCONFIG NOT NULL DEFAULT 'english'
+);
+
+INSERT INTO search_settings DEFAULT VALUES;
+
+ALTER TABLE products ADD COLUMN search_vector TSVECTOR NOT NULL DEFAULT ''::tsvector;
+
+CREATE INDEX products_search_idx ON products USING GIN (search_vector);
+
+-- The code and the name of a product have the highest weight, then its category and its
+-- variants, and then its description
+CREATE OR REPLACE FUNCTION products_search_document(p products) RETURNS TSVECTOR AS $$
+    SELECT setweight(to_tsvector(s.language, concat_ws(' ', p.code, p.name)), 'A') ||
+        setweight(to_tsvector(s.language, coalesce(
+            (SELECT c.name FROM category c WHERE c.id = p.category), '')), 'B') ||
+        setweight(to_tsvector(s.language, coalesce(
+            (SELECT string_agg(concat_ws(' ', v.name, v.sku), ' ' ORDER BY v.id)
+            FROM product_variants v
+            WHERE v.product_id = p.id AND v.deleted_at IS NULL), '')), 'B') ||
+        setweight(to_tsvector(s.language, p.description), 'C')
+    FROM search_settings s
+$$ LANGUAGE SQL STABLE;
+
+CREATE OR REPLACE FUNCTION products_search_update() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector := products_search_document(NEW);
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER products_search BEFORE INSERT OR UPDATE OF code, name, description, category
+    ON products FOR EACH ROW EXECUTE FUNCTION products_search_update();
+
+CREATE OR REPLACE FUNCTION product_variants_search_update() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE products p SET search_vector = products_search_document(p)
+    WHERE p.id IN (NEW.product_id, OLD.product_id);
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER product_variants_search AFTER INSERT OR UPDATE OR DELETE
+    ON product_variants FOR EACH ROW EXECUTE FUNCTION product_variants_search_update();
+
+CREATE OR REPLACE FUNCTION category_search_update() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE products p SET search_vector = products_search_document(p)
+    WHERE p.category = NEW.id;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER category_search AFTER UPDATE OF name
+    ON category FOR EACH ROW EXECUTE FUNCTION category_search_update();
+
+CREATE OR REPLACE FUNCTION search_settings_update() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE products p SET search_vector = products_search_document(p);
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER search_settings_language AFTER UPDATE
+    ON search_settings FOR EACH STATEMENT EXECUTE FUNCTION search_settings_update();
+
+-- Index the existing products
+UPDATE products p SET search_vector = products_search_document(p);
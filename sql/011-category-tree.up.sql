@@ -0,0 +1,7 @@
+-- The categories are nested under their parent category. The parents can't be removed
+-- while they have subcategories, and the application prevents the cycles
+ALTER TABLE category
+    ADD COLUMN parent_id INTEGER NULL REFERENCES category(id),
+    ADD CONSTRAINT category_parent_check CHECK (parent_id <> id);
+
+CREATE INDEX category_parent_id_idx ON category (parent_id);
@@ -0,0 +1,8 @@
+ALTER TABLE product_variants
+    DROP CONSTRAINT IF EXISTS product_variants_currency_check,
+    DROP COLUMN currency,
+    ALTER COLUMN price TYPE DECIMAL(10, 2);
+
+ALTER TABLE products
+    DROP COLUMN currency,
+    ALTER COLUMN price TYPE DECIMAL(10, 2);
@@ -0,0 +1,17 @@
+-- The prices have an ISO 4217 currency, and up to four decimals as some currencies have
+-- more than two. The existing prices are in euros, and the variants without price
+-- inherit the price and the currency of their product
+ALTER TABLE products
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'EUR',
+    ALTER COLUMN price TYPE DECIMAL(12, 4);
+
+ALTER TABLE product_variants
+    ADD COLUMN currency CHAR(3) NULL,
+    ALTER COLUMN price TYPE DECIMAL(12, 4);
+
+UPDATE product_variants AS v SET currency = p.currency
+FROM products AS p
+WHERE p.id = v.product_id AND v.price IS NOT NULL;
+
+ALTER TABLE product_variants
+    ADD CONSTRAINT product_variants_currency_check CHECK ((price IS NULL) = (currency IS NULL));
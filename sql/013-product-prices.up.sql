@@ -0,0 +1,16 @@
+-- The scheduled prices of the products and their variants replace their price while
+-- they are valid, from effective_from until effective_to (not included), or forever
+-- without end. A sale has the original price in compare_at
+CREATE TABLE IF NOT EXISTS product_prices (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sku VARCHAR(32) NULL,
+    amount DECIMAL(12, 4) NOT NULL CHECK (amount > 0),
+    compare_at DECIMAL(12, 4) NULL CHECK (compare_at > amount),
+    currency CHAR(3) NOT NULL,
+    effective_from TIMESTAMPTZ NOT NULL,
+    effective_to TIMESTAMPTZ NULL CHECK (effective_to > effective_from),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX product_prices_product_id_idx ON product_prices (product_id, sku, effective_from);
@@ -0,0 +1,33 @@
+-- The stock of the variants: the units on hand, and the units of them reserved by the
+-- active reservations. The variants without a row don't have stock
+CREATE TABLE IF NOT EXISTS inventory_levels (
+    variant_id INTEGER PRIMARY KEY REFERENCES product_variants(id) ON DELETE CASCADE,
+    on_hand BIGINT NOT NULL DEFAULT 0,
+    reserved BIGINT NOT NULL DEFAULT 0 CHECK (reserved >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (on_hand >= reserved)
+);
+
+-- The reservations hold units of several variants until they are committed, released
+-- or expired
+CREATE TABLE IF NOT EXISTS stock_reservations (
+    id SERIAL PRIMARY KEY,
+    status VARCHAR(16) NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'committed', 'released', 'expired')),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX stock_reservations_expires_at_idx ON stock_reservations (expires_at)
+    WHERE status = 'active';
+
+CREATE TABLE IF NOT EXISTS stock_reservation_lines (
+    reservation_id INTEGER NOT NULL REFERENCES stock_reservations(id) ON DELETE CASCADE,
+    variant_id INTEGER NOT NULL REFERENCES product_variants(id) ON DELETE CASCADE,
+    sku VARCHAR(32) NOT NULL,
+    quantity BIGINT NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (reservation_id, variant_id)
+);
+
+CREATE INDEX stock_reservation_lines_variant_id_idx ON stock_reservation_lines (variant_id);
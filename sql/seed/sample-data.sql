@@ -0,0 +1,82 @@
+-- Sample categories, products and variants for the development databases. They aren't
+-- part of the migrations, so they are only loaded by the seed step (migrate seed). The
+-- rows already stored are kept, so the seed can run several times
+
+-- Insert 3 categories
+INSERT INTO category (code, name) VALUES
+('CAT001', 'Clothing'),
+('CAT002', 'Shoes'),
+('CAT003', 'Accessories')
+ON CONFLICT DO NOTHING;
+
+-- Insert 8 products
+INSERT INTO products (code, name, description, attributes, price, category)
+SELECT p.code, p.name, p.description, p.attributes::jsonb, p.price, c.id
+FROM (VALUES
+    ('PROD001', 'Classic T-Shirt', 'Short sleeve t-shirt with a crew neck.', '{"material": "cotton"}', 10.99, 'CAT001'),
+    ('PROD002', 'Running Shoes', 'Lightweight shoes for road running.', '{"material": "mesh"}', 12.49, 'CAT002'),
+    ('PROD003', 'Leather Belt', 'Adjustable belt with a metal buckle.', '{"material": "leather"}', 8.75, 'CAT003'),
+    ('PROD004', 'Denim Jeans', 'Slim fit jeans with five pockets.', '{"material": "cotton"}', 15.00, 'CAT001'),
+    ('PROD005', 'Wool Scarf', 'Warm knitted scarf for the winter.', '{"material": "wool"}', 22.99, 'CAT003'),
+    ('PROD006', 'Flip Flops', 'Rubber sandals for the beach.', '{"material": "rubber"}', 5.50, 'CAT002'),
+    ('PROD007', 'Hooded Sweatshirt', 'Fleece hoodie with a front pocket.', '{"material": "cotton"}', 18.20, 'CAT001'),
+    ('PROD008', 'Baseball Cap', 'Adjustable cap with a curved brim.', '{"material": "cotton"}', 9.99, 'CAT003')
+) AS p (code, name, description, attributes, price, category)
+JOIN category c ON c.code = p.category AND c.deleted_at IS NULL
+WHERE NOT EXISTS (
+    SELECT 1 FROM products WHERE products.deleted_at IS NULL AND products.code = p.code
+);
+
+-- Insert variants for each product using product code to look up product_id
+
+-- Product 1: 3 variants
+INSERT INTO product_variants (product_id, name, sku, price, currency) VALUES
+((SELECT id FROM products WHERE deleted_at IS NULL AND code = 'PROD001'), 'Variant A', 'SKU001A', 11.99, 'EUR'),
+((SELECT id FROM products WHERE deleted_at IS NULL AND code = 'PROD001'), 'Variant B', 'SKU001B', NULL, NULL),
+((SELECT id FROM products WHERE deleted_at IS NULL AND code = 'PROD001'), 'Variant C', 'SKU001C', NULL, NULL)
+ON CONFLICT DO NOTHING;
+
+-- Product 2: 2 variants
+INSERT INTO product_variants (product_id, name, sku, price, currency) VALUES
+((SELECT id FROM products WHERE deleted_at IS NULL AND code = 'PROD002'), 'Variant A', 'SKU002A', NULL, NULL),
+((SELECT id FROM products WHERE deleted_at IS NULL AND code = 'PROD002'), 'Variant B', 'SKU002B', NULL, NULL)
+ON CONFLICT DO NOTHING;
+
+-- Product 3: 1 variant
+INSERT INTO product_variants (product_id, name, sku, price, currency) VALUES
+((SELECT id FROM products WHERE deleted_at IS NULL AND code = 'PROD003'), 'Variant A', 'SKU003A', 8.99, 'EUR')
+ON CONFLICT DO NOTHING;
+
+-- Product 4: 4 variants
+INSERT INTO product_variants (product_id, name, sku, price, currency) VALUES
+((SELECT id FROM products WHERE deleted_at IS NULL AND code = 'PROD004'), 'Variant A', 'SKU004A', 15.50, 'EUR'),
+((SELECT id FROM products WHERE deleted_at IS NULL AND code = 'PROD004'), 'Variant B', 'SKU004B', 16.00, 'EUR'),
+((SELECT id FROM products WHERE deleted_at IS NULL AND code = 'PROD004'), 'Variant C', 'SKU004C', NULL, NULL),
+((SELECT id FROM products WHERE deleted_at IS NULL AND code = 'PROD004'), 'Variant D', 'SKU004D', 16.99, 'EUR')
+ON CONFLICT DO NOTHING;
+
+-- Product 5: 6 variants
+INSERT INTO product_variants (product_id, name, sku, price, currency) VALUES
+((SELECT id FROM products WHERE deleted_at IS NULL AND code = 'PROD005'), 'Variant A', 'SKU005A', 23.99, 'EUR'),
+((SELECT id FROM products WHERE deleted_at IS NULL AND code = 'PROD005'), 'Variant B', 'SKU005B', NULL, NULL),
+((SELECT id FROM products WHERE deleted_at IS NULL AND code = 'PROD005'), 'Variant C', 'SKU005C', NULL, NULL),
+((SELECT id FROM products WHERE deleted_at IS NULL AND code = 'PROD005'), 'Variant D', 'SKU005D', 22.99, 'EUR'),
+((SELECT id FROM products WHERE deleted_at IS NULL AND code = 'PROD005'), 'Variant E', 'SKU005E', 23.49, 'EUR'),
+((SELECT id FROM products WHERE deleted_at IS NULL AND code = 'PROD005'), 'Variant F', 'SKU005F', NULL, NULL)
+ON CONFLICT DO NOTHING;
+
+-- Product 6: no variants
+
+-- Product 7: 5 variants
+INSERT INTO product_variants (product_id, name, sku, price, currency) VALUES
+((SELECT id FROM products WHERE deleted_at IS NULL AND code = 'PROD007'), 'Variant A', 'SKU007A', NULL, NULL),
+((SELECT id FROM products WHERE deleted_at IS NULL AND code = 'PROD007'), 'Variant B', 'SKU007B', NULL, NULL),
+((SELECT id FROM products WHERE deleted_at IS NULL AND code = 'PROD007'), 'Variant C', 'SKU007C', NULL, NULL),
+((SELECT id FROM products WHERE deleted_at IS NULL AND code = 'PROD007'), 'Variant D', 'SKU007D', NULL, NULL),
+((SELECT id FROM products WHERE deleted_at IS NULL AND code = 'PROD007'), 'Variant E', 'SKU007E', 18.75, 'EUR')
+ON CONFLICT DO NOTHING;
+
+-- Product 8: 1 variant
+INSERT INTO product_variants (product_id, name, sku, price, currency) VALUES
+((SELECT id FROM products WHERE deleted_at IS NULL AND code = 'PROD008'), 'Variant A', 'SKU008A', 10.49, 'EUR')
+ON CONFLICT DO NOTHING;